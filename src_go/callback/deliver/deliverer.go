@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/callback"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type CallbackDeliverer struct {
+	httpClient *http.Client
+}
+
+func (deliverer *CallbackDeliverer) Deliver(commands events.SQSEvent) (commandsProcessed events.SQSEventResponse, err error) {
+	config := zap.NewProductionConfig()
+	config.OutputPaths = []string{"stdout"}
+	timeEncoder := func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.UTC().Format("2006-01-02T15:04:05.000Z"))
+	}
+	config.EncoderConfig.EncodeTime = timeEncoder
+
+	logger, err := config.Build()
+	if err != nil {
+		return
+	}
+	defer logger.Sync()
+
+	logger.Info("Processing commands in total", zap.Int("commands", len(commands.Records)))
+
+	rejected := 0
+	for _, message := range commands.Records {
+		commandFailure, errOfCommand := deliverer.deliverSingle(&message, logger)
+		if commandFailure != nil {
+			commandsProcessed.BatchItemFailures = append(commandsProcessed.BatchItemFailures, *commandFailure)
+			continue
+		}
+		if errOfCommand != nil {
+			rejected++
+		}
+	}
+
+	logger.Info(
+		"commands processed",
+		zap.Int("rejected", rejected),
+		zap.Int("redelivered", len(commandsProcessed.BatchItemFailures)),
+	)
+	return
+}
+
+func (deliverer *CallbackDeliverer) deliverSingle(
+	message *events.SQSMessage,
+	logger *zap.Logger,
+) (commandProcessed *events.SQSBatchItemFailure, err error) {
+	command := queue.DeliverCallbackCommand{}
+	err = json.Unmarshal([]byte(message.Body), &command)
+	if err != nil {
+		logger.Error("impossible to unmarshal the command", zap.Error(err))
+		return
+	}
+
+	// the id of the message stays the same on every delivery, so the client can tell the repeated callbacks
+	logger = logger.With(zap.String("deliveryId", message.MessageId))
+	logger = logger.With(zap.String("callbackUrl", command.CallbackUrl))
+
+	logger.Info("delivering the callback")
+	err = callback.Deliver(deliverer.httpClient, command.CallbackUrl, command.CallbackSecret, message.MessageId, command.Payload, time.Now())
+
+	if errors.Is(err, callback.ErrUnavailable) {
+		// the command goes to the dead letter queue after the last receive
+		logger.Info("callback will be delivered again", zap.Error(err))
+		commandProcessed = &events.SQSBatchItemFailure{ItemIdentifier: message.MessageId}
+		return
+	}
+
+	if err != nil {
+		logger.Error("callback is rejected by the client", zap.Error(err))
+		return
+	}
+
+	logger.Info("callback delivered")
+	return
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/callback"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CallbackDeliverer_should_post_the_payload_and_redeliver_only_the_temporary_failures(t *testing.T) {
+	var err error
+	receivedBodies := map[string]string{}
+	receivedSignatures := map[string]string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		receivedBodies[request.URL.Path] = string(body)
+		receivedSignatures[request.URL.Path] = request.Header.Get(callback.SignatureHeader)
+		switch request.URL.Path {
+		case "/accepted":
+			writer.WriteHeader(http.StatusNoContent)
+		case "/unavailable":
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			writer.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer server.Close()
+
+	deliverer := CallbackDeliverer{httpClient: server.Client()}
+
+	commandTo := func(messageId string, path string) events.SQSMessage {
+		return events.SQSMessage{
+			MessageId: messageId,
+			Body: fmt.Sprintf(
+				`{"callbackUrl": "%s%s", "callbackSecret": "secret", "payload": {"event":"DOWNLOAD_COMPLETED","downloadId":"%s"}}`,
+				server.URL,
+				path,
+				messageId,
+			),
+		}
+	}
+
+	commands := events.SQSEvent{
+		Records: []events.SQSMessage{
+			commandTo("1", "/accepted"),
+			commandTo("2", "/unavailable"),
+			commandTo("3", "/gone"),
+		},
+	}
+
+	actualCommandsProcessed, err := deliverer.Deliver(commands)
+	assert.NoError(t, err)
+
+	expectedCommandsProcessed := events.SQSEventResponse{
+		BatchItemFailures: []events.SQSBatchItemFailure{
+			{ItemIdentifier: "2"},
+		},
+	}
+	assert.Equal(t, expectedCommandsProcessed, actualCommandsProcessed)
+
+	assert.Equal(t, `{"event":"DOWNLOAD_COMPLETED","downloadId":"1"}`, receivedBodies["/accepted"])
+	assert.NotEmpty(t, receivedSignatures["/accepted"])
+	assert.Len(t, receivedBodies, 3)
+}
+
+func Test_CallbackDeliverer_should_drop_a_command_that_is_not_a_callback(t *testing.T) {
+	deliverer := CallbackDeliverer{httpClient: http.DefaultClient}
+
+	commands := events.SQSEvent{
+		Records: []events.SQSMessage{
+			{MessageId: "1", Body: "this is not a callback"},
+		},
+	}
+
+	actualCommandsProcessed, err := deliverer.Deliver(commands)
+	assert.NoError(t, err)
+	assert.Equal(t, events.SQSEventResponse{BatchItemFailures: nil}, actualCommandsProcessed)
+}
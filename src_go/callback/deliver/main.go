@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/callback"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+)
+
+func main() {
+
+	callbackTimeout := 10 * time.Second
+	callbackTimeoutValue, callbackTimeoutExists := os.LookupEnv("CALLBACK_TIMEOUT")
+	if callbackTimeoutExists {
+		timeout, err := time.ParseDuration(callbackTimeoutValue)
+		if err != nil {
+			panic(fmt.Errorf("CALLBACK_TIMEOUT is invalid: %w", err))
+		}
+		callbackTimeout = timeout
+	}
+
+	deliverer := CallbackDeliverer{
+		httpClient: callback.NewHttpClient(callbackTimeout),
+	}
+
+	lambda.Start(queue.SealErrors(deliverer.Deliver))
+
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/aws/aws-lambda-go/events"
 )
@@ -20,6 +21,14 @@ var ServiceOverloaded = BusinessError{
 	Code: "SERVICE_OVERLOADED",
 }
 
+// DownloadNotFound is shared by every endpoint that looks up a download request by its id
+func DownloadNotFound(downloadId string) BusinessError {
+	return BusinessError{
+		Msg:  fmt.Sprintf("Download request %v not found", downloadId),
+		Code: "DOWNLOAD_REQUEST_NOT_FOUND",
+	}
+}
+
 func (businessError BusinessError) toResponseEvent() (responseEvent events.APIGatewayV2HTTPResponse) {
 	responseBody, err := json.Marshal(businessError)
 	if err != nil {
@@ -0,0 +1,171 @@
+package callback
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+var ErrInvalidUrl = errors.New("callback url has to be an absolute https url of a public host")
+var ErrRejected = errors.New("callback is rejected by the client")
+var ErrUnavailable = errors.New("callback is not accepted by the client for now")
+var errPrivateAddress = errors.New("callback url resolves to a private address")
+
+// lookupIP is replaced in the tests, so they do not depend on the dns
+var lookupIP = net.DefaultResolver.LookupIPAddr
+
+const (
+	DeliveryHeader  = "X-Chessfinder-Delivery"
+	TimestampHeader = "X-Chessfinder-Timestamp"
+	SignatureHeader = "X-Chessfinder-Signature"
+)
+
+const (
+	DownloadCompletedEvent = "DOWNLOAD_COMPLETED"
+	SearchCompletedEvent   = "SEARCH_COMPLETED"
+)
+
+type DownloadCompleted struct {
+	Event      string `json:"event"`
+	DownloadId string `json:"downloadId"`
+	Failed     int    `json:"failed"`
+	Succeed    int    `json:"succeed"`
+	Cancelled  int    `json:"cancelled"`
+	Done       int    `json:"done"`
+	Pending    int    `json:"pending"`
+	Total      int    `json:"total"`
+}
+
+// SearchCompleted tells only how many games are matched, the matches themselves are read page by page
+type SearchCompleted struct {
+	Event        string `json:"event"`
+	SearchId     string `json:"searchId"`
+	Status       string `json:"status"`
+	Examined     int    `json:"examined"`
+	Total        int    `json:"total"`
+	MatchedCount int    `json:"matchedCount"`
+}
+
+// ValidateUrl accepts only https urls whose host resolves to public addresses,
+// the callbacks are posted from inside the cloud and must not reach its internal services
+func ValidateUrl(callbackUrl string) (err error) {
+	parsedUrl, err := url.ParseRequestURI(callbackUrl)
+	if err != nil || parsedUrl.Scheme != "https" || parsedUrl.Hostname() == "" || parsedUrl.User != nil {
+		err = ErrInvalidUrl
+		return
+	}
+
+	addresses, err := lookupIP(context.Background(), parsedUrl.Hostname())
+	if err != nil || len(addresses) == 0 {
+		err = ErrInvalidUrl
+		return
+	}
+	for _, address := range addresses {
+		if !isPublic(address.IP) {
+			err = ErrInvalidUrl
+			return
+		}
+	}
+	return
+}
+
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
+
+// NewHttpClient is the client the callbacks are delivered with. It never follows a redirect
+// and never connects to a private address, the host of a validated url can resolve to another address later
+func NewHttpClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: rejectPrivateAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func rejectPrivateAddress(network string, address string, _ syscall.RawConn) (err error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublic(ip) {
+		err = fmt.Errorf("%w: %v", errPrivateAddress, host)
+	}
+	return
+}
+
+// NewSecret generates the secret a client verifies the signatures of its callbacks with
+func NewSecret() (secret string, err error) {
+	randomBytes := make([]byte, 32)
+	_, err = rand.Read(randomBytes)
+	if err != nil {
+		return
+	}
+	secret = hex.EncodeToString(randomBytes)
+	return
+}
+
+// Sign is the hex encoded HMAC-SHA256 of the timestamp and the body joined by a dot,
+// signing the timestamp too lets the client reject replays of old callbacks
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver posts the signed payload to the callback url. The client answers with 2xx when it accepts the callback,
+// redirects and other client errors are final, the rest is worth a retry
+func Deliver(httpClient *http.Client, callbackUrl string, secret string, deliveryId string, body []byte, now time.Time) (err error) {
+	request, err := http.NewRequest(http.MethodPost, callbackUrl, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(DeliveryHeader, deliveryId)
+	request.Header.Set(TimestampHeader, timestamp)
+	request.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrUnavailable, err)
+		return
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return
+	case response.StatusCode >= 300 && response.StatusCode < 400:
+		err = fmt.Errorf("%w: redirect with status %v", ErrRejected, response.StatusCode)
+	case response.StatusCode == http.StatusRequestTimeout || response.StatusCode == http.StatusTooManyRequests:
+		err = fmt.Errorf("%w: status %v", ErrUnavailable, response.StatusCode)
+	case response.StatusCode >= 400 && response.StatusCode < 500:
+		err = fmt.Errorf("%w: status %v", ErrRejected, response.StatusCode)
+	default:
+		err = fmt.Errorf("%w: status %v", ErrUnavailable, response.StatusCode)
+	}
+	return
+}
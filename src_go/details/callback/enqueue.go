@@ -0,0 +1,33 @@
+package callback
+
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+)
+
+// Enqueue hands the payload over to the deliverer of the callbacks, which posts it to the client and retries on failures
+func Enqueue(svc *sqs.SQS, deliverCallbacksQueueUrl string, callbackUrl string, callbackSecret string, payload any) (err error) {
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	command := queue.DeliverCallbackCommand{
+		CallbackUrl:    callbackUrl,
+		CallbackSecret: callbackSecret,
+		Payload:        payloadJson,
+	}
+	commandJson, err := json.Marshal(command)
+	if err != nil {
+		return
+	}
+
+	_, err = svc.SendMessage(&sqs.SendMessageInput{
+		MessageBody: aws.String(string(commandJson)),
+		QueueUrl:    aws.String(deliverCallbacksQueueUrl),
+	})
+	return
+}
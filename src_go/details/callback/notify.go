@@ -0,0 +1,86 @@
+package callback
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"go.uber.org/zap"
+)
+
+// Claim is the record whose callback is sent once it is finished, Condition tells when it is finished
+type Claim struct {
+	TableName                 string
+	Key                       map[string]*dynamodb.AttributeValue
+	Condition                 string
+	ExpressionAttributeNames  map[string]*string
+	ExpressionAttributeValues map[string]*dynamodb.AttributeValue
+}
+
+// NotifyOnce hands the payload of a finished record over to the deliverer of the callbacks.
+// The notification is claimed with a conditional update, so only one of the workers finishing the record sends it,
+// and it is released again if the callback can not be enqueued. The payload is built from the claimed record
+func NotifyOnce(
+	dynamodbClient *dynamodb.DynamoDB,
+	svc *sqs.SQS,
+	logger *zap.Logger,
+	deliverCallbacksQueueUrl string,
+	claim Claim,
+	payloadOf func(record map[string]*dynamodb.AttributeValue) (callbackUrl string, callbackSecret string, payload any, err error),
+) (err error) {
+	expressionAttributeValues := map[string]*dynamodb.AttributeValue{
+		":notified": {
+			BOOL: aws.Bool(true),
+		},
+	}
+	for name, value := range claim.ExpressionAttributeValues {
+		expressionAttributeValues[name] = value
+	}
+
+	updateItemOutput, err := dynamodbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String(claim.TableName),
+		Key:                       claim.Key,
+		UpdateExpression:          aws.String("SET notified = :notified"),
+		ConditionExpression:       aws.String(claim.Condition + " AND attribute_exists(callback_url) AND attribute_not_exists(notified)"),
+		ExpressionAttributeNames:  claim.ExpressionAttributeNames,
+		ExpressionAttributeValues: expressionAttributeValues,
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	})
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		// the record is not finished, is notified already or nobody waits for it
+		err = nil
+		return
+	}
+
+	if err != nil {
+		logger.Error("impossible to claim the notification", zap.Error(err))
+		return
+	}
+
+	callbackUrl, callbackSecret, payload, err := payloadOf(updateItemOutput.Attributes)
+	if err != nil {
+		logger.Error("impossible to build the payload of the callback", zap.Error(err))
+		return
+	}
+
+	err = Enqueue(svc, deliverCallbacksQueueUrl, callbackUrl, callbackSecret, payload)
+	if err != nil {
+		logger.Error("impossible to enqueue the callback, releasing the notification", zap.Error(err))
+		_, errOfRelease := dynamodbClient.UpdateItem(&dynamodb.UpdateItemInput{
+			TableName:        aws.String(claim.TableName),
+			Key:              claim.Key,
+			UpdateExpression: aws.String("REMOVE notified"),
+		})
+		if errOfRelease != nil {
+			logger.Error("impossible to release the notification", zap.Error(errOfRelease))
+		}
+		return
+	}
+
+	logger.Info("callback is enqueued")
+	return
+}
@@ -0,0 +1,30 @@
+package chess
+
+// Validate tells whether the search fen is a board that can be searched
+func Validate(searchFen string) bool {
+	_, err := ReadSearchFen(searchFen)
+	return err == nil
+}
+
+// Find tells whether the board is in any position of the game, from the one before the first move to the last one.
+// A game that can not be replayed to its end has no board, as in the core
+func Find(searchFen string, pgn string) bool {
+	board, err := ReadSearchFen(searchFen)
+	if err != nil {
+		return false
+	}
+	game, err := Read(pgn)
+	if err != nil {
+		return false
+	}
+	positions, err := game.Positions()
+	if err != nil {
+		return false
+	}
+	for _, position := range positions {
+		if board.Includes(position) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package chess
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const operaGame = `[Event "Paris"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 d6 3. d4 Bg4 4. dxe5 Bxf3 5. Qxf3 dxe5 6. Bc4 Nf6 7. Qb3 Qe7
+8. Nc3 c6 9. Bg5 b5 10. Nxb5 cxb5 11. Bxb5+ Nbd7 12. O-O-O Rd8
+13. Rxd7 Rxd7 14. Rd1 Qe6 15. Bxd7+ Nxd7 16. Qb8+ Nxb8 17. Rd8# 1-0
+`
+
+func Test_Validate_should_accept_only_the_search_fens_of_8_rows_of_8_squares(t *testing.T) {
+	assert.True(t, Validate("????????/????????/????????/????????/????????/????????/????????/????????"))
+	assert.False(t, Validate("????????/????????/????????/????????/????????/????????/????????"))
+}
+
+func Test_Find_should_find_the_board_in_the_last_position(t *testing.T) {
+	assert.True(t, Find("-n-Rkb-r/p----ppp/----q---/----p-B-/----P---/--------/PPP--PPP/--K-----", operaGame))
+	assert.True(t, Find("-n-Rk???/?????ppp/????????/????????/????????/????????/????????/????????", operaGame))
+}
+
+func Test_Find_should_find_the_board_in_the_position_before_the_first_move(t *testing.T) {
+	assert.True(t, Find("rnbqkbnr/pppppppp/--------/--------/--------/--------/PPPPPPPP/RNBQKBNR", operaGame))
+}
+
+func Test_Find_should_not_find_the_board_that_is_not_in_the_game(t *testing.T) {
+	assert.False(t, Find("????????/????????/????????/????????/????????/????????/????????/???????q", operaGame))
+}
+
+func Test_Find_should_not_find_any_board_in_a_game_that_can_not_be_replayed(t *testing.T) {
+	assert.False(t, Find("rnbqkbnr/pppppppp/--------/--------/--------/--------/PPPPPPPP/RNBQKBNR", "1. e4 e5 2. Ke3 *"))
+}
@@ -0,0 +1,96 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/pgn"
+)
+
+// Game is a pgn split into its tags and its moves, so it can be replayed up to any ply
+type Game struct {
+	pgn   pgn.Game
+	start Position
+	Moves []string
+}
+
+// the variants with the standard moves, the others change the board in ways a replay of the moves does not know
+var supportedVariants = map[string]bool{
+	"":              true,
+	"standard":      true,
+	"chess960":      true,
+	"fromposition":  true,
+	"kingofthehill": true,
+}
+
+// Read splits the pgn, the comments, the variations and the annotations of the moves are dropped
+func Read(pgnOfGame string) (game Game, err error) {
+	game.pgn, err = pgn.Read(pgnOfGame)
+	if err != nil {
+		return
+	}
+
+	variant := game.pgn.Tag("Variant")
+	if !supportedVariants[strings.NewReplacer(" ", "", "-", "").Replace(strings.ToLower(variant))] {
+		err = fmt.Errorf("variant %v can not be replayed", variant)
+		return
+	}
+
+	startingFen := StartingFen
+	if fen, exists := game.pgn.Tags["FEN"]; exists {
+		startingFen = fen
+	}
+	game.start, err = ParseFen(startingFen)
+	if err != nil {
+		return
+	}
+
+	game.Moves = game.pgn.Moves()
+	return
+}
+
+// Positions replays the moves, the position before the first move has the ply 0
+func (game Game) Positions() (positions []Position, err error) {
+	position := game.start
+	positions = append(positions, position)
+	for ply, move := range game.Moves {
+		err = position.Play(move)
+		if err != nil {
+			err = fmt.Errorf("impossible to replay the ply %d: %w", ply+1, err)
+			return
+		}
+		positions = append(positions, position)
+	}
+	return
+}
+
+// PgnUpTo writes the game stopped after the given ply, with the tags of the original pgn and an unknown result
+func (game Game) PgnUpTo(ply int) string {
+	builder := strings.Builder{}
+	for _, name := range game.pgn.TagNames {
+		if name == "Result" {
+			continue
+		}
+		builder.WriteString(pgn.TagPair(name, game.pgn.Tag(name)))
+		builder.WriteByte('\n')
+	}
+	builder.WriteByte('\n')
+
+	turn := game.start.turn
+	moveNumber := game.start.fullmoveNumber
+	for i, move := range game.Moves[:ply] {
+		if turn == white {
+			builder.WriteString(fmt.Sprintf("%d. ", moveNumber))
+		} else if i == 0 {
+			builder.WriteString(fmt.Sprintf("%d... ", moveNumber))
+		}
+		builder.WriteString(move)
+		builder.WriteByte(' ')
+		if turn == black {
+			moveNumber++
+		}
+		turn = 1 - turn
+	}
+	builder.WriteString("*")
+	return builder.String()
+}
@@ -0,0 +1,57 @@
+package chess
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Read_should_drop_the_comments_variations_and_annotations_of_the_moves(t *testing.T) {
+	pgn := `[Event "Live Chess"]
+[Result "1-0"]
+
+1. e4 {[%clk 0:09:59.9]} 1... e5 $1 2. Nf3 (2. f4 exf4) 2... Nc6?! ; the knight
+3.Bb5 a6 1-0`
+
+	game, err := Read(pgn)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e4", "e5", "Nf3", "Nc6?!", "Bb5", "a6"}, game.Moves)
+}
+
+func Test_Read_should_reject_the_variants_that_change_the_board_beyond_the_moves(t *testing.T) {
+	_, err := Read(`[Variant "Crazyhouse"]
+
+1. e4 d5 2. exd5 Qxd5 *`)
+	assert.Error(t, err)
+}
+
+func Test_Positions_should_start_from_the_fen_of_the_game(t *testing.T) {
+	pgn := `[Variant "Chess960"]
+[SetUp "1"]
+[FEN "bqnbrkrn/pppppppp/8/8/8/8/PPPPPPPP/BQNBRKRN w KQkq - 0 1"]
+
+1. g4 g5 2. Ng3 Ng6 3. O-O O-O *`
+
+	game, err := Read(pgn)
+	assert.NoError(t, err)
+
+	positions, err := game.Positions()
+	assert.NoError(t, err)
+	assert.Len(t, positions, 7)
+	assert.Equal(t, "bqnbrkrn/pppppppp/8/8/8/8/PPPPPPPP/BQNBRKRN w KQkq - 0 1", positions[0].Fen())
+	assert.Equal(t, "bqnbrkrn/pppppp1p/8/6p1/6P1/8/PPPPPP1P/BQNBRKRN w KQkq g6 0 2", positions[2].Fen())
+	assert.Equal(t, "bqnbrrk1/pppppp1p/6n1/6p1/6P1/6N1/PPPPPP1P/BQNBRRK1 w - - 4 4", positions[6].Fen())
+}
+
+func Test_PgnUpTo_should_keep_the_tags_and_stop_after_the_ply(t *testing.T) {
+	pgn := `[Event "Live Chess"]
+[Result "0-1"]
+
+1. e4 e5 2. Nf3 Nc6 0-1`
+
+	game, err := Read(pgn)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "[Event \"Live Chess\"]\n\n*", game.PgnUpTo(0))
+	assert.Equal(t, "[Event \"Live Chess\"]\n\n1. e4 e5 2. Nf3 *", game.PgnUpTo(3))
+}
@@ -0,0 +1,585 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const StartingFen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+const (
+	white = 0
+	black = 1
+)
+
+const (
+	kingSide  = 0
+	queenSide = 1
+)
+
+const noSquare = -1
+
+// Position is the board between two moves, the squares are numbered from a1 to h8 rank by rank
+// and the pieces are the letters of the fen, upper case for white
+type Position struct {
+	board [64]byte
+	turn  int
+	// castlingRooks keeps the squares of the rooks that may castle, by color and side,
+	// the rooks of chess960 start on any file
+	castlingRooks  [2][2]int
+	enPassant      int
+	halfmoveClock  int
+	fullmoveNumber int
+}
+
+func file(square int) int {
+	return square % 8
+}
+
+func rank(square int) int {
+	return square / 8
+}
+
+func squareOf(file int, rank int) int {
+	return rank*8 + file
+}
+
+func squareName(square int) string {
+	return string([]byte{byte('a' + file(square)), byte('1' + rank(square))})
+}
+
+func parseSquare(name string) (square int, err error) {
+	if len(name) != 2 || name[0] < 'a' || name[0] > 'h' || name[1] < '1' || name[1] > '8' {
+		err = fmt.Errorf("%v is not a square", name)
+		return
+	}
+	square = squareOf(int(name[0]-'a'), int(name[1]-'1'))
+	return
+}
+
+func colorOf(piece byte) int {
+	if piece >= 'a' {
+		return black
+	}
+	return white
+}
+
+func kindOf(piece byte) byte {
+	if piece >= 'a' {
+		return piece - 'a' + 'A'
+	}
+	return piece
+}
+
+func pieceOf(color int, kind byte) byte {
+	if color == black {
+		return kind - 'A' + 'a'
+	}
+	return kind
+}
+
+func backRank(color int) int {
+	if color == black {
+		return 7
+	}
+	return 0
+}
+
+// ParseFen reads the position of a fen, the castling rights are given as KQkq or as the files of the rooks
+func ParseFen(fen string) (position Position, err error) {
+	fields := strings.Fields(fen)
+	if len(fields) < 4 {
+		err = fmt.Errorf("fen %v has to have at least 4 fields", fen)
+		return
+	}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		err = fmt.Errorf("fen %v has to have 8 ranks", fen)
+		return
+	}
+	for i, rankOfFen := range ranks {
+		currentRank := 7 - i
+		currentFile := 0
+		for _, char := range []byte(rankOfFen) {
+			switch {
+			case char >= '1' && char <= '8':
+				currentFile += int(char - '0')
+			case strings.IndexByte("PNBRQKpnbrqk", char) >= 0:
+				if currentFile > 7 {
+					err = fmt.Errorf("rank %v of fen %v is too long", rankOfFen, fen)
+					return
+				}
+				position.board[squareOf(currentFile, currentRank)] = char
+				currentFile++
+			default:
+				err = fmt.Errorf("fen %v has an unknown piece %c", fen, char)
+				return
+			}
+		}
+		if currentFile != 8 {
+			err = fmt.Errorf("rank %v of fen %v does not have 8 files", rankOfFen, fen)
+			return
+		}
+	}
+
+	switch fields[1] {
+	case "w":
+		position.turn = white
+	case "b":
+		position.turn = black
+	default:
+		err = fmt.Errorf("fen %v has an unknown side to move %v", fen, fields[1])
+		return
+	}
+
+	position.castlingRooks = [2][2]int{{noSquare, noSquare}, {noSquare, noSquare}}
+	if fields[2] != "-" {
+		for _, char := range []byte(fields[2]) {
+			err = position.addCastling(char)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	position.enPassant = noSquare
+	if fields[3] != "-" {
+		position.enPassant, err = parseSquare(fields[3])
+		if err != nil {
+			return
+		}
+	}
+
+	position.fullmoveNumber = 1
+	if len(fields) >= 6 {
+		position.halfmoveClock, err = strconv.Atoi(fields[4])
+		if err != nil {
+			err = fmt.Errorf("fen %v has a wrong halfmove clock", fen)
+			return
+		}
+		position.fullmoveNumber, err = strconv.Atoi(fields[5])
+		if err != nil {
+			err = fmt.Errorf("fen %v has a wrong fullmove number", fen)
+			return
+		}
+	}
+	return
+}
+
+func (position *Position) addCastling(char byte) error {
+	color := white
+	if char >= 'a' {
+		color = black
+	}
+	kind := kindOf(char)
+	king, kingExists := position.find(pieceOf(color, 'K'))
+	if !kingExists || rank(king) != backRank(color) {
+		return fmt.Errorf("castling %c needs the king on its back rank", char)
+	}
+
+	rook := noSquare
+	switch {
+	case kind == 'K':
+		for f := 7; f > file(king); f-- {
+			if position.board[squareOf(f, backRank(color))] == pieceOf(color, 'R') {
+				rook = squareOf(f, backRank(color))
+				break
+			}
+		}
+	case kind == 'Q':
+		for f := 0; f < file(king); f++ {
+			if position.board[squareOf(f, backRank(color))] == pieceOf(color, 'R') {
+				rook = squareOf(f, backRank(color))
+				break
+			}
+		}
+	case kind >= 'A' && kind <= 'H':
+		candidate := squareOf(int(kind-'A'), backRank(color))
+		if position.board[candidate] == pieceOf(color, 'R') {
+			rook = candidate
+		}
+	default:
+		return fmt.Errorf("castling %c is unknown", char)
+	}
+	if rook == noSquare {
+		return fmt.Errorf("castling %c does not have its rook", char)
+	}
+
+	side := kingSide
+	if file(rook) < file(king) {
+		side = queenSide
+	}
+	position.castlingRooks[color][side] = rook
+	return nil
+}
+
+func (position Position) find(piece byte) (square int, exists bool) {
+	for square = 0; square < 64; square++ {
+		if position.board[square] == piece {
+			exists = true
+			return
+		}
+	}
+	return
+}
+
+// Fen writes the position, the en passant square is written after every double step of a pawn as chess.com does
+func (position Position) Fen() string {
+	builder := strings.Builder{}
+	for r := 7; r >= 0; r-- {
+		empty := 0
+		for f := 0; f < 8; f++ {
+			piece := position.board[squareOf(f, r)]
+			if piece == 0 {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				builder.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			builder.WriteByte(piece)
+		}
+		if empty > 0 {
+			builder.WriteByte(byte('0' + empty))
+		}
+		if r > 0 {
+			builder.WriteByte('/')
+		}
+	}
+
+	if position.turn == white {
+		builder.WriteString(" w ")
+	} else {
+		builder.WriteString(" b ")
+	}
+
+	castling := position.castlingFen()
+	if castling == "" {
+		castling = "-"
+	}
+	builder.WriteString(castling)
+
+	if position.enPassant != noSquare {
+		builder.WriteString(" " + squareName(position.enPassant))
+	} else {
+		builder.WriteString(" -")
+	}
+
+	builder.WriteString(fmt.Sprintf(" %d %d", position.halfmoveClock, position.fullmoveNumber))
+	return builder.String()
+}
+
+// castlingFen writes KQkq for the outermost rooks and the files of the rooks for the others, as X-FEN does
+func (position Position) castlingFen() string {
+	castling := []byte{}
+	for _, color := range []int{white, black} {
+		for _, side := range []int{kingSide, queenSide} {
+			rook := position.castlingRooks[color][side]
+			if rook == noSquare {
+				continue
+			}
+			outermost := true
+			step := 1
+			if side == queenSide {
+				step = -1
+			}
+			for f := file(rook) + step; f >= 0 && f < 8; f += step {
+				if position.board[squareOf(f, backRank(color))] == pieceOf(color, 'R') {
+					outermost = false
+				}
+			}
+			letter := byte('K')
+			if side == queenSide {
+				letter = 'Q'
+			}
+			if !outermost {
+				letter = byte('A' + file(rook))
+			}
+			castling = append(castling, pieceOf(color, letter))
+		}
+	}
+	return string(castling)
+}
+
+// reaches tells whether the piece on from attacks to, the pawns are asked only about their captures
+func (position Position) reaches(from int, to int) bool {
+	piece := position.board[from]
+	df := file(to) - file(from)
+	dr := rank(to) - rank(from)
+	switch kindOf(piece) {
+	case 'P':
+		forward := 1
+		if colorOf(piece) == black {
+			forward = -1
+		}
+		return dr == forward && (df == 1 || df == -1)
+	case 'N':
+		return (abs(df) == 1 && abs(dr) == 2) || (abs(df) == 2 && abs(dr) == 1)
+	case 'K':
+		return abs(df) <= 1 && abs(dr) <= 1 && (df != 0 || dr != 0)
+	case 'B':
+		return abs(df) == abs(dr) && df != 0 && position.isPathEmpty(from, to)
+	case 'R':
+		return (df == 0) != (dr == 0) && position.isPathEmpty(from, to)
+	case 'Q':
+		return ((abs(df) == abs(dr) && df != 0) || (df == 0) != (dr == 0)) && position.isPathEmpty(from, to)
+	}
+	return false
+}
+
+func (position Position) isPathEmpty(from int, to int) bool {
+	stepFile := sign(file(to) - file(from))
+	stepRank := sign(rank(to) - rank(from))
+	f, r := file(from)+stepFile, rank(from)+stepRank
+	for squareOf(f, r) != to {
+		if position.board[squareOf(f, r)] != 0 {
+			return false
+		}
+		f, r = f+stepFile, r+stepRank
+	}
+	return true
+}
+
+func (position Position) isAttacked(square int, byColor int) bool {
+	for from := 0; from < 64; from++ {
+		piece := position.board[from]
+		if piece != 0 && colorOf(piece) == byColor && position.reaches(from, square) {
+			return true
+		}
+	}
+	return false
+}
+
+func (position Position) isInCheck(color int) bool {
+	king, kingExists := position.find(pieceOf(color, 'K'))
+	return kingExists && position.isAttacked(king, 1-color)
+}
+
+// move puts the piece on from to the square to, taking the pawn passed by en passant and promoting to the given kind
+func (position *Position) move(from int, to int, promotion byte) {
+	piece := position.board[from]
+	if kindOf(piece) == 'P' && to == position.enPassant && file(from) != file(to) && position.board[to] == 0 {
+		position.board[squareOf(file(to), rank(from))] = 0
+	}
+	position.board[to] = piece
+	position.board[from] = 0
+	if promotion != 0 {
+		position.board[to] = pieceOf(colorOf(piece), promotion)
+	}
+}
+
+var ErrIllegalMove = errors.New("illegal move")
+
+// Play applies a move written in the standard algebraic notation
+func (position *Position) Play(san string) (err error) {
+	move := strings.TrimRight(san, "+#!?")
+	if move == "" {
+		return fmt.Errorf("%w: empty move", ErrIllegalMove)
+	}
+
+	switch move {
+	case "O-O", "0-0":
+		return position.castle(kingSide)
+	case "O-O-O", "0-0-0":
+		return position.castle(queenSide)
+	}
+
+	promotion := byte(0)
+	if index := strings.IndexByte(move, '='); index >= 0 {
+		if index+2 != len(move) {
+			return fmt.Errorf("%w: %v", ErrIllegalMove, san)
+		}
+		promotion = move[index+1]
+		move = move[:index]
+	} else if last := move[len(move)-1]; strings.IndexByte("QRBN", last) >= 0 && len(move) > 2 {
+		promotion = last
+		move = move[:len(move)-1]
+	}
+	if promotion != 0 && strings.IndexByte("QRBN", promotion) < 0 {
+		return fmt.Errorf("%w: %v", ErrIllegalMove, san)
+	}
+
+	kind := byte('P')
+	if strings.IndexByte("NBRQK", move[0]) >= 0 {
+		kind = move[0]
+		move = move[1:]
+	}
+	if len(move) < 2 {
+		return fmt.Errorf("%w: %v", ErrIllegalMove, san)
+	}
+
+	to, err := parseSquare(move[len(move)-2:])
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIllegalMove, san)
+	}
+	disambiguation := strings.ReplaceAll(move[:len(move)-2], "x", "")
+	disambiguation = strings.ReplaceAll(disambiguation, ":", "")
+	fromFile, fromRank := -1, -1
+	for _, char := range []byte(disambiguation) {
+		switch {
+		case char >= 'a' && char <= 'h':
+			fromFile = int(char - 'a')
+		case char >= '1' && char <= '8':
+			fromRank = int(char - '1')
+		default:
+			return fmt.Errorf("%w: %v", ErrIllegalMove, san)
+		}
+	}
+	if (promotion != 0) != (kind == 'P' && rank(to) == backRank(1-position.turn)) {
+		return fmt.Errorf("%w: %v", ErrIllegalMove, san)
+	}
+
+	piece := pieceOf(position.turn, kind)
+	candidates := []int{}
+	for from := 0; from < 64; from++ {
+		if position.board[from] != piece {
+			continue
+		}
+		if (fromFile >= 0 && file(from) != fromFile) || (fromRank >= 0 && rank(from) != fromRank) {
+			continue
+		}
+		if !position.canMove(from, to) {
+			continue
+		}
+		afterMove := *position
+		afterMove.move(from, to, promotion)
+		if afterMove.isInCheck(position.turn) {
+			continue
+		}
+		candidates = append(candidates, from)
+	}
+	if len(candidates) != 1 {
+		return fmt.Errorf("%w: %v matches %d pieces", ErrIllegalMove, san, len(candidates))
+	}
+
+	position.apply(candidates[0], to, promotion)
+	return nil
+}
+
+// canMove tells whether the piece on from may go to the square to, the pins are checked by the caller
+func (position Position) canMove(from int, to int) bool {
+	piece := position.board[from]
+	target := position.board[to]
+	if target != 0 && colorOf(target) == colorOf(piece) {
+		return false
+	}
+	if kindOf(piece) != 'P' {
+		return position.reaches(from, to)
+	}
+
+	forward := 8
+	if colorOf(piece) == black {
+		forward = -8
+	}
+	if file(from) == file(to) {
+		if target != 0 {
+			return false
+		}
+		if to-from == forward {
+			return true
+		}
+		startRank := 1
+		if colorOf(piece) == black {
+			startRank = 6
+		}
+		return to-from == 2*forward && rank(from) == startRank && position.board[from+forward] == 0
+	}
+	return position.reaches(from, to) && (target != 0 || to == position.enPassant)
+}
+
+func (position *Position) apply(from int, to int, promotion byte) {
+	piece := position.board[from]
+	isCapture := position.board[to] != 0 || (kindOf(piece) == 'P' && file(from) != file(to))
+
+	position.move(from, to, promotion)
+
+	position.enPassant = noSquare
+	if kindOf(piece) == 'P' && abs(to-from) == 16 {
+		position.enPassant = (from + to) / 2
+	}
+	if kindOf(piece) == 'K' {
+		position.castlingRooks[position.turn] = [2]int{noSquare, noSquare}
+	}
+	for color := range position.castlingRooks {
+		for side, rook := range position.castlingRooks[color] {
+			if rook == from || rook == to {
+				position.castlingRooks[color][side] = noSquare
+			}
+		}
+	}
+
+	position.halfmoveClock++
+	if kindOf(piece) == 'P' || isCapture {
+		position.halfmoveClock = 0
+	}
+	position.pass()
+}
+
+func (position *Position) castle(side int) error {
+	rook := position.castlingRooks[position.turn][side]
+	king, kingExists := position.find(pieceOf(position.turn, 'K'))
+	if rook == noSquare || !kingExists {
+		return fmt.Errorf("%w: castling is not allowed", ErrIllegalMove)
+	}
+
+	kingTo, rookTo := squareOf(6, backRank(position.turn)), squareOf(5, backRank(position.turn))
+	if side == queenSide {
+		kingTo, rookTo = squareOf(2, backRank(position.turn)), squareOf(3, backRank(position.turn))
+	}
+	// the squares the king and the rook cross are empty but for themselves, the rooks of chess960 may stand on either side
+	for square := min(king, rook, kingTo, rookTo); square <= max(king, rook, kingTo, rookTo); square++ {
+		if square != king && square != rook && position.board[square] != 0 {
+			return fmt.Errorf("%w: castling is blocked on %v", ErrIllegalMove, squareName(square))
+		}
+	}
+	// the king does not castle out of, through or into check
+	for square := min(king, kingTo); square <= max(king, kingTo); square++ {
+		if position.isAttacked(square, 1-position.turn) {
+			return fmt.Errorf("%w: castling king is attacked on %v", ErrIllegalMove, squareName(square))
+		}
+	}
+
+	afterCastling := *position
+	afterCastling.board[king] = 0
+	afterCastling.board[rook] = 0
+	afterCastling.board[kingTo] = pieceOf(position.turn, 'K')
+	afterCastling.board[rookTo] = pieceOf(position.turn, 'R')
+	if afterCastling.isInCheck(position.turn) {
+		return fmt.Errorf("%w: castling king is attacked on %v", ErrIllegalMove, squareName(kingTo))
+	}
+	position.board = afterCastling.board
+
+	position.castlingRooks[position.turn] = [2]int{noSquare, noSquare}
+	position.enPassant = noSquare
+	position.halfmoveClock++
+	position.pass()
+	return nil
+}
+
+func (position *Position) pass() {
+	if position.turn == black {
+		position.fullmoveNumber++
+	}
+	position.turn = 1 - position.turn
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+func sign(value int) int {
+	switch {
+	case value > 0:
+		return 1
+	case value < 0:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package chess
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func playAll(t *testing.T, fen string, moves ...string) Position {
+	position, err := ParseFen(fen)
+	assert.NoError(t, err)
+	for _, move := range moves {
+		err = position.Play(move)
+		assert.NoError(t, err, move)
+	}
+	return position
+}
+
+func Test_Play_should_take_the_pawn_passed_by_en_passant(t *testing.T) {
+	position := playAll(t, StartingFen, "e4", "a6", "e5", "d5", "exd6")
+	assert.Equal(t, "rnbqkbnr/1pp1pppp/p2P4/8/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 3", position.Fen())
+}
+
+func Test_Play_should_promote_the_pawn(t *testing.T) {
+	position := playAll(t, "8/4P1k1/8/8/8/8/8/4K3 w - - 0 1", "e8=N+")
+	assert.Equal(t, "4N3/6k1/8/8/8/8/8/4K3 b - - 0 1", position.Fen())
+}
+
+func Test_Play_should_choose_the_piece_that_is_not_pinned(t *testing.T) {
+	position := playAll(t, "4k3/8/8/8/1b6/8/3N1N2/4K3 w - - 0 1", "Ne4")
+	assert.Equal(t, "4k3/8/8/8/1b2N3/8/3N4/4K3 b - - 1 1", position.Fen())
+}
+
+func Test_Play_should_reject_an_ambiguous_move(t *testing.T) {
+	position, err := ParseFen("4k3/8/8/8/8/8/3N1N2/4K3 w - - 0 1")
+	assert.NoError(t, err)
+	err = position.Play("Ne4")
+	assert.ErrorIs(t, err, ErrIllegalMove)
+}
+
+func Test_Play_should_drop_the_castling_of_a_moved_rook(t *testing.T) {
+	position := playAll(t, StartingFen, "h4", "a5", "Rh3", "Ra6")
+	assert.Equal(t, "1nbqkbnr/1ppppppp/r7/p7/7P/7R/PPPPPPP1/RNBQKBN1 w Qk - 2 3", position.Fen())
+}
+
+func Test_Fen_should_write_the_file_of_a_rook_that_is_not_the_outermost(t *testing.T) {
+	position, err := ParseFen("4k3/8/8/8/8/8/8/R1R1K2R w CK - 0 1")
+	assert.NoError(t, err)
+	assert.Equal(t, "4k3/8/8/8/8/8/8/R1R1K2R w KC - 0 1", position.Fen())
+}
+
+func Test_Play_should_castle_when_the_squares_are_empty_and_not_attacked(t *testing.T) {
+	position := playAll(t, "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "O-O", "O-O-O")
+	assert.Equal(t, "2kr3r/8/8/8/8/8/8/R4RK1 w - - 2 2", position.Fen())
+}
+
+func Test_Play_should_reject_a_blocked_castling(t *testing.T) {
+	position, err := ParseFen("4k3/8/8/8/8/8/8/RN2K1NR w KQ - 0 1")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, position.Play("O-O"), ErrIllegalMove)
+	assert.ErrorIs(t, position.Play("O-O-O"), ErrIllegalMove)
+}
+
+func Test_Play_should_reject_a_castling_out_of_check(t *testing.T) {
+	position, err := ParseFen("4r1k1/8/8/8/8/8/8/4K2R w K - 0 1")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, position.Play("O-O"), ErrIllegalMove)
+}
+
+func Test_Play_should_reject_a_castling_through_check(t *testing.T) {
+	position, err := ParseFen("5rk1/8/8/8/8/8/8/4K2R w K - 0 1")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, position.Play("O-O"), ErrIllegalMove)
+}
+
+func Test_Play_should_reject_a_castling_into_check(t *testing.T) {
+	position, err := ParseFen("2r3k1/8/8/8/8/8/8/R3K3 w Q - 0 1")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, position.Play("O-O-O"), ErrIllegalMove)
+}
@@ -0,0 +1,88 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	freeSquare     = '-'
+	occupiedSquare = 'o'
+	unknownSquare  = '?'
+)
+
+// ProbabilisticBoard is the board of a search fen. A square holds a piece of the fen, is free, is occupied
+// by an unknown piece or is unknown. It is read as the core reads it, so both find the same games
+type ProbabilisticBoard struct {
+	squares [64]byte
+}
+
+// ReadSearchFen reads the first word of the search fen, it has 8 rows of 8 characters separated by slashes.
+// A row is read from the file a, ? is an unknown square, o, O and 0 are squares occupied by an unknown piece,
+// the letters of the fen are the pieces and every other character is a free square
+func ReadSearchFen(searchFen string) (board ProbabilisticBoard, err error) {
+	word := strings.TrimFunc(searchFen, func(char rune) bool { return char <= ' ' })
+	if index := strings.IndexByte(word, ' '); index >= 0 {
+		word = word[:index]
+	}
+
+	if strings.Count(word, "/") != 7 {
+		err = fmt.Errorf("search fen %v does not have 8 rows", searchFen)
+		return
+	}
+
+	// the empty rows at the end are dropped and the characters are counted in the units of utf-16 as the core does
+	rows := strings.Split(word, "/")
+	for len(rows) > 0 && rows[len(rows)-1] == "" {
+		rows = rows[:len(rows)-1]
+	}
+	for _, row := range rows {
+		if len(utf16.Encode([]rune(row))) != 8 {
+			err = fmt.Errorf("row %v of search fen %v does not have 8 squares", row, searchFen)
+			return
+		}
+	}
+
+	for i := range board.squares {
+		board.squares[i] = freeSquare
+	}
+	for i, row := range rows {
+		currentRank := 7 - i
+		for currentFile, char := range utf16.Encode([]rune(row)) {
+			switch {
+			case char == 'o' || char == 'O' || char == '0':
+				board.squares[squareOf(currentFile, currentRank)] = occupiedSquare
+			case char == '?':
+				board.squares[squareOf(currentFile, currentRank)] = unknownSquare
+			case char < 128 && strings.IndexByte("PNBRQKpnbrqk", byte(char)) >= 0:
+				board.squares[squareOf(currentFile, currentRank)] = byte(char)
+			}
+		}
+	}
+	return
+}
+
+// Includes tells whether the position has the pieces of the board on their squares, has some piece
+// on the occupied squares and nothing on the free ones
+func (board ProbabilisticBoard) Includes(position Position) bool {
+	for square, expected := range board.squares {
+		actual := position.board[square]
+		switch expected {
+		case unknownSquare:
+		case freeSquare:
+			if actual != 0 {
+				return false
+			}
+		case occupiedSquare:
+			if actual == 0 {
+				return false
+			}
+		default:
+			if actual != expected {
+				return false
+			}
+		}
+	}
+	return true
+}
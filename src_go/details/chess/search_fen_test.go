@@ -0,0 +1,61 @@
+package chess
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ReadSearchFen_should_read_only_the_first_word(t *testing.T) {
+	_, err := ReadSearchFen("  rnbqkbnr/pppppppp/--------/--------/--------/--------/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	assert.NoError(t, err)
+}
+
+func Test_ReadSearchFen_should_reject_the_boards_that_are_not_8_rows_of_8_squares(t *testing.T) {
+	for _, searchFen := range []string{
+		"",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR",
+		"rnbqkbnr/pppppppp/--------/--------/--------/--------/PPPPPPPP",
+		"rnbqkbnr/pppppppp/--------/--------/--------/--------/PPPPPPPP/RNBQKBNR/",
+		"rnbqkbnr/ppppppp/--------/--------/--------/--------/PPPPPPPP/RNBQKBNR",
+	} {
+		_, err := ReadSearchFen(searchFen)
+		assert.Error(t, err, searchFen)
+	}
+}
+
+func Test_ReadSearchFen_should_take_the_empty_last_row_as_a_free_rank(t *testing.T) {
+	board, err := ReadSearchFen("????????/????????/????????/????????/????????/????????/????????/")
+	assert.NoError(t, err)
+
+	position, err := ParseFen(StartingFen)
+	assert.NoError(t, err)
+	assert.False(t, board.Includes(position))
+
+	position, err = ParseFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/8 w - - 0 1")
+	assert.NoError(t, err)
+	assert.True(t, board.Includes(position))
+}
+
+func Test_Includes_should_check_the_pieces_the_occupied_and_the_free_squares(t *testing.T) {
+	position, err := ParseFen("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	assert.NoError(t, err)
+
+	cases := map[string]bool{
+		"????????/????????/????????/????????/????P???/????????/????????/????????": true,
+		"????????/????????/????????/????????/????p???/????????/????????/????????": false,
+		"????????/????????/????????/????????/????o???/????-???/????-???/????????": true,
+		"????????/????????/????????/????????/????O???/????????/????0???/????????": false,
+		"rnbqkbnr/pppppppp/--------/--------/----P---/--------/PPPP-PPP/RNBQKBNR": true,
+		"rnbqkbnr/pppppppp/......../--------/----P---/--------/PPPP-PPP/RNBQKBNR": true,
+		"rnbqkbnr/pppppppp/......../--------/----x---/--------/PPPP-PPP/RNBQKBNR": false,
+		"rnbqkbnr/pppppppp/12345678/--------/----P---/--------/PPPP-PPP/RNBQKBNR": true,
+		"rnbqkbnr/pppppppp/--------/--------/--------/--------/PPPPPPPP/RNBQKBNR": false,
+	}
+	for searchFen, expected := range cases {
+		board, err := ReadSearchFen(searchFen)
+		if assert.NoError(t, err, searchFen) {
+			assert.Equal(t, expected, board.Includes(position), searchFen)
+		}
+	}
+}
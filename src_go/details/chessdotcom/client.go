@@ -0,0 +1,239 @@
+package chessdotcom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/fetch"
+	"go.uber.org/zap"
+)
+
+var ErrNotFound = errors.New("chess.com resource not found")
+var ErrGone = errors.New("chess.com resource is gone")
+var ErrRateLimited = errors.New("chess.com rate limit exceeded")
+var ErrServerFailure = errors.New("chess.com server failure")
+var ErrUnexpectedStatus = errors.New("unexpected status code from chess.com")
+var ErrNotModified = errors.New("chess.com resource is not modified")
+var ErrStalled = errors.New("chess.com stopped sending the response")
+
+type Config struct {
+	Url string
+	fetch.Config
+	RequestsPerSecond float64
+}
+
+func DefaultConfig(url string) Config {
+	return Config{
+		Url: url,
+		Config: fetch.Config{
+			Timeout:        10 * time.Second,
+			MaxRetries:     3,
+			InitialBackoff: 500 * time.Millisecond,
+			MaxBackoff:     10 * time.Second,
+		},
+		RequestsPerSecond: 3,
+	}
+}
+
+type Client struct {
+	config      Config
+	fetchClient *fetch.Client
+}
+
+func NewClient(config Config) (client *Client, err error) {
+	baseUrl, err := url.ParseRequestURI(config.Url)
+	if err != nil {
+		return
+	}
+	limiter := limiterFor(baseUrl.Host, config.RequestsPerSecond)
+	client = &Client{
+		config: config,
+		fetchClient: fetch.NewClient(config.Config, fetch.Site{
+			Name:        "chess.com",
+			StatusError: statusError,
+			IsRetryable: isRetryable,
+			ErrStalled:  ErrStalled,
+			Wait:        limiter.wait,
+			Postpone:    limiter.postpone,
+		}),
+	}
+	return
+}
+
+func (client *Client) Profile(logger *zap.Logger, username string) (profile Profile, err error) {
+	err = client.getJson(logger, "/pub/player/"+username, nil, &profile)
+	return
+}
+
+func (client *Client) Archives(logger *zap.Logger, username string) (archives Archives, err error) {
+	err = client.getJson(logger, "/pub/player/"+username+"/games/archives", nil, &archives)
+	return
+}
+
+// ArchivesOfFirstUsedUsername tries the usernames in order and skips the ones not used on chess.com anymore,
+// a player keeps all the usernames they were cached under. The username is empty if none of them is used
+func (client *Client) ArchivesOfFirstUsedUsername(logger *zap.Logger, usernames []string) (username string, archives Archives, err error) {
+	for _, candidate := range usernames {
+		archives, err = client.Archives(logger, candidate)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrGone) {
+			logger.Info("username is not used on chess.com anymore", zap.String("username", candidate))
+			err = nil
+			continue
+		}
+		if err != nil {
+			return
+		}
+		username = candidate
+		return
+	}
+	archives = Archives{}
+	return
+}
+
+// StreamGames requests the monthly archive conditionally when validators of the previous download are known.
+// Games are decoded one by one and handed to consume, so the archive is never held in memory as a whole.
+// ErrNotModified is returned if the archive has not changed since the previous download.
+func (client *Client) StreamGames(
+	logger *zap.Logger,
+	username string,
+	year int,
+	month int,
+	validators Validators,
+	consume func(game Game) error,
+) (latestValidators Validators, err error) {
+	header := http.Header{}
+	if validators.ETag != "" {
+		header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	response, err := client.get(logger, fmt.Sprintf("/pub/player/%s/games/%d/%02d", username, year, month), header)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	err = decodeGames(json.NewDecoder(response.Body), consume)
+	if err != nil {
+		logger.Error("impossible to decode the games from chess.com!", zap.Error(err))
+		return
+	}
+
+	latestValidators = Validators{
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+	}
+	return
+}
+
+// decodeGames walks the {"games": [...]} document token by token, every other field is skipped
+func decodeGames(decoder *json.Decoder, consume func(game Game) error) (err error) {
+	err = expectDelimiter(decoder, '{')
+	if err != nil {
+		return
+	}
+
+	for decoder.More() {
+		var token json.Token
+		token, err = decoder.Token()
+		if err != nil {
+			return
+		}
+
+		if token != "games" {
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		err = expectDelimiter(decoder, '[')
+		if err != nil {
+			return
+		}
+
+		for decoder.More() {
+			game := Game{}
+			err = decoder.Decode(&game)
+			if err != nil {
+				return
+			}
+			err = consume(game)
+			if err != nil {
+				return
+			}
+		}
+
+		err = expectDelimiter(decoder, ']')
+		if err != nil {
+			return
+		}
+	}
+
+	err = expectDelimiter(decoder, '}')
+	return
+}
+
+func expectDelimiter(decoder *json.Decoder, expected json.Delim) (err error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return
+	}
+	if token != expected {
+		err = fmt.Errorf("expected %v but got %v", expected, token)
+	}
+	return
+}
+
+func (client *Client) getJson(logger *zap.Logger, path string, header http.Header, target interface{}) (err error) {
+	response, err := client.get(logger, path, header)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	err = json.NewDecoder(response.Body).Decode(target)
+	if err != nil {
+		logger.Error("impossible to unmarshal the response body from chess.com!", zap.Error(err))
+		return
+	}
+	return
+}
+
+func (client *Client) get(logger *zap.Logger, path string, header http.Header) (response *http.Response, err error) {
+	header = header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Accept", "application/json")
+	return client.fetchClient.Get(logger, client.config.Url+path, header)
+}
+
+func statusError(statusCode int) error {
+	switch {
+	case statusCode == http.StatusNotModified:
+		return ErrNotModified
+	case statusCode == http.StatusNotFound:
+		return ErrNotFound
+	case statusCode == http.StatusGone:
+		return ErrGone
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode >= 500:
+		return fmt.Errorf("%w: status code %d", ErrServerFailure, statusCode)
+	default:
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, statusCode)
+	}
+}
+
+func isRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServerFailure)
+}
@@ -0,0 +1,341 @@
+package chessdotcom
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/fetch"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func testConfig(url string) Config {
+	return Config{
+		Url: url,
+		Config: fetch.Config{
+			Timeout:        time.Second,
+			MaxRetries:     2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     5 * time.Millisecond,
+		},
+		RequestsPerSecond: 0,
+	}
+}
+
+func Test_Client_should_decode_the_profile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "/pub/player/tigran-c-137", request.URL.Path)
+		writer.Write([]byte(`{"@id":"https://api.chess.com/pub/player/tigran-c-137"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(testConfig(server.URL))
+	assert.NoError(t, err)
+
+	profile, err := client.Profile(zap.NewNop(), "tigran-c-137")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.chess.com/pub/player/tigran-c-137", profile.UserId)
+}
+
+func Test_Client_should_not_retry_when_resource_is_not_found_or_gone(t *testing.T) {
+	for statusCode, expectedErr := range map[int]error{http.StatusNotFound: ErrNotFound, http.StatusGone: ErrGone} {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			writer.WriteHeader(statusCode)
+		}))
+
+		client, err := NewClient(testConfig(server.URL))
+		assert.NoError(t, err)
+
+		_, err = client.Archives(zap.NewNop(), "tigran-c-137")
+		assert.True(t, errors.Is(err, expectedErr), "expected %v, got %v", expectedErr, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+		server.Close()
+	}
+}
+
+func Test_Client_should_get_the_archives_of_the_first_username_still_in_use(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/pub/player/tigran-c-137/games/archives":
+			writer.WriteHeader(http.StatusNotFound)
+		case "/pub/player/tigran-c-138/games/archives":
+			writer.Write([]byte(`{"archives":["https://api.chess.com/pub/player/tigran-c-138/games/2022/07"]}`))
+		default:
+			t.Errorf("unexpected path %v", request.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(testConfig(server.URL))
+	assert.NoError(t, err)
+
+	username, archives, err := client.ArchivesOfFirstUsedUsername(zap.NewNop(), []string{"tigran-c-137", "tigran-c-138", "tigran-c-139"})
+	assert.NoError(t, err)
+	assert.Equal(t, "tigran-c-138", username)
+	assert.Equal(t, []string{"https://api.chess.com/pub/player/tigran-c-138/games/2022/07"}, archives.Archives)
+
+	username, archives, err = client.ArchivesOfFirstUsedUsername(zap.NewNop(), []string{"tigran-c-137"})
+	assert.NoError(t, err)
+	assert.Equal(t, "", username)
+	assert.Empty(t, archives.Archives)
+}
+
+func Test_Client_should_retry_server_failures_and_eventually_succeed(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			writer.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		assert.Equal(t, "/pub/player/tigran-c-137/games/2022/07", request.URL.Path)
+		writer.Write([]byte(`{"games":[{"url":"https://www.chess.com/game/live/1","pgn":"1. e4 *","end_time":1658948502}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(testConfig(server.URL))
+	assert.NoError(t, err)
+
+	games := []Game{}
+	_, err = client.StreamGames(zap.NewNop(), "tigran-c-137", 2022, 7, Validators{}, func(game Game) error {
+		games = append(games, game)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Game{{Url: "https://www.chess.com/game/live/1", Pgn: "1. e4 *", EndTime: 1658948502}}, games)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func Test_Client_should_give_up_after_max_retries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(testConfig(server.URL))
+	assert.NoError(t, err)
+
+	_, err = client.Archives(zap.NewNop(), "tigran-c-137")
+	assert.True(t, errors.Is(err, ErrServerFailure), "got %v", err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func Test_Client_should_honor_retry_after_when_rate_limited(t *testing.T) {
+	var calls int32
+	var firstCallAt time.Time
+	var secondCallAt time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			firstCallAt = time.Now()
+			writer.Header().Set("Retry-After", "1")
+			writer.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		secondCallAt = time.Now()
+		writer.Write([]byte(`{"archives":[]}`))
+	}))
+	defer server.Close()
+
+	config := testConfig(server.URL)
+	config.MaxBackoff = 2 * time.Second
+	client, err := NewClient(config)
+	assert.NoError(t, err)
+
+	_, err = client.Archives(zap.NewNop(), "tigran-c-137")
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, secondCallAt.Sub(firstCallAt), time.Second)
+}
+
+func Test_Client_should_give_up_when_retry_after_exceeds_the_max_backoff(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		writer.Header().Set("Retry-After", "3600")
+		writer.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(testConfig(server.URL))
+	assert.NoError(t, err)
+
+	startedAt := time.Now()
+	_, err = client.Archives(zap.NewNop(), "tigran-c-137")
+	assert.True(t, errors.Is(err, ErrRateLimited), "got %v", err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Less(t, time.Since(startedAt), time.Second)
+}
+
+func Test_Client_should_report_rate_limiting_once_retries_are_exhausted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(testConfig(server.URL))
+	assert.NoError(t, err)
+
+	_, err = client.Profile(zap.NewNop(), "tigran-c-137")
+	assert.True(t, errors.Is(err, ErrRateLimited), "got %v", err)
+}
+
+func Test_Client_should_not_retry_other_client_errors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		writer.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(testConfig(server.URL))
+	assert.NoError(t, err)
+
+	_, err = client.Profile(zap.NewNop(), "tigran-c-137")
+	assert.True(t, errors.Is(err, ErrUnexpectedStatus), "got %v", err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func Test_Client_should_request_games_conditionally(t *testing.T) {
+	lastModified := "Wed, 27 Jul 2022 19:01:42 GMT"
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("If-None-Match") == `"abc"` && request.Header.Get("If-Modified-Since") == lastModified {
+			writer.WriteHeader(http.StatusNotModified)
+			return
+		}
+		writer.Header().Set("ETag", `"abc"`)
+		writer.Header().Set("Last-Modified", lastModified)
+		writer.Write([]byte(`{"games":[]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(testConfig(server.URL))
+	assert.NoError(t, err)
+
+	ignore := func(game Game) error { return nil }
+
+	validators, err := client.StreamGames(zap.NewNop(), "tigran-c-137", 2022, 7, Validators{}, ignore)
+	assert.NoError(t, err)
+	assert.Equal(t, Validators{ETag: `"abc"`, LastModified: lastModified}, validators)
+
+	_, err = client.StreamGames(zap.NewNop(), "tigran-c-137", 2022, 7, validators, ignore)
+	assert.True(t, errors.Is(err, ErrNotModified), "got %v", err)
+}
+
+func Test_Client_should_read_an_archive_slower_than_the_timeout_as_long_as_it_keeps_coming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte(`{"games":[`))
+		for i := 0; i < 5; i++ {
+			if i > 0 {
+				writer.Write([]byte(`,`))
+			}
+			writer.Write([]byte(`{"url":"https://www.chess.com/game/live/` + strconv.Itoa(i) + `"}`))
+			writer.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+		writer.Write([]byte(`]}`))
+	}))
+	defer server.Close()
+
+	config := testConfig(server.URL)
+	config.Timeout = 100 * time.Millisecond
+	client, err := NewClient(config)
+	assert.NoError(t, err)
+
+	consumed := 0
+	_, err = client.StreamGames(zap.NewNop(), "tigran-c-137", 2022, 7, Validators{}, func(game Game) error {
+		consumed++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, consumed)
+}
+
+func Test_Client_should_fail_when_the_archive_stalls(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte(`{"games":[{"url":"https://www.chess.com/game/live/1"},`))
+		writer.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	config := testConfig(server.URL)
+	config.Timeout = 100 * time.Millisecond
+	client, err := NewClient(config)
+	assert.NoError(t, err)
+
+	consumed := 0
+	_, err = client.StreamGames(zap.NewNop(), "tigran-c-137", 2022, 7, Validators{}, func(game Game) error {
+		consumed++
+		return nil
+	})
+	assert.True(t, errors.Is(err, ErrStalled), "got %v", err)
+	assert.Equal(t, 1, consumed)
+}
+
+func Test_decodeGames_should_yield_games_one_by_one_and_skip_other_fields(t *testing.T) {
+	document := `{
+		"comment": {"nested": [1, 2, {"games": []}]},
+		"games": [
+			{"url": "https://www.chess.com/game/live/1", "pgn": "1. e4 *", "end_time": 1, "rules": "chess", "white": {"username": "a"}},
+			{"url": "https://www.chess.com/game/live/2", "pgn": "1. d4 *", "end_time": 2}
+		],
+		"trailer": true
+	}`
+
+	games := []Game{}
+	err := decodeGames(json.NewDecoder(strings.NewReader(document)), func(game Game) error {
+		games = append(games, game)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Game{
+		{Url: "https://www.chess.com/game/live/1", Pgn: "1. e4 *", EndTime: 1, Rules: "chess", White: Player{Username: "a"}},
+		{Url: "https://www.chess.com/game/live/2", Pgn: "1. d4 *", EndTime: 2},
+	}, games)
+}
+
+func Test_decodeGames_should_stop_as_soon_as_the_consumer_fails(t *testing.T) {
+	document := `{"games": [{"url": "1"}, {"url": "2"}, {"url": "3"}]}`
+	consumerErr := errors.New("table is on fire")
+
+	consumed := 0
+	err := decodeGames(json.NewDecoder(strings.NewReader(document)), func(game Game) error {
+		consumed++
+		if game.Url == "2" {
+			return consumerErr
+		}
+		return nil
+	})
+
+	assert.Equal(t, consumerErr, err)
+	assert.Equal(t, 2, consumed)
+}
+
+func Test_decodeGames_should_fail_on_malformed_documents(t *testing.T) {
+	for _, document := range []string{`[]`, `{"games": {}}`, `{"games": [{"url": "1"}`} {
+		err := decodeGames(json.NewDecoder(strings.NewReader(document)), func(game Game) error { return nil })
+		assert.Error(t, err, document)
+	}
+}
+
+func Test_limiter_should_space_requests_to_the_same_host(t *testing.T) {
+	hostLimiter := limiterFor("limiter.test", 20)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		hostLimiter.wait()
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	assert.Same(t, hostLimiter, limiterFor("limiter.test", 1))
+}
@@ -0,0 +1,18 @@
+module github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom
+
+go 1.21.0
+
+require (
+	github.com/chessfinder/chessfinder-faster-backend/src_go/details/fetch v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.8.4
+	go.uber.org/zap v1.26.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/fetch => ../../details/fetch
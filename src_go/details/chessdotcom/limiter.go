@@ -0,0 +1,54 @@
+package chessdotcom
+
+import (
+	"sync"
+	"time"
+)
+
+// limiters are shared between all clients of the process, so a warm lambda keeps respecting the limit across invocations
+var limiters = map[string]*limiter{}
+var limitersLock sync.Mutex
+
+type limiter struct {
+	lock     sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func limiterFor(host string, requestsPerSecond float64) *limiter {
+	limitersLock.Lock()
+	defer limitersLock.Unlock()
+
+	hostLimiter, exists := limiters[host]
+	if !exists {
+		interval := time.Duration(0)
+		if requestsPerSecond > 0 {
+			interval = time.Duration(float64(time.Second) / requestsPerSecond)
+		}
+		hostLimiter = &limiter{interval: interval}
+		limiters[host] = hostLimiter
+	}
+	return hostLimiter
+}
+
+func (hostLimiter *limiter) wait() {
+	hostLimiter.lock.Lock()
+	now := time.Now()
+	if hostLimiter.next.Before(now) {
+		hostLimiter.next = now
+	}
+	waitFor := hostLimiter.next.Sub(now)
+	hostLimiter.next = hostLimiter.next.Add(hostLimiter.interval)
+	hostLimiter.lock.Unlock()
+
+	time.Sleep(waitFor)
+}
+
+// postpone makes every request to the host wait at least till the given moment, chess.com asks for it with Retry-After
+func (hostLimiter *limiter) postpone(till time.Time) {
+	hostLimiter.lock.Lock()
+	defer hostLimiter.lock.Unlock()
+	if hostLimiter.next.Before(till) {
+		hostLimiter.next = till
+	}
+}
@@ -0,0 +1,33 @@
+package chessdotcom
+
+type Profile struct {
+	UserId   string `json:"@id"`
+	PlayerId int64  `json:"player_id"`
+}
+
+type Archives struct {
+	Archives []string `json:"archives"`
+}
+
+type Game struct {
+	Url         string `json:"url"`
+	Pgn         string `json:"pgn"`
+	EndTime     int64  `json:"end_time"`
+	TimeControl string `json:"time_control"`
+	TimeClass   string `json:"time_class"`
+	Rules       string `json:"rules"`
+	White       Player `json:"white"`
+	Black       Player `json:"black"`
+}
+
+type Player struct {
+	Username string `json:"username"`
+	Rating   int    `json:"rating"`
+	Result   string `json:"result"`
+}
+
+// Validators of a previously downloaded resource, used to make conditional requests
+type Validators struct {
+	ETag         string
+	LastModified string
+}
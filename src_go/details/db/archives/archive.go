@@ -12,4 +12,7 @@ type ArchiveRecord struct {
 	Month        int              `dynamodbav:"month"`
 	Downloaded   int              `dynamodbav:"downloaded"`
 	DownloadedAt *db.ZuluDateTime `dynamodbav:"downloaded_at"`
+	Uploaded     bool             `dynamodbav:"uploaded,omitempty"`
+	ETag         string           `dynamodbav:"etag,omitempty"`
+	LastModified string           `dynamodbav:"last_modified,omitempty"`
 }
@@ -181,3 +181,57 @@ func Test_ArchiveRecord_should_be_stored_in_correct_form_even_if_some_of_its_fie
 
 	assert.Equal(t, expectedArchive, actualArchive)
 }
+
+func Test_uploaded_ArchiveRecord_should_be_marked_as_uploaded(t *testing.T) {
+	downloadedAt := db.Zuludatetime(time.Date(2023, time.October, 1, 11, 30, 17, 123000000, time.UTC))
+
+	archive := ArchiveRecord{
+		UserId:       "userId",
+		ArchiveId:    "upload/downloadId",
+		Resource:     "upload/downloadId",
+		Year:         2023,
+		Month:        10,
+		DownloadedAt: &downloadedAt,
+		Downloaded:   2,
+		Uploaded:     true,
+	}
+
+	actualMarshalledItems, err := dynamodbattribute.MarshalMap(archive)
+	assert.NoError(t, err)
+
+	assert.Equal(t, &dynamodb.AttributeValue{BOOL: aws.Bool(true)}, actualMarshalledItems["uploaded"])
+
+	actualArchive := ArchiveRecord{}
+	err = dynamodbattribute.UnmarshalMap(actualMarshalledItems, &actualArchive)
+	assert.NoError(t, err)
+
+	assert.Equal(t, archive, actualArchive)
+}
+
+func Test_ArchiveRecord_should_keep_the_validators_of_the_last_download(t *testing.T) {
+	downloadedAt := db.Zuludatetime(time.Date(2023, time.October, 1, 11, 30, 17, 123000000, time.UTC))
+
+	archive := ArchiveRecord{
+		UserId:       "userId",
+		ArchiveId:    "archiveId",
+		Resource:     "archiveId",
+		Year:         2023,
+		Month:        10,
+		DownloadedAt: &downloadedAt,
+		Downloaded:   2,
+		ETag:         `"5c3f0b1f"`,
+		LastModified: "Sun, 01 Oct 2023 11:30:17 GMT",
+	}
+
+	actualMarshalledItems, err := dynamodbattribute.MarshalMap(archive)
+	assert.NoError(t, err)
+
+	assert.Equal(t, &dynamodb.AttributeValue{S: aws.String(`"5c3f0b1f"`)}, actualMarshalledItems["etag"])
+	assert.Equal(t, &dynamodb.AttributeValue{S: aws.String("Sun, 01 Oct 2023 11:30:17 GMT")}, actualMarshalledItems["last_modified"])
+
+	actualArchive := ArchiveRecord{}
+	err = dynamodbattribute.UnmarshalMap(actualMarshalledItems, &actualArchive)
+	assert.NoError(t, err)
+
+	assert.Equal(t, archive, actualArchive)
+}
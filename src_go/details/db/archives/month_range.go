@@ -0,0 +1,71 @@
+package archives
+
+import (
+	"fmt"
+	"time"
+)
+
+const yearMonthLayout = "2006-01"
+
+// MonthRange limits a download to the archives from the month From till the month To inclusive, a zero bound is open
+type MonthRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParseMonthRange reads bounds like 2023-01, an empty bound is open
+func ParseMonthRange(from string, to string) (monthRange MonthRange, err error) {
+	if from != "" {
+		monthRange.From, err = time.Parse(yearMonthLayout, from)
+		if err != nil {
+			err = fmt.Errorf("from %v is not a year-month like 2023-01", from)
+			return
+		}
+	}
+	if to != "" {
+		monthRange.To, err = time.Parse(yearMonthLayout, to)
+		if err != nil {
+			err = fmt.Errorf("to %v is not a year-month like 2023-01", to)
+			return
+		}
+	}
+	if !monthRange.From.IsZero() && !monthRange.To.IsZero() && monthRange.From.After(monthRange.To) {
+		err = fmt.Errorf("from %v is after to %v", from, to)
+		return
+	}
+	return
+}
+
+func (monthRange MonthRange) Contains(year int, month int) bool {
+	archiveMonth := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	if !monthRange.From.IsZero() && archiveMonth.Before(monthRange.From) {
+		return false
+	}
+	if !monthRange.To.IsZero() && archiveMonth.After(monthRange.To) {
+		return false
+	}
+	return true
+}
+
+// FilterUrls keeps the archive urls of the months in the range, the ones without a month are kept as they are
+func (monthRange MonthRange) FilterUrls(archiveUrls []string) (archiveUrlsInRange []string) {
+	archiveUrlsInRange = make([]string, 0, len(archiveUrls))
+	for _, archiveUrl := range archiveUrls {
+		year, month, err := Month(archiveUrl)
+		if err == nil && !monthRange.Contains(year, month) {
+			continue
+		}
+		archiveUrlsInRange = append(archiveUrlsInRange, archiveUrl)
+	}
+	return
+}
+
+func (monthRange MonthRange) FilterRecords(archiveRecords []ArchiveRecord) (archiveRecordsInRange []ArchiveRecord) {
+	archiveRecordsInRange = make([]ArchiveRecord, 0, len(archiveRecords))
+	for _, archiveRecord := range archiveRecords {
+		if monthRange.Contains(archiveRecord.Year, archiveRecord.Month) {
+			archiveRecordsInRange = append(archiveRecordsInRange, archiveRecord)
+		}
+	}
+	return
+}
@@ -0,0 +1,54 @@
+package archives
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MonthRange_should_keep_only_archives_of_months_in_range(t *testing.T) {
+	monthRange, err := ParseMonthRange("2021-11", "2022-01")
+	assert.NoError(t, err)
+
+	archiveUrls := []string{
+		"https://api.chess.com/pub/player/tigran-c-137/games/2021/10",
+		"https://api.chess.com/pub/player/tigran-c-137/games/2021/11",
+		"https://api.chess.com/pub/player/tigran-c-137/games/2021/12",
+		"https://api.chess.com/pub/player/tigran-c-137/games/2022/01",
+		"https://api.chess.com/pub/player/tigran-c-137/games/2022/02",
+	}
+	expectedArchiveUrls := []string{
+		"https://api.chess.com/pub/player/tigran-c-137/games/2021/11",
+		"https://api.chess.com/pub/player/tigran-c-137/games/2021/12",
+		"https://api.chess.com/pub/player/tigran-c-137/games/2022/01",
+	}
+	assert.Equal(t, expectedArchiveUrls, monthRange.FilterUrls(archiveUrls))
+
+	archiveRecords := []ArchiveRecord{
+		{ArchiveId: "2021/10", Year: 2021, Month: 10},
+		{ArchiveId: "2022/01", Year: 2022, Month: 1},
+	}
+	assert.Equal(t, archiveRecords[1:], monthRange.FilterRecords(archiveRecords))
+}
+
+func Test_MonthRange_should_be_open_without_bounds(t *testing.T) {
+	monthRange, err := ParseMonthRange("", "")
+	assert.NoError(t, err)
+	assert.True(t, monthRange.Contains(2007, 1))
+
+	monthRange, err = ParseMonthRange("2023-05", "")
+	assert.NoError(t, err)
+	assert.False(t, monthRange.Contains(2023, 4))
+	assert.True(t, monthRange.Contains(2030, 12))
+}
+
+func Test_ParseMonthRange_should_reject_invalid_bounds(t *testing.T) {
+	_, err := ParseMonthRange("2023-13", "")
+	assert.Error(t, err)
+
+	_, err = ParseMonthRange("", "last year")
+	assert.Error(t, err)
+
+	_, err = ParseMonthRange("2023-05", "2023-04")
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package archives
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResolveMissing treats an archive as existing if it is stored under the same url or for the same month.
+// Archive urls contain the username, so after a rename the archives of the previous username are still found by month.
+func ResolveMissing(
+	archiveUrls []string,
+	archivesFromDb []ArchiveRecord,
+) (missingArchives []string) {
+	existingArchives := make(map[string]ArchiveRecord, len(archivesFromDb))
+	existingMonths := make(map[[2]int]struct{}, len(archivesFromDb))
+	for _, archiveFromDb := range archivesFromDb {
+		existingArchives[archiveFromDb.ArchiveId] = archiveFromDb
+		if !archiveFromDb.Uploaded {
+			existingMonths[[2]int{archiveFromDb.Year, archiveFromDb.Month}] = struct{}{}
+		}
+	}
+
+	missingArchives = make([]string, 0)
+	for _, archiveUrl := range archiveUrls {
+		if _, ok := existingArchives[archiveUrl]; ok {
+			continue
+		}
+		if year, month, err := Month(archiveUrl); err == nil {
+			if _, ok := existingMonths[[2]int{year, month}]; ok {
+				continue
+			}
+		}
+		missingArchives = append(missingArchives, archiveUrl)
+	}
+	return
+}
+
+// Month extracts the month from archive urls, both chess.com and lichess ones end with /{year}/{month}
+func Month(archiveUrl string) (year int, month int, err error) {
+	archiveSegments := strings.Split(archiveUrl, "/")
+	if len(archiveSegments) < 2 {
+		err = fmt.Errorf("archive url %v has no month", archiveUrl)
+		return
+	}
+
+	year, err = strconv.Atoi(archiveSegments[len(archiveSegments)-2])
+	if err != nil {
+		return
+	}
+
+	month, err = strconv.Atoi(archiveSegments[len(archiveSegments)-1])
+	return
+}
+
+// ResolveToDownload picks the stored archives that could have got new games since they were downloaded
+func ResolveToDownload(
+	archivesFromDb []ArchiveRecord,
+) (archivesToDownload []ArchiveRecord) {
+	archivesToDownload = make([]ArchiveRecord, 0)
+	for _, archiveFromDb := range archivesFromDb {
+		if archiveFromDb.Uploaded {
+			continue
+		}
+		if archiveFromDb.DownloadedAt == nil {
+			archivesToDownload = append(archivesToDownload, archiveFromDb)
+			continue
+		}
+		archiveHasGamesTill := time.Date(archiveFromDb.Year, time.Month(archiveFromDb.Month), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+		if archiveFromDb.DownloadedAt.ToTime().Before(archiveHasGamesTill) {
+			archivesToDownload = append(archivesToDownload, archiveFromDb)
+		}
+	}
+	return
+}
+
+// LichessArchives splits the history of a lichess user into monthly pseudo-archives, since lichess does not have archives,
+// starting from the month the account was created till the current month.
+func LichessArchives(lichessUrl string, username string, createdAt time.Time, now time.Time) (archiveUrls []string) {
+	archiveUrls = make([]string, 0)
+	month := time.Date(createdAt.UTC().Year(), createdAt.UTC().Month(), 1, 0, 0, 0, 0, time.UTC)
+	for !month.After(now.UTC()) {
+		archiveUrl := fmt.Sprintf("%s/api/games/user/%s/%d/%02d", lichessUrl, username, month.Year(), int(month.Month()))
+		archiveUrls = append(archiveUrls, archiveUrl)
+		month = month.AddDate(0, 1, 0)
+	}
+	return
+}
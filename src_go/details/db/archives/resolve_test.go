@@ -0,0 +1,59 @@
+package archives
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ResolveMissing_should_recognise_stored_archives_by_month(t *testing.T) {
+	downloadedAt := db.Zuludatetime(time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC))
+	archivesFromDb := []ArchiveRecord{
+		{
+			ArchiveId:    "https://api.chess.com/pub/player/previous/games/2021/10",
+			Year:         2021,
+			Month:        10,
+			DownloadedAt: &downloadedAt,
+		},
+		{
+			ArchiveId:    "upload/d6c5e5b4-8a43-4a64-9a4a-3d6e0d4c6f0b",
+			Year:         2021,
+			Month:        12,
+			DownloadedAt: &downloadedAt,
+			Uploaded:     true,
+		},
+	}
+
+	archiveUrls := []string{
+		"https://api.chess.com/pub/player/current/games/2021/10",
+		"https://api.chess.com/pub/player/current/games/2021/11",
+		"https://api.chess.com/pub/player/current/games/2021/12",
+	}
+
+	actualMissingArchives := ResolveMissing(archiveUrls, archivesFromDb)
+
+	expectedMissingArchives := []string{
+		"https://api.chess.com/pub/player/current/games/2021/11",
+		"https://api.chess.com/pub/player/current/games/2021/12",
+	}
+
+	assert.Equal(t, expectedMissingArchives, actualMissingArchives)
+}
+
+func Test_lichess_history_is_split_into_monthly_archives_till_the_current_month(t *testing.T) {
+	createdAt := time.Date(2022, time.November, 17, 21, 3, 12, 0, time.UTC)
+	now := time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)
+
+	actualArchives := LichessArchives("https://lichess.org", "tigran-c-137", createdAt, now)
+
+	expectedArchives := []string{
+		"https://lichess.org/api/games/user/tigran-c-137/2022/11",
+		"https://lichess.org/api/games/user/tigran-c-137/2022/12",
+		"https://lichess.org/api/games/user/tigran-c-137/2023/01",
+		"https://lichess.org/api/games/user/tigran-c-137/2023/02",
+	}
+
+	assert.Equal(t, expectedArchives, actualArchives)
+}
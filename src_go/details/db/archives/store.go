@@ -0,0 +1,105 @@
+package archives
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/batcher"
+)
+
+// GetArchiveRecords reads all the archives of the user, a page of the query holds at most 1 MB of them
+func GetArchiveRecords(
+	dynamodbClient *dynamodb.DynamoDB,
+	archivesTableName string,
+	userId string,
+) (archiveRecords []ArchiveRecord, err error) {
+	archiveRecords = []ArchiveRecord{}
+	var lastKey map[string]*dynamodb.AttributeValue
+	for {
+		var response *dynamodb.QueryOutput
+		response, err = dynamodbClient.Query(&dynamodb.QueryInput{
+			TableName:              aws.String(archivesTableName),
+			KeyConditionExpression: aws.String("user_id = :user_id"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":user_id": {
+					S: aws.String(userId),
+				},
+			},
+			ExclusiveStartKey: lastKey,
+		})
+		if err != nil {
+			return
+		}
+
+		pageOfArchiveRecords := []ArchiveRecord{}
+		err = dynamodbattribute.UnmarshalListOfMaps(response.Items, &pageOfArchiveRecords)
+		if err != nil {
+			return
+		}
+		archiveRecords = append(archiveRecords, pageOfArchiveRecords...)
+
+		lastKey = response.LastEvaluatedKey
+		if len(lastKey) == 0 {
+			return
+		}
+	}
+}
+
+// PersistMissing stores the archives the platform has and the cache does not, none of their games are downloaded yet
+func PersistMissing(
+	dynamodbClient *dynamodb.DynamoDB,
+	archivesTableName string,
+	userId string,
+	missingArchiveUrls []string,
+) (missingArchiveRecords []ArchiveRecord, err error) {
+	missingArchiveRecordWriteRequests := make([]*dynamodb.WriteRequest, 0, len(missingArchiveUrls))
+	for _, missingArchiveUrl := range missingArchiveUrls {
+		var year, month int
+		year, month, err = Month(missingArchiveUrl)
+		if err != nil {
+			return
+		}
+
+		missingArchiveRecord := ArchiveRecord{
+			UserId:    userId,
+			ArchiveId: missingArchiveUrl,
+			Resource:  missingArchiveUrl,
+			Year:      year,
+			Month:     month,
+		}
+		missingArchiveRecords = append(missingArchiveRecords, missingArchiveRecord)
+
+		var missingArchiveRecordItems map[string]*dynamodb.AttributeValue
+		missingArchiveRecordItems, err = dynamodbattribute.MarshalMap(missingArchiveRecord)
+		if err != nil {
+			return
+		}
+		missingArchiveRecordWriteRequests = append(missingArchiveRecordWriteRequests, &dynamodb.WriteRequest{
+			PutRequest: &dynamodb.PutRequest{
+				Item: missingArchiveRecordItems,
+			},
+		})
+	}
+
+	for _, batch := range batcher.Batcher(missingArchiveRecordWriteRequests, 25) {
+		unprocessedWriteRequests := map[string][]*dynamodb.WriteRequest{
+			archivesTableName: batch,
+		}
+		for len(unprocessedWriteRequests) > 0 {
+			var writeOutput *dynamodb.BatchWriteItemOutput
+			writeOutput, err = dynamodbClient.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+				RequestItems: unprocessedWriteRequests,
+			})
+			if err != nil {
+				return
+			}
+			unprocessedWriteRequests = writeOutput.UnprocessedItems
+			if len(unprocessedWriteRequests) > 0 {
+				time.Sleep(time.Millisecond * 100)
+			}
+		}
+	}
+	return
+}
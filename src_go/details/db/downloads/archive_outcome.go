@@ -0,0 +1,27 @@
+package downloads
+
+import "github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/users"
+
+type ArchiveState string
+
+const (
+	ArchiveSucceeded ArchiveState = "SUCCEEDED"
+	ArchiveFailed    ArchiveState = "FAILED"
+	ArchiveCancelled ArchiveState = "CANCELLED"
+	// ArchiveRetrying is a failed archive that is queued again and waits for its new outcome
+	ArchiveRetrying ArchiveState = "RETRYING"
+)
+
+// ArchiveOutcomeRecord is what happened to one archive of a download, it is overwritten when the archive is retried.
+// It keeps the user the archive was downloaded for, so the archive can be queued again without the original command.
+type ArchiveOutcomeRecord struct {
+	DownloadId     string         `dynamodbav:"download_id"`
+	ArchiveId      string         `dynamodbav:"archive_id"`
+	UserId         string         `dynamodbav:"user_id"`
+	Username       string         `dynamodbav:"username"`
+	Platform       users.Platform `dynamodbav:"platform"`
+	State          ArchiveState   `dynamodbav:"state"`
+	ErrorCode      string         `dynamodbav:"error_code,omitempty"`
+	GamesAdded     int            `dynamodbav:"games_added"`
+	DurationMillis int64          `dynamodbav:"duration_millis"`
+}
@@ -0,0 +1,96 @@
+package downloads
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/users"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const archiveOutcomesTableName = "chessfinder_dynamodb-archiveOutcomes"
+
+func Test_ArchiveOutcomeRecord_should_be_stored_in_correct_form(t *testing.T) {
+	downloadId := uuid.New().String()
+	archiveId := uuid.New().String()
+	userId := uuid.New().String()
+
+	archiveOutcome := ArchiveOutcomeRecord{
+		DownloadId:     downloadId,
+		ArchiveId:      archiveId,
+		UserId:         userId,
+		Username:       "tigran-c-137",
+		Platform:       users.ChessDotCom,
+		State:          ArchiveFailed,
+		ErrorCode:      "SOURCE_UNAVAILABLE",
+		GamesAdded:     12,
+		DurationMillis: 1500,
+	}
+
+	actualMarshalledItems, err := dynamodbattribute.MarshalMap(archiveOutcome)
+	assert.NoError(t, err)
+
+	expectedMarshalledItems := map[string]*dynamodb.AttributeValue{
+		"download_id": {
+			S: aws.String(downloadId),
+		},
+		"archive_id": {
+			S: aws.String(archiveId),
+		},
+		"user_id": {
+			S: aws.String(userId),
+		},
+		"username": {
+			S: aws.String("tigran-c-137"),
+		},
+		"platform": {
+			S: aws.String("CHESS_DOT_COM"),
+		},
+		"state": {
+			S: aws.String("FAILED"),
+		},
+		"error_code": {
+			S: aws.String("SOURCE_UNAVAILABLE"),
+		},
+		"games_added": {
+			N: aws.String("12"),
+		},
+		"duration_millis": {
+			N: aws.String("1500"),
+		},
+	}
+
+	assert.Equal(t, expectedMarshalledItems, actualMarshalledItems)
+
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(archiveOutcomesTableName),
+		Item:      actualMarshalledItems,
+	})
+
+	assert.NoError(t, err)
+
+	getArchiveOutcomeOutput, err := dynamodbClient.GetItem(
+		&dynamodb.GetItemInput{
+			TableName: aws.String(archiveOutcomesTableName),
+			Key: map[string]*dynamodb.AttributeValue{
+				"download_id": {
+					S: aws.String(downloadId),
+				},
+				"archive_id": {
+					S: aws.String(archiveId),
+				},
+			},
+		},
+	)
+
+	assert.NoError(t, err)
+
+	actualArchiveOutcome := ArchiveOutcomeRecord{}
+	err = dynamodbattribute.UnmarshalMap(getArchiveOutcomeOutput.Item, &actualArchiveOutcome)
+	assert.NoError(t, err)
+
+	assert.Equal(t, archiveOutcome, actualArchiveOutcome)
+}
@@ -1,12 +1,18 @@
 package downloads
 
 type DownloadRecord struct {
-	DownloadId string `dynamodbav:"download_id"`
-	Succeed    int    `dynamodbav:"succeed"`
-	Failed     int    `dynamodbav:"failed"`
-	Done       int    `dynamodbav:"done"`
-	Pending    int    `dynamodbav:"pending"`
-	Total      int    `dynamodbav:"total"`
+	DownloadId      string `dynamodbav:"download_id"`
+	Succeed         int    `dynamodbav:"succeed"`
+	Failed          int    `dynamodbav:"failed"`
+	Cancelled       int    `dynamodbav:"cancelled"`
+	Done            int    `dynamodbav:"done"`
+	Pending         int    `dynamodbav:"pending"`
+	Total           int    `dynamodbav:"total"`
+	CancelRequested bool   `dynamodbav:"cancel_requested,omitempty"`
+	CallbackUrl     string `dynamodbav:"callback_url,omitempty"`
+	CallbackSecret  string `dynamodbav:"callback_secret,omitempty"`
+	// Notified is set once the completion is sent to the callback url
+	Notified bool `dynamodbav:"notified,omitempty"`
 }
 
 func NewDownloadRecord(downloadId string, total int) DownloadRecord {
@@ -14,6 +20,7 @@ func NewDownloadRecord(downloadId string, total int) DownloadRecord {
 		DownloadId: downloadId,
 		Failed:     0,
 		Succeed:    0,
+		Cancelled:  0,
 		Done:       0,
 		Pending:    total,
 		Total:      total,
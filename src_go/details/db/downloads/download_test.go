@@ -27,17 +27,20 @@ func Test_DownloadRecord_should_be_stored_in_correct_form(t *testing.T) {
 	downloadId := uuid.New().String()
 	succeed := 1
 	failed := 2
-	done := 3
-	pending := 4
+	cancelled := 1
+	done := 4
+	pending := 3
 	total := 7
 
 	download := DownloadRecord{
-		DownloadId: downloadId,
-		Succeed:    succeed,
-		Failed:     failed,
-		Done:       done,
-		Pending:    pending,
-		Total:      total,
+		DownloadId:      downloadId,
+		Succeed:         succeed,
+		Failed:          failed,
+		Cancelled:       cancelled,
+		Done:            done,
+		Pending:         pending,
+		Total:           total,
+		CancelRequested: true,
 	}
 
 	actualMarshalledItems, err := dynamodbattribute.MarshalMap(download)
@@ -53,15 +56,21 @@ func Test_DownloadRecord_should_be_stored_in_correct_form(t *testing.T) {
 		"failed": {
 			N: aws.String("2"),
 		},
+		"cancelled": {
+			N: aws.String("1"),
+		},
 		"done": {
-			N: aws.String("3"),
+			N: aws.String("4"),
 		},
 		"pending": {
-			N: aws.String("4"),
+			N: aws.String("3"),
 		},
 		"total": {
 			N: aws.String("7"),
 		},
+		"cancel_requested": {
+			BOOL: aws.Bool(true),
+		},
 	}
 
 	assert.Equal(t, expectedMarshalledItems, actualMarshalledItems)
@@ -0,0 +1,96 @@
+package games
+
+import "strings"
+
+const (
+	White = "white"
+	Black = "black"
+)
+
+const (
+	Win  = "win"
+	Draw = "draw"
+	Loss = "loss"
+)
+
+// Filter narrows the games down by their metadata, the empty fields do not filter.
+// It has the fields of queue.SearchFilter, so the filter of a command converts into it
+type Filter struct {
+	Username  string
+	Aliases   []string
+	From      int64
+	To        int64
+	Color     string
+	Opponent  string
+	Result    string
+	TimeClass string
+}
+
+// Passes tells whether the game is one of the filtered ones. The games that do not tell who played them
+// do not pass the filters of the color, the opponent and the result
+func (filter Filter) Passes(game GameRecord) bool {
+	if filter.From != 0 && game.EndTimestamp < filter.From {
+		return false
+	}
+	if filter.To != 0 && game.EndTimestamp >= filter.To {
+		return false
+	}
+	if filter.TimeClass != "" && game.TimeClass != filter.TimeClass {
+		return false
+	}
+	if filter.Color == "" && filter.Opponent == "" && filter.Result == "" {
+		return true
+	}
+
+	color, opponent := game.sideOf(filter.Username)
+	for _, alias := range filter.Aliases {
+		if color != "" {
+			break
+		}
+		color, opponent = game.sideOf(alias)
+	}
+	if color == "" {
+		return false
+	}
+	if filter.Color != "" && color != filter.Color {
+		return false
+	}
+	if filter.Opponent != "" && !strings.EqualFold(opponent, filter.Opponent) {
+		return false
+	}
+	if filter.Result != "" && game.resultOf(color) != filter.Result {
+		return false
+	}
+	return true
+}
+
+// sideOf tells the color the player had in the game and the name of the opponent
+func (game GameRecord) sideOf(username string) (color string, opponent string) {
+	switch {
+	case strings.EqualFold(game.White, username):
+		return White, game.Black
+	case strings.EqualFold(game.Black, username):
+		return Black, game.White
+	default:
+		return "", ""
+	}
+}
+
+func (game GameRecord) resultOf(color string) string {
+	switch game.Result {
+	case "1/2-1/2":
+		return Draw
+	case "1-0":
+		if color == White {
+			return Win
+		}
+		return Loss
+	case "0-1":
+		if color == Black {
+			return Win
+		}
+		return Loss
+	default:
+		return ""
+	}
+}
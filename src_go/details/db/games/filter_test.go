@@ -0,0 +1,60 @@
+package games
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var filteredGame = GameRecord{
+	GameId:       "https://www.chess.com/game/live/52659611873",
+	EndTimestamp: 1659431445,
+	White:        "Tigran-C-137",
+	Black:        "Garry",
+	Result:       "0-1",
+	TimeClass:    Blitz,
+}
+
+func Test_Filter_should_pass_every_game_if_it_is_empty(t *testing.T) {
+	assert.True(t, Filter{Username: "tigran-c-137"}.Passes(filteredGame))
+	assert.True(t, Filter{Username: "tigran-c-137"}.Passes(GameRecord{}))
+}
+
+func Test_Filter_should_tell_the_color_opponent_and_result_of_the_player_ignoring_the_case(t *testing.T) {
+	assert.True(t, Filter{Username: "tigran-c-137", Color: White}.Passes(filteredGame))
+	assert.False(t, Filter{Username: "tigran-c-137", Color: Black}.Passes(filteredGame))
+	assert.True(t, Filter{Username: "garry", Color: Black}.Passes(filteredGame))
+
+	assert.True(t, Filter{Username: "tigran-c-137", Opponent: "GARRY"}.Passes(filteredGame))
+	assert.False(t, Filter{Username: "tigran-c-137", Opponent: "Magnus"}.Passes(filteredGame))
+
+	assert.True(t, Filter{Username: "tigran-c-137", Result: Loss}.Passes(filteredGame))
+	assert.True(t, Filter{Username: "garry", Result: Win}.Passes(filteredGame))
+	assert.False(t, Filter{Username: "tigran-c-137", Result: Draw}.Passes(filteredGame))
+}
+
+func Test_Filter_should_tell_the_side_of_the_player_under_any_of_their_usernames(t *testing.T) {
+	assert.True(t, Filter{Username: "tigran-c-138", Aliases: []string{"tigran-c-137"}, Color: White}.Passes(filteredGame))
+	assert.True(t, Filter{Username: "tigran-c-138", Aliases: []string{"tigran-c-137"}, Opponent: "garry"}.Passes(filteredGame))
+	assert.True(t, Filter{Username: "tigran-c-138", Aliases: []string{"tigran-c-137"}, Result: Loss}.Passes(filteredGame))
+	assert.False(t, Filter{Username: "tigran-c-138", Color: White}.Passes(filteredGame))
+}
+
+func Test_Filter_should_reject_the_games_of_unknown_players_if_the_side_matters(t *testing.T) {
+	unknownPlayers := GameRecord{EndTimestamp: 1659431445, TimeClass: Blitz}
+
+	assert.True(t, Filter{Username: "tigran-c-137", TimeClass: Blitz}.Passes(unknownPlayers))
+	assert.False(t, Filter{Username: "tigran-c-137", Color: White}.Passes(unknownPlayers))
+	assert.False(t, Filter{Username: "tigran-c-137", Result: Draw}.Passes(unknownPlayers))
+}
+
+func Test_Filter_should_keep_the_games_ended_within_the_dates(t *testing.T) {
+	assert.True(t, Filter{From: 1659431445, To: 1659431446}.Passes(filteredGame))
+	assert.False(t, Filter{From: 1659431446}.Passes(filteredGame))
+	assert.False(t, Filter{To: 1659431445}.Passes(filteredGame))
+}
+
+func Test_Filter_should_keep_the_games_of_the_time_class(t *testing.T) {
+	assert.True(t, Filter{TimeClass: Blitz}.Passes(filteredGame))
+	assert.False(t, Filter{TimeClass: Rapid}.Passes(filteredGame))
+}
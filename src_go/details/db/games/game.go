@@ -9,6 +9,15 @@ type GameRecord struct {
 	Resource     string `dynamodbav:"resource"`
 	Pgn          string `dynamodbav:"pgn"`
 	EndTimestamp int64  `dynamodbav:"end_timestamp"`
+	White        string `dynamodbav:"white,omitempty"`
+	Black        string `dynamodbav:"black,omitempty"`
+	WhiteRating  int    `dynamodbav:"white_rating,omitempty"`
+	BlackRating  int    `dynamodbav:"black_rating,omitempty"`
+	Result       string `dynamodbav:"result,omitempty"`
+	Eco          string `dynamodbav:"eco,omitempty"`
+	TimeControl  string `dynamodbav:"time_control,omitempty"`
+	TimeClass    string `dynamodbav:"time_class,omitempty"`
+	Rules        string `dynamodbav:"rules,omitempty"`
 }
 
 func (game GameRecord) String() string {
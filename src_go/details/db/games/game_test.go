@@ -44,6 +44,9 @@ func Test_GameRecord_should_be_stored_in_correct_form(t *testing.T) {
 	actualMarshalledItems, err := dynamodbattribute.MarshalMap(game)
 	assert.NoError(t, err)
 
+	encodedPgn, err := EncodePgn(pgn)
+	assert.NoError(t, err)
+
 	expectedMarshalledItems := map[string]*dynamodb.AttributeValue{
 		"user_id": {
 			S: aws.String(userId),
@@ -58,7 +61,7 @@ func Test_GameRecord_should_be_stored_in_correct_form(t *testing.T) {
 			S: aws.String(resource),
 		},
 		"pgn": {
-			S: aws.String(pgn),
+			B: encodedPgn,
 		},
 		"end_timestamp": {
 			N: aws.String("1696706773"),
@@ -97,3 +100,50 @@ func Test_GameRecord_should_be_stored_in_correct_form(t *testing.T) {
 
 	assert.Equal(t, expectedGame, actualGame)
 }
+
+func Test_GameRecord_should_store_the_structured_fields_as_typed_attributes(t *testing.T) {
+
+	game := GameRecord{
+		UserId:       "user",
+		ArchiveId:    "archive",
+		GameId:       "game",
+		Resource:     "game",
+		Pgn:          "pgn",
+		EndTimestamp: 1696706773,
+		White:        "tigran-c-137",
+		Black:        "Garry",
+		WhiteRating:  1517,
+		BlackRating:  2851,
+		Result:       "0-1",
+		Eco:          "B01",
+		TimeControl:  "180+2",
+		TimeClass:    Blitz,
+		Rules:        StandardRules,
+	}
+
+	actualMarshalledItems, err := dynamodbattribute.MarshalMap(game)
+	assert.NoError(t, err)
+
+	encodedPgn, err := EncodePgn("pgn")
+	assert.NoError(t, err)
+
+	expectedMarshalledItems := map[string]*dynamodb.AttributeValue{
+		"user_id":       {S: aws.String("user")},
+		"archive_id":    {S: aws.String("archive")},
+		"game_id":       {S: aws.String("game")},
+		"resource":      {S: aws.String("game")},
+		"pgn":           {B: encodedPgn},
+		"end_timestamp": {N: aws.String("1696706773")},
+		"white":         {S: aws.String("tigran-c-137")},
+		"black":         {S: aws.String("Garry")},
+		"white_rating":  {N: aws.String("1517")},
+		"black_rating":  {N: aws.String("2851")},
+		"result":        {S: aws.String("0-1")},
+		"eco":           {S: aws.String("B01")},
+		"time_control":  {S: aws.String("180+2")},
+		"time_class":    {S: aws.String("blitz")},
+		"rules":         {S: aws.String("chess")},
+	}
+
+	assert.Equal(t, expectedMarshalledItems, actualMarshalledItems)
+}
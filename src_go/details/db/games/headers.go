@@ -0,0 +1,99 @@
+package games
+
+import (
+	"strconv"
+	"strings"
+)
+
+const StandardRules = "chess"
+
+const (
+	Bullet = "bullet"
+	Blitz  = "blitz"
+	Rapid  = "rapid"
+	Daily  = "daily"
+)
+
+// WithPgnTags fills the structured fields that are still empty from the tag pairs of the pgn,
+// the fields taken from a richer source like the chess.com api are kept as they are
+func (game GameRecord) WithPgnTags(tags map[string]string) GameRecord {
+	if game.White == "" {
+		game.White = knownTag(tags["White"])
+	}
+	if game.Black == "" {
+		game.Black = knownTag(tags["Black"])
+	}
+	if game.WhiteRating == 0 {
+		game.WhiteRating, _ = strconv.Atoi(tags["WhiteElo"])
+	}
+	if game.BlackRating == 0 {
+		game.BlackRating, _ = strconv.Atoi(tags["BlackElo"])
+	}
+	if game.Result == "" {
+		game.Result = knownTag(tags["Result"])
+	}
+	if game.Eco == "" {
+		game.Eco = knownTag(tags["ECO"])
+	}
+	if game.TimeControl == "" {
+		game.TimeControl = knownTag(tags["TimeControl"])
+	}
+	if game.TimeClass == "" {
+		game.TimeClass = TimeClassOf(game.TimeControl)
+	}
+	if game.Rules == "" {
+		game.Rules = RulesOf(tags["Variant"])
+	}
+	return game
+}
+
+// Variant tells the rules of the game, the games stored before the rules were known are all standard ones
+func (game GameRecord) Variant() string {
+	if game.Rules == "" {
+		return StandardRules
+	}
+	return game.Rules
+}
+
+// RulesOf names the variant of a pgn the way chess.com does, like chess, chess960 or kingofthehill
+func RulesOf(variant string) string {
+	rules := strings.ToLower(variant)
+	rules = strings.NewReplacer(" ", "", "-", "").Replace(rules)
+	if rules == "" || rules == "standard" {
+		return StandardRules
+	}
+	return rules
+}
+
+// TimeClassOf classifies a pgn time control like 180+2 the way chess.com does,
+// by the expected duration of the game with 40 moves
+func TimeClassOf(timeControl string) string {
+	if strings.Contains(timeControl, "/") {
+		return Daily
+	}
+
+	base, increment, _ := strings.Cut(timeControl, "+")
+	baseSeconds, err := strconv.Atoi(base)
+	if err != nil {
+		return ""
+	}
+	incrementSeconds, _ := strconv.Atoi(increment)
+
+	expectedSeconds := baseSeconds + 40*incrementSeconds
+	switch {
+	case expectedSeconds < 180:
+		return Bullet
+	case expectedSeconds < 600:
+		return Blitz
+	default:
+		return Rapid
+	}
+}
+
+// the pgn standard uses ? and - for the values that are unknown or do not apply
+func knownTag(value string) string {
+	if value == "?" || value == "-" {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,82 @@
+package games
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithPgnTags_should_fill_the_structured_fields_from_the_tags(t *testing.T) {
+	tags := map[string]string{
+		"White":       "tigran-c-137",
+		"Black":       "Garry",
+		"WhiteElo":    "1517",
+		"BlackElo":    "?",
+		"Result":      "1-0",
+		"ECO":         "B01",
+		"TimeControl": "180+2",
+	}
+
+	actualGame := GameRecord{GameId: "game"}.WithPgnTags(tags)
+
+	expectedGame := GameRecord{
+		GameId:      "game",
+		White:       "tigran-c-137",
+		Black:       "Garry",
+		WhiteRating: 1517,
+		BlackRating: 0,
+		Result:      "1-0",
+		Eco:         "B01",
+		TimeControl: "180+2",
+		TimeClass:   Blitz,
+		Rules:       StandardRules,
+	}
+
+	assert.Equal(t, expectedGame, actualGame)
+}
+
+func Test_WithPgnTags_should_keep_the_fields_that_are_already_known(t *testing.T) {
+	tags := map[string]string{
+		"White":       "tigran-c-137",
+		"WhiteElo":    "1517",
+		"TimeControl": "180+2",
+		"Variant":     "Chess960",
+	}
+
+	game := GameRecord{
+		White:       "Tigran-C-137",
+		WhiteRating: 1520,
+		TimeClass:   Bullet,
+	}
+
+	actualGame := game.WithPgnTags(tags)
+
+	assert.Equal(t, "Tigran-C-137", actualGame.White)
+	assert.Equal(t, 1520, actualGame.WhiteRating)
+	assert.Equal(t, Bullet, actualGame.TimeClass)
+	assert.Equal(t, "180+2", actualGame.TimeControl)
+	assert.Equal(t, "chess960", actualGame.Rules)
+}
+
+func Test_TimeClassOf_should_classify_time_controls_like_chess_dot_com(t *testing.T) {
+	assert.Equal(t, Bullet, TimeClassOf("60"))
+	assert.Equal(t, Bullet, TimeClassOf("120+1"))
+	assert.Equal(t, Blitz, TimeClassOf("180"))
+	assert.Equal(t, Blitz, TimeClassOf("300+5"))
+	assert.Equal(t, Rapid, TimeClassOf("600"))
+	assert.Equal(t, Daily, TimeClassOf("1/259200"))
+	assert.Equal(t, "", TimeClassOf(""))
+}
+
+func Test_RulesOf_should_name_variants_like_chess_dot_com(t *testing.T) {
+	assert.Equal(t, StandardRules, RulesOf(""))
+	assert.Equal(t, StandardRules, RulesOf("Standard"))
+	assert.Equal(t, "chess960", RulesOf("Chess960"))
+	assert.Equal(t, "kingofthehill", RulesOf("King of the Hill"))
+	assert.Equal(t, "threecheck", RulesOf("Three-check"))
+}
+
+func Test_Variant_should_treat_the_games_without_rules_as_standard(t *testing.T) {
+	assert.Equal(t, StandardRules, GameRecord{}.Variant())
+	assert.Equal(t, "crazyhouse", GameRecord{Rules: "crazyhouse"}.Variant())
+}
@@ -0,0 +1,100 @@
+package games
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// The pgn is stored as binary, the first byte tells how the rest is encoded.
+// The games stored before the compression keep the pgn as a plain string and are read as they are
+const (
+	PgnDeflated byte = 1
+)
+
+var ErrUnknownPgnEncoding = errors.New("unknown encoding of the pgn")
+
+// plainGameRecord has the fields of GameRecord without its custom marshaling
+type plainGameRecord GameRecord
+
+func (game GameRecord) MarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) (err error) {
+	gameRecordItems, err := dynamodbattribute.MarshalMap(plainGameRecord(game))
+	if err != nil {
+		return
+	}
+
+	encodedPgn, err := EncodePgn(game.Pgn)
+	if err != nil {
+		return
+	}
+	gameRecordItems["pgn"] = &dynamodb.AttributeValue{B: encodedPgn}
+
+	av.M = gameRecordItems
+	return
+}
+
+func (game *GameRecord) UnmarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) (err error) {
+	gameRecordItems := make(map[string]*dynamodb.AttributeValue, len(av.M))
+	for name, value := range av.M {
+		gameRecordItems[name] = value
+	}
+
+	if pgnItem, exists := gameRecordItems["pgn"]; exists && pgnItem.B != nil {
+		var pgn string
+		pgn, err = DecodePgn(pgnItem.B)
+		if err != nil {
+			return
+		}
+		gameRecordItems["pgn"] = &dynamodb.AttributeValue{S: &pgn}
+	}
+
+	return dynamodbattribute.UnmarshalMap(gameRecordItems, (*plainGameRecord)(game))
+}
+
+func EncodePgn(pgn string) (encodedPgn []byte, err error) {
+	buffer := bytes.Buffer{}
+	buffer.WriteByte(PgnDeflated)
+
+	writer, err := flate.NewWriter(&buffer, flate.BestCompression)
+	if err != nil {
+		return
+	}
+	_, err = writer.Write([]byte(pgn))
+	if err != nil {
+		return
+	}
+	err = writer.Close()
+	if err != nil {
+		return
+	}
+
+	encodedPgn = buffer.Bytes()
+	return
+}
+
+func DecodePgn(encodedPgn []byte) (pgn string, err error) {
+	if len(encodedPgn) == 0 {
+		err = ErrUnknownPgnEncoding
+		return
+	}
+
+	switch encodedPgn[0] {
+	case PgnDeflated:
+		reader := flate.NewReader(bytes.NewReader(encodedPgn[1:]))
+		defer reader.Close()
+		var decodedPgn []byte
+		decodedPgn, err = io.ReadAll(reader)
+		if err != nil {
+			return
+		}
+		pgn = string(decodedPgn)
+	default:
+		err = fmt.Errorf("%w: version %v", ErrUnknownPgnEncoding, encodedPgn[0])
+	}
+	return
+}
@@ -0,0 +1,68 @@
+package games
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/stretchr/testify/assert"
+)
+
+const pgnWithClocks = "[Event \"Live Chess\"]\n[Site \"Chess.com\"]\n[White \"tigran-c-137\"]\n[Black \"NguoiandanhVN\"]\n[Result \"0-1\"]\n\n1. d4 {[%clk 0:05:00]} 1... d5 {[%clk 0:04:59.5]} 2. c4 {[%clk 0:04:58.9]} 2... e6 {[%clk 0:04:59.2]} 3. Nc3 {[%clk 0:04:56]} 3... dxc4 {[%clk 0:04:57.9]} 4. e3 {[%clk 0:04:54.8]} 4... Nf6 {[%clk 0:04:55.1]} 5. Bxc4 {[%clk 0:04:47.1]} 5... b6 {[%clk 0:04:53.8]} 0-1\n"
+
+func Test_GameRecord_should_store_the_pgn_compressed_with_a_version_marker(t *testing.T) {
+	game := GameRecord{
+		UserId: "user",
+		GameId: "game",
+		Pgn:    pgnWithClocks,
+	}
+
+	gameRecordItems, err := dynamodbattribute.MarshalMap(game)
+	assert.NoError(t, err)
+
+	storedPgn := gameRecordItems["pgn"]
+	assert.Nil(t, storedPgn.S)
+	assert.Equal(t, PgnDeflated, storedPgn.B[0])
+	assert.Less(t, len(storedPgn.B), len(pgnWithClocks))
+
+	actualGame := GameRecord{}
+	err = dynamodbattribute.UnmarshalMap(gameRecordItems, &actualGame)
+	assert.NoError(t, err)
+	assert.Equal(t, game, actualGame)
+}
+
+func Test_GameRecord_should_read_the_pgn_stored_as_a_plain_string(t *testing.T) {
+	gameRecordItems := map[string]*dynamodb.AttributeValue{
+		"user_id":       {S: aws.String("user")},
+		"archive_id":    {S: aws.String("archive")},
+		"game_id":       {S: aws.String("game")},
+		"resource":      {S: aws.String("game")},
+		"pgn":           {S: aws.String(pgnWithClocks)},
+		"end_timestamp": {N: aws.String("1696706773")},
+	}
+
+	actualGames := []GameRecord{}
+	err := dynamodbattribute.UnmarshalListOfMaps([]map[string]*dynamodb.AttributeValue{gameRecordItems}, &actualGames)
+	assert.NoError(t, err)
+
+	expectedGames := []GameRecord{
+		{
+			UserId:       "user",
+			ArchiveId:    "archive",
+			GameId:       "game",
+			Resource:     "game",
+			Pgn:          pgnWithClocks,
+			EndTimestamp: 1696706773,
+		},
+	}
+	assert.Equal(t, expectedGames, actualGames)
+}
+
+func Test_DecodePgn_should_reject_an_unknown_version(t *testing.T) {
+	_, err := DecodePgn([]byte{42, 1, 2, 3})
+	assert.ErrorIs(t, err, ErrUnknownPgnEncoding)
+
+	_, err = DecodePgn([]byte{})
+	assert.ErrorIs(t, err, ErrUnknownPgnEncoding)
+}
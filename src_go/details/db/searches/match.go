@@ -0,0 +1,32 @@
+package searches
+
+import "fmt"
+
+// MatchRecord is one game a search has found, the matches of a search are ordered by the date of their games
+type MatchRecord struct {
+	SearchId string `dynamodbav:"search_id"`
+	// MatchId starts with the end of the game, so the sort key orders the matches by the game date
+	MatchId      string `dynamodbav:"match_id"`
+	GameId       string `dynamodbav:"game_id"`
+	Resource     string `dynamodbav:"resource"`
+	EndTimestamp int64  `dynamodbav:"end_timestamp"`
+	// Ply and Fen tell the first position of the game the board is found in, the ply 0 is the position before
+	// the first move, the matches that could not be located have no fen
+	Ply int    `dynamodbav:"ply"`
+	Fen string `dynamodbav:"fen,omitempty"`
+}
+
+func NewMatchRecord(searchId string, gameId string, resource string, endTimestamp int64) MatchRecord {
+	return MatchRecord{
+		SearchId:     searchId,
+		MatchId:      MatchIdOf(gameId, endTimestamp),
+		GameId:       gameId,
+		Resource:     resource,
+		EndTimestamp: endTimestamp,
+	}
+}
+
+// MatchIdOf pads the end of the game, the ids of the games ended at the same second are ordered by the game id
+func MatchIdOf(gameId string, endTimestamp int64) string {
+	return fmt.Sprintf("%012d#%s", endTimestamp, gameId)
+}
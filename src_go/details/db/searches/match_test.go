@@ -0,0 +1,25 @@
+package searches
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MatchIdOf_should_order_the_matches_by_the_game_date(t *testing.T) {
+	matchIds := []string{
+		MatchIdOf("https://www.chess.com/game/live/3", 1659431445),
+		MatchIdOf("https://www.chess.com/game/live/1", 987654321),
+		MatchIdOf("https://www.chess.com/game/live/2", 1659431445),
+	}
+
+	sort.Strings(matchIds)
+
+	expectedMatchIds := []string{
+		"000987654321#https://www.chess.com/game/live/1",
+		"001659431445#https://www.chess.com/game/live/2",
+		"001659431445#https://www.chess.com/game/live/3",
+	}
+	assert.Equal(t, expectedMatchIds, matchIds)
+}
@@ -14,6 +14,20 @@ type SearchRecord struct {
 	Total          int             `dynamodbav:"total"`
 	Matched        []string        `dynamodbav:"matched,stringset"`
 	Status         SearchStatus    `dynamodbav:"status"`
+	CallbackUrl    string          `dynamodbav:"callback_url,omitempty"`
+	CallbackSecret string          `dynamodbav:"callback_secret,omitempty"`
+	// Notified is set once the final status is sent to the callback url
+	Notified bool `dynamodbav:"notified,omitempty"`
+	// MatchedCount counts the matches stored apart, only the searches made before that keep them in Matched
+	MatchedCount int `dynamodbav:"matched_count,omitempty"`
+	// Shards is the amount of archives the search is split into, the shards add up their progress once they are over
+	// and the final status is set when all of them are done, the searches without shards are made by a single command
+	Shards        int `dynamodbav:"shards,omitempty"`
+	ShardsDone    int `dynamodbav:"shards_done,omitempty"`
+	StoppedShards int `dynamodbav:"stopped_shards,omitempty"`
+	// ReservedMatches counts the matches the shards claimed before storing them, so the shards running together
+	// do not store more matches than the limit, every shard keeps its own claim in its shard record
+	ReservedMatches int `dynamodbav:"reserved_matches,omitempty"`
 }
 
 type SearchStatus string
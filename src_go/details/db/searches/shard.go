@@ -0,0 +1,22 @@
+package searches
+
+// ShardRecord is what one shard of a search has done, it exists once the shard reserves matches or is counted in the search record
+type ShardRecord struct {
+	SearchId  string `dynamodbav:"search_id"`
+	ArchiveId string `dynamodbav:"archive_id"`
+	Examined  int    `dynamodbav:"examined"`
+	Matched   int    `dynamodbav:"matched"`
+	// Stopped is set if the shard did not examine all its games, because of the limit of matches or a failure
+	Stopped bool `dynamodbav:"stopped"`
+	// Reserved counts the matches the shard claimed under the limit of the search, a redelivered shard reuses them
+	Reserved int `dynamodbav:"reserved,omitempty"`
+	// Counted is set once the progress of the shard is added to the search record
+	Counted bool `dynamodbav:"counted,omitempty"`
+}
+
+func NewShardRecord(searchId string, archiveId string) ShardRecord {
+	return ShardRecord{
+		SearchId:  searchId,
+		ArchiveId: archiveId,
+	}
+}
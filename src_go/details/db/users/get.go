@@ -0,0 +1,88 @@
+package users
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// GetUserRecord finds the user by the normalized username, the users cached before usernames were normalized
+// can be found only by the username as it was requested. A user that is not cached is not found without an error
+func GetUserRecord(
+	dynamodbClient *dynamodb.DynamoDB,
+	usersTableName string,
+	username string,
+	platform Platform,
+) (user UserRecord, isFound bool, err error) {
+	usernames := []string{NormalizeUsername(username)}
+	if usernames[0] != username {
+		usernames = append(usernames, username)
+	}
+
+	for _, usernameToGet := range usernames {
+		var getItemOutput *dynamodb.GetItemOutput
+		getItemOutput, err = dynamodbClient.GetItem(
+			&dynamodb.GetItemInput{
+				TableName: aws.String(usersTableName),
+				Key: map[string]*dynamodb.AttributeValue{
+					"username": {
+						S: aws.String(usernameToGet),
+					},
+					"platform": {
+						S: aws.String(string(platform)),
+					},
+				},
+			},
+		)
+		if err != nil {
+			return
+		}
+		if len(getItemOutput.Item) > 0 {
+			err = dynamodbattribute.UnmarshalMap(getItemOutput.Item, &user)
+			isFound = err == nil
+			return
+		}
+	}
+	return
+}
+
+// GetAliases finds all the users a chess.com player has been cached as, the player id stays the same after renames
+func GetAliases(
+	dynamodbClient *dynamodb.DynamoDB,
+	usersTableName string,
+	usersByPlayerIdIndexName string,
+	playerId int64,
+) (aliases []UserRecord, err error) {
+	var lastKey map[string]*dynamodb.AttributeValue
+	for {
+		var queryOutput *dynamodb.QueryOutput
+		queryOutput, err = dynamodbClient.Query(&dynamodb.QueryInput{
+			TableName:              aws.String(usersTableName),
+			IndexName:              aws.String(usersByPlayerIdIndexName),
+			KeyConditionExpression: aws.String("player_id = :player_id"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":player_id": {
+					N: aws.String(strconv.FormatInt(playerId, 10)),
+				},
+			},
+			ExclusiveStartKey: lastKey,
+		})
+		if err != nil {
+			return
+		}
+
+		pageOfAliases := []UserRecord{}
+		err = dynamodbattribute.UnmarshalListOfMaps(queryOutput.Items, &pageOfAliases)
+		if err != nil {
+			return
+		}
+		aliases = append(aliases, pageOfAliases...)
+
+		lastKey = queryOutput.LastEvaluatedKey
+		if len(lastKey) == 0 {
+			return
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package users
 
+import "strings"
+
 type UserRecord struct {
 	Username string   `dynamodbav:"username"`
 	Platform Platform `dynamodbav:"platform"`
 	UserId   string   `dynamodbav:"user_id"`
+	PlayerId int64    `dynamodbav:"player_id,omitempty"`
 }
 
 type Platform string
@@ -12,3 +15,8 @@ const (
 	ChessDotCom Platform = "CHESS_DOT_COM"
 	Lichess     Platform = "LICHESS"
 )
+
+// NormalizeUsername makes usernames comparable, both chess.com and lichess treat them case-insensitively
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
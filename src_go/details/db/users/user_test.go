@@ -81,3 +81,56 @@ func Test_UserRecord_should_be_stored_in_correct_form(t *testing.T) {
 
 	assert.Equal(t, expectedUser, actualUser)
 }
+
+func Test_NormalizeUsername_should_ignore_case_and_surrounding_spaces(t *testing.T) {
+	assert.Equal(t, "magnuscarlsen", NormalizeUsername("MagnusCarlsen"))
+	assert.Equal(t, "magnuscarlsen", NormalizeUsername(" magnuscarlsen\n"))
+	assert.Equal(t, "tigran-c-137", NormalizeUsername("Tigran-C-137"))
+}
+
+func Test_UserRecord_should_keep_the_player_id_only_if_it_is_known(t *testing.T) {
+	user := UserRecord{
+		Username: "magnuscarlsen",
+		Platform: ChessDotCom,
+		UserId:   "https://api.chess.com/pub/player/magnuscarlsen",
+		PlayerId: 3889224,
+	}
+
+	actualMarshalledItems, err := dynamodbattribute.MarshalMap(user)
+	assert.NoError(t, err)
+	assert.Equal(t, &dynamodb.AttributeValue{N: aws.String("3889224")}, actualMarshalledItems["player_id"])
+
+	actualUser := UserRecord{}
+	err = dynamodbattribute.UnmarshalMap(actualMarshalledItems, &actualUser)
+	assert.NoError(t, err)
+	assert.Equal(t, user, actualUser)
+
+	user.PlayerId = 0
+	actualMarshalledItems, err = dynamodbattribute.MarshalMap(user)
+	assert.NoError(t, err)
+	assert.NotContains(t, actualMarshalledItems, "player_id")
+}
+
+func Test_GetUserRecord_should_find_the_user_cached_before_usernames_were_normalized(t *testing.T) {
+	user := UserRecord{
+		Username: "Tigran-C-137-GetUserRecord",
+		Platform: ChessDotCom,
+		UserId:   "https://api.chess.com/pub/player/tigran-c-137-getuserrecord",
+	}
+	userItems, err := dynamodbattribute.MarshalMap(user)
+	assert.NoError(t, err)
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(usersTableName),
+		Item:      userItems,
+	})
+	assert.NoError(t, err)
+
+	actualUser, isFound, err := GetUserRecord(dynamodbClient, usersTableName, "Tigran-C-137-GetUserRecord", ChessDotCom)
+	assert.NoError(t, err)
+	assert.True(t, isFound)
+	assert.Equal(t, user, actualUser)
+
+	_, isFound, err = GetUserRecord(dynamodbClient, usersTableName, "Not-Cached-GetUserRecord", ChessDotCom)
+	assert.NoError(t, err)
+	assert.False(t, isFound)
+}
@@ -0,0 +1,211 @@
+// Package fetch requests the sites the games are downloaded from, it retries their failures
+// and guards the responses that stop coming
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	// Timeout limits connecting, waiting for the headers and every single read of the body,
+	// but not the whole response, a large download is read as long as the site keeps sending it
+	Timeout        time.Duration
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Site turns the answers of a site into its own errors, so the callers of its client tell the sites apart
+type Site struct {
+	Name string
+	// StatusError is the error of an answer other than 200
+	StatusError func(statusCode int) error
+	// IsRetryable tells whether a failed answer is requested again, the network failures always are
+	IsRetryable func(err error) bool
+	// ErrStalled is returned when a single read of the body waits longer than the timeout
+	ErrStalled error
+	// Wait is called before every request and Postpone when the site asks to wait with Retry-After, both are optional
+	Wait     func()
+	Postpone func(till time.Time)
+}
+
+type Client struct {
+	config     Config
+	site       Site
+	httpClient *http.Client
+}
+
+func NewClient(config Config, site Site) *Client {
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = config.Timeout
+	transport.ResponseHeaderTimeout = config.Timeout
+	return &Client{
+		config:     config,
+		site:       site,
+		httpClient: &http.Client{Transport: transport},
+	}
+}
+
+// Get requests the site until it answers with 200, the caller is responsible for closing the body of the response.
+// The retryable answers and the network failures are retried with jittered exponential backoff, other answers fail
+// immediately. Retry-After is honored up to the max backoff, a longer one fails the request immediately.
+func (client *Client) Get(logger *zap.Logger, url string, header http.Header) (response *http.Response, err error) {
+	logger = logger.With(zap.String("url", url))
+	name := client.site.Name
+
+	for attempt := 0; ; attempt++ {
+		logger := logger.With(zap.Int("attempt", attempt+1))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var request *http.Request
+		request, err = http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			cancel()
+			logger.Error("impossible to create a request to "+name+"!", zap.Error(err))
+			return
+		}
+		for headerName, values := range header {
+			request.Header[headerName] = values
+		}
+
+		if client.site.Wait != nil {
+			client.site.Wait()
+		}
+		logger.Info("requesting " + name)
+		response, err = client.httpClient.Do(request)
+
+		retryAfter := time.Duration(0)
+		if err != nil {
+			cancel()
+			logger.Error("impossible to request "+name+"!", zap.Error(err))
+		} else {
+			if response.StatusCode == http.StatusOK {
+				response.Body = newStallGuard(response.Body, client.config.Timeout, cancel, client.site.ErrStalled)
+				return
+			}
+
+			err = client.site.StatusError(response.StatusCode)
+			if response.StatusCode == http.StatusNotModified {
+				response.Body.Close()
+				cancel()
+				response = nil
+				logger.Info(name + " resource is not modified")
+				return
+			}
+
+			responseBodyBytes, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+			response.Body.Close()
+			cancel()
+			logger.Error("unexpected status code from "+name, zap.Int("statusCode", response.StatusCode), zap.String("responseBody", string(responseBodyBytes)))
+
+			retryAfter = parseRetryAfter(response.Header.Get("Retry-After"), time.Now())
+			response = nil
+
+			if !client.site.IsRetryable(err) {
+				return
+			}
+
+			// waiting longer than the max backoff would hold the caller too long, it is better to give up and try later
+			if retryAfter > client.config.MaxBackoff {
+				logger.Error(name+" asks to wait longer than the max backoff, giving up", zap.Duration("retryAfter", retryAfter), zap.Error(err))
+				return
+			}
+		}
+
+		if attempt >= client.config.MaxRetries {
+			logger.Error("giving up on "+name, zap.Error(err))
+			return
+		}
+
+		backoff := client.backoff(attempt)
+		if retryAfter > backoff {
+			backoff = retryAfter
+			if client.site.Postpone != nil {
+				client.site.Postpone(time.Now().Add(retryAfter))
+			}
+		}
+		logger.Info("retrying "+name, zap.Duration("backoff", backoff))
+		time.Sleep(backoff)
+	}
+}
+
+// full jitter, see https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+func (client *Client) backoff(attempt int) time.Duration {
+	ceiling := client.config.InitialBackoff << attempt
+	if ceiling <= 0 || ceiling > client.config.MaxBackoff {
+		ceiling = client.config.MaxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+func parseRetryAfter(retryAfter string, now time.Time) time.Duration {
+	if retryAfter == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(retryAfter); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// stallGuard cancels the request when a single read of the body waits longer than the timeout, no timeout waits forever.
+// The time between the reads is not limited, the games are stored while the response is still being read.
+type stallGuard struct {
+	body       io.ReadCloser
+	timeout    time.Duration
+	cancel     context.CancelFunc
+	errStalled error
+	timer      *time.Timer
+	stalled    atomic.Bool
+}
+
+func newStallGuard(body io.ReadCloser, timeout time.Duration, cancel context.CancelFunc, errStalled error) *stallGuard {
+	guard := &stallGuard{body: body, timeout: timeout, cancel: cancel, errStalled: errStalled}
+	if timeout > 0 {
+		guard.timer = time.AfterFunc(timeout, func() {
+			guard.stalled.Store(true)
+			guard.cancel()
+		})
+		guard.timer.Stop()
+	}
+	return guard
+}
+
+func (guard *stallGuard) Read(p []byte) (n int, err error) {
+	if guard.timer == nil {
+		return guard.body.Read(p)
+	}
+	guard.timer.Reset(guard.timeout)
+	n, err = guard.body.Read(p)
+	guard.timer.Stop()
+	if err != nil && err != io.EOF && guard.stalled.Load() {
+		err = fmt.Errorf("%w: %v", guard.errStalled, err)
+	}
+	return
+}
+
+func (guard *stallGuard) Close() error {
+	if guard.timer != nil {
+		guard.timer.Stop()
+	}
+	defer guard.cancel()
+	return guard.body.Close()
+}
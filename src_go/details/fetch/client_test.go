@@ -0,0 +1,26 @@
+package fetch
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseRetryAfter_should_support_seconds_and_http_dates(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 5*time.Second, parseRetryAfter("5", now))
+	assert.Equal(t, 30*time.Second, parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
+
+func Test_backoff_should_stay_under_the_max_backoff(t *testing.T) {
+	client := NewClient(Config{InitialBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}, Site{})
+	for attempt := 0; attempt < 70; attempt++ {
+		backoff := client.backoff(attempt)
+		assert.GreaterOrEqual(t, backoff, time.Duration(0))
+		assert.Less(t, backoff, 4*time.Millisecond)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/chessfinder/chessfinder-faster-backend/src_go/details/isolate
+
+go 1.21.0
@@ -0,0 +1,166 @@
+// Package isolate runs the functions of the core on threads of a Graal isolate. The core library itself is linked
+// by the packages that call its functions, a thread is handed to them as an unsafe.Pointer to graal_isolatethread_t
+package isolate
+
+/*
+#include "graal_isolate.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"unsafe"
+)
+
+var ErrNotCreated = errors.New("impossible to create the isolate")
+var ErrNotAttached = errors.New("impossible to attach a thread to the isolate")
+
+// isolatePool keeps a single isolate of the core for the whole process. A thread of the isolate belongs to the OS thread
+// that attached it, so every worker holds an OS thread of its own and the goroutines hand their calls over to the workers
+type isolatePool struct {
+	calls chan func(thread *C.graal_isolatethread_t)
+}
+
+var sharedPoolLock sync.Mutex
+var sharedPool *isolatePool
+
+// Call runs the function on a thread of the isolate pool and waits for it, it is safe to call from many goroutines at once.
+// A panic of the function is returned as an error
+func Call(function func(thread unsafe.Pointer)) (err error) {
+	pool, err := getPool()
+	if err != nil {
+		return
+	}
+	err = pool.call(func(thread *C.graal_isolatethread_t) {
+		function(unsafe.Pointer(thread))
+	})
+	return
+}
+
+// CallInOwnIsolate creates and tears down an isolate for the function as the searches did before the pool,
+// it is kept to measure the pool against
+func CallInOwnIsolate(function func(thread unsafe.Pointer)) (err error) {
+	debug.SetPanicOnFault(true)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	var isolate *C.graal_isolate_t = nil
+	var thread *C.graal_isolatethread_t = nil
+
+	if C.graal_create_isolate(nil, &isolate, &thread) != 0 {
+		err = ErrNotCreated
+		return
+	}
+
+	defer C.graal_tear_down_isolate(thread)
+
+	function(unsafe.Pointer(thread))
+	return
+}
+
+// getPool starts the pool on the first call, a pool that failed to start is tried again on the next call
+func getPool() (sharedOne *isolatePool, err error) {
+	sharedPoolLock.Lock()
+	defer sharedPoolLock.Unlock()
+	if sharedPool == nil {
+		sharedPool, err = newPool(runtime.NumCPU())
+	}
+	sharedOne = sharedPool
+	return
+}
+
+// newPool creates the isolate with the first worker and attaches the others to it. If a worker fails to attach,
+// the started workers detach and the isolate is torn down, so the next try starts from scratch
+func newPool(workers int) (started *isolatePool, err error) {
+	started = &isolatePool{
+		calls: make(chan func(thread *C.graal_isolatethread_t)),
+	}
+	isStarted := make(chan error)
+
+	var isolate *C.graal_isolate_t = nil
+	go started.work(
+		func(thread **C.graal_isolatethread_t) error {
+			if C.graal_create_isolate(nil, &isolate, thread) != 0 {
+				return ErrNotCreated
+			}
+			return nil
+		},
+		func(thread *C.graal_isolatethread_t) {
+			C.graal_tear_down_isolate(thread)
+		},
+		isStarted,
+	)
+	err = <-isStarted
+	if err != nil {
+		started = nil
+		return
+	}
+
+	for worker := 1; worker < workers; worker++ {
+		go started.work(
+			func(thread **C.graal_isolatethread_t) error {
+				if C.graal_attach_thread(isolate, thread) != 0 {
+					return ErrNotAttached
+				}
+				return nil
+			},
+			func(thread *C.graal_isolatethread_t) {
+				C.graal_detach_thread(thread)
+			},
+			isStarted,
+		)
+		err = <-isStarted
+		if err != nil {
+			close(started.calls)
+			started = nil
+			return
+		}
+	}
+	return
+}
+
+// work keeps the OS thread while the pool takes calls, the isolate thread is attached to it and can not move
+func (pool *isolatePool) work(
+	attach func(thread **C.graal_isolatethread_t) error,
+	release func(thread *C.graal_isolatethread_t),
+	isStarted chan<- error,
+) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	debug.SetPanicOnFault(true)
+
+	var thread *C.graal_isolatethread_t = nil
+	err := attach(&thread)
+	isStarted <- err
+	if err != nil {
+		return
+	}
+	defer release(thread)
+
+	for call := range pool.calls {
+		call(thread)
+	}
+}
+
+// call runs the function on a thread of the isolate and waits for it, a panic of the function is returned as an error
+func (pool *isolatePool) call(function func(thread *C.graal_isolatethread_t)) error {
+	done := make(chan error, 1)
+	pool.calls <- func(thread *C.graal_isolatethread_t) {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("%v", r)
+			}
+		}()
+		function(thread)
+		done <- nil
+	}
+	return <-done
+}
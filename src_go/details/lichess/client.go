@@ -0,0 +1,149 @@
+package lichess
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/fetch"
+	"go.uber.org/zap"
+)
+
+var ErrNotFound = errors.New("lichess account not found")
+var ErrClosed = errors.New("lichess account is closed")
+var ErrRateLimited = errors.New("lichess rate limit exceeded")
+var ErrServerFailure = errors.New("lichess server failure")
+var ErrUnexpectedStatus = errors.New("unexpected status code from lichess")
+var ErrStalled = errors.New("lichess stopped sending the response")
+
+type Config struct {
+	Url string
+	fetch.Config
+}
+
+func DefaultConfig(url string) Config {
+	return Config{
+		Url: url,
+		Config: fetch.Config{
+			Timeout:        10 * time.Second,
+			MaxRetries:     3,
+			InitialBackoff: 500 * time.Millisecond,
+			MaxBackoff:     10 * time.Second,
+		},
+	}
+}
+
+type Client struct {
+	config      Config
+	fetchClient *fetch.Client
+}
+
+func NewClient(config Config) (client *Client, err error) {
+	_, err = url.ParseRequestURI(config.Url)
+	if err != nil {
+		return
+	}
+	client = &Client{
+		config: config,
+		fetchClient: fetch.NewClient(config.Config, fetch.Site{
+			Name:        "lichess",
+			StatusError: statusError,
+			IsRetryable: isRetryable,
+			ErrStalled:  ErrStalled,
+		}),
+	}
+	return
+}
+
+// Profile fails with ErrClosed for a closed account, lichess still answers with its profile then
+func (client *Client) Profile(logger *zap.Logger, username string) (profile Profile, err error) {
+	response, err := client.get(logger, "/api/user/"+username, nil, "application/json")
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	err = json.NewDecoder(response.Body).Decode(&profile)
+	if err != nil {
+		logger.Error("impossible to unmarshal the response body from lichess!", zap.Error(err))
+		return
+	}
+
+	if profile.Disabled {
+		logger.Info("lichess account is closed")
+		err = ErrClosed
+	}
+	return
+}
+
+// StreamGames exports the games that ended within [since, until) and hands them to consume one by one
+func (client *Client) StreamGames(
+	logger *zap.Logger,
+	username string,
+	since time.Time,
+	until time.Time,
+	consume func(game Game) error,
+) (err error) {
+	query := url.Values{}
+	query.Set("since", strconv.FormatInt(since.UnixMilli(), 10))
+	query.Set("until", strconv.FormatInt(until.UnixMilli()-1, 10))
+	query.Set("pgnInJson", "true")
+	query.Set("clocks", "true")
+
+	response, err := client.get(logger, "/api/games/user/"+username, query, "application/x-ndjson")
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	decoder := json.NewDecoder(response.Body)
+	for {
+		game := Game{}
+		err = decoder.Decode(&game)
+		if err == io.EOF {
+			err = nil
+			return
+		}
+		if err != nil {
+			logger.Error("impossible to unmarshal the games from lichess!", zap.Error(err))
+			return
+		}
+
+		err = consume(game)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// get requests lichess, 5xx and network failures are retried. Lichess asks to wait a full minute after a 429,
+// so it is not retried here but left to the caller, as well as other statuses.
+func (client *Client) get(logger *zap.Logger, path string, query url.Values, accept string) (response *http.Response, err error) {
+	requestUrl := client.config.Url + path
+	if len(query) > 0 {
+		requestUrl += "?" + query.Encode()
+	}
+	return client.fetchClient.Get(logger, requestUrl, http.Header{"Accept": []string{accept}})
+}
+
+func isRetryable(err error) bool {
+	return errors.Is(err, ErrServerFailure)
+}
+
+func statusError(statusCode int) error {
+	switch {
+	case statusCode == http.StatusNotFound:
+		return ErrNotFound
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode >= 500:
+		return fmt.Errorf("%w: status code %d", ErrServerFailure, statusCode)
+	default:
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, statusCode)
+	}
+}
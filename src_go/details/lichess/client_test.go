@@ -0,0 +1,121 @@
+package lichess
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/fetch"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func testConfig(url string) Config {
+	return Config{
+		Url: url,
+		Config: fetch.Config{
+			Timeout:        100 * time.Millisecond,
+			MaxRetries:     2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     5 * time.Millisecond,
+		},
+	}
+}
+
+func Test_Client_should_decode_the_profile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "/api/user/tigran-c-137", request.URL.Path)
+		writer.Write([]byte(`{"id":"tigran-c-137","username":"Tigran-C-137","createdAt":1600000000000}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(testConfig(server.URL))
+	assert.NoError(t, err)
+
+	profile, err := client.Profile(zap.NewNop(), "tigran-c-137")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://lichess.org/@/tigran-c-137", profile.UserId())
+	assert.Equal(t, int64(1600000000000), profile.CreatedAt)
+}
+
+func Test_Client_should_fail_for_a_closed_account(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte(`{"id":"tigran-c-137","username":"Tigran-C-137","disabled":true}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(testConfig(server.URL))
+	assert.NoError(t, err)
+
+	_, err = client.Profile(zap.NewNop(), "tigran-c-137")
+	assert.True(t, errors.Is(err, ErrClosed), "expected %v, got %v", ErrClosed, err)
+}
+
+func Test_Client_should_retry_server_failures_but_not_rate_limits(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writer.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(testConfig(server.URL))
+	assert.NoError(t, err)
+
+	_, err = client.Profile(zap.NewNop(), "tigran-c-137")
+	assert.True(t, errors.Is(err, ErrRateLimited), "expected %v, got %v", ErrRateLimited, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func Test_Client_should_stream_games_however_slowly_they_are_consumed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "/api/games/user/tigran-c-137", request.URL.Path)
+		assert.Equal(t, "1659312000000", request.URL.Query().Get("since"))
+		assert.Equal(t, "1661990399999", request.URL.Query().Get("until"))
+		writer.Write([]byte("{\"id\":\"q7ZvsdUF\",\"speed\":\"blitz\"}\n{\"id\":\"4bS5w1nZ\",\"speed\":\"rapid\"}\n"))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(testConfig(server.URL))
+	assert.NoError(t, err)
+
+	since := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	games := []Game{}
+	err = client.StreamGames(zap.NewNop(), "tigran-c-137", since, since.AddDate(0, 1, 0), func(game Game) error {
+		// storing the games takes longer than the timeout of a single read
+		time.Sleep(150 * time.Millisecond)
+		games = append(games, game)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Game{{Id: "q7ZvsdUF", Speed: "blitz"}, {Id: "4bS5w1nZ", Speed: "rapid"}}, games)
+}
+
+func Test_Client_should_fail_when_the_export_stalls(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte("{\"id\":\"q7ZvsdUF\"}\n"))
+		writer.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := NewClient(testConfig(server.URL))
+	assert.NoError(t, err)
+
+	since := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	consumed := 0
+	err = client.StreamGames(zap.NewNop(), "tigran-c-137", since, since.AddDate(0, 1, 0), func(game Game) error {
+		consumed++
+		return nil
+	})
+	assert.True(t, errors.Is(err, ErrStalled), "expected %v, got %v", ErrStalled, err)
+	assert.Equal(t, 1, consumed)
+}
@@ -0,0 +1,24 @@
+package lichess
+
+// SiteUrl is where the players and the games of lichess are found, the api may be served from elsewhere
+const SiteUrl = "https://lichess.org"
+
+type Profile struct {
+	Id        string `json:"id"`
+	Username  string `json:"username"`
+	CreatedAt int64  `json:"createdAt"`
+	// Disabled is set for closed accounts, lichess keeps their profiles but not their games
+	Disabled bool `json:"disabled"`
+}
+
+func (profile Profile) UserId() string {
+	return SiteUrl + "/@/" + profile.Id
+}
+
+type Game struct {
+	Id         string `json:"id"`
+	Pgn        string `json:"pgn"`
+	LastMoveAt int64  `json:"lastMoveAt"`
+	Variant    string `json:"variant"`
+	Speed      string `json:"speed"`
+}
@@ -0,0 +1,195 @@
+package pgn
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var ErrMalformedTag = errors.New("malformed tag pair")
+var ErrMissingMovetext = errors.New("game has no moves")
+var ErrMissingResult = errors.New("movetext is not terminated by a result")
+var ErrResultMismatch = errors.New("result tag does not match the game termination marker")
+
+var tagPairRegex = regexp.MustCompile(`^\[([A-Za-z0-9_]+)\s+"((?:[^"\\]|\\.)*)"\]$`)
+var moveNumberRegex = regexp.MustCompile(`^\d+\.+`)
+
+var tagValueUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`)
+var tagValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+var results = map[string]bool{
+	"1-0":     true,
+	"0-1":     true,
+	"1/2-1/2": true,
+	"*":       true,
+}
+
+type Game struct {
+	Tags map[string]string
+	// TagNames are the names of the tags in the order they are written in
+	TagNames []string
+	// Movetext keeps the line breaks, they end the comments after a semicolon
+	Movetext string
+	Result   string
+}
+
+func (game Game) Tag(name string) string {
+	return game.Tags[name]
+}
+
+// Split cuts a multi-game pgn into separate games. A new game starts with a tag pair that follows the movetext of the previous one.
+func Split(pgns string) (games []string) {
+	pgns = strings.TrimPrefix(pgns, "\ufeff")
+	pgns = strings.ReplaceAll(pgns, "\r\n", "\n")
+
+	games = make([]string, 0)
+	current := strings.Builder{}
+	inMovetext := false
+
+	flush := func() {
+		game := strings.TrimSpace(current.String())
+		if game != "" {
+			games = append(games, game+"\n")
+		}
+		current.Reset()
+		inMovetext = false
+	}
+
+	for _, line := range strings.Split(pgns, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && inMovetext {
+			flush()
+		}
+		if trimmed != "" && !strings.HasPrefix(trimmed, "[") && !strings.HasPrefix(trimmed, "%") {
+			inMovetext = true
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	flush()
+
+	return
+}
+
+// Read reads the tag pairs and the movetext of a single game, unlike Parse it does not check that the game is complete.
+func Read(pgn string) (game Game, err error) {
+	game.Tags = map[string]string{}
+	movetext := strings.Builder{}
+
+	for _, line := range strings.Split(strings.ReplaceAll(pgn, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "%") {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "[") && movetext.Len() == 0 {
+			tagPair := tagPairRegex.FindStringSubmatch(trimmed)
+			if tagPair == nil {
+				err = ErrMalformedTag
+				return
+			}
+			if _, exists := game.Tags[tagPair[1]]; !exists {
+				game.TagNames = append(game.TagNames, tagPair[1])
+			}
+			game.Tags[tagPair[1]] = tagValueUnescaper.Replace(tagPair[2])
+			continue
+		}
+
+		if movetext.Len() > 0 {
+			movetext.WriteString("\n")
+		}
+		movetext.WriteString(trimmed)
+	}
+
+	game.Movetext = movetext.String()
+	tokens := strings.Fields(stripAnnotations(game.Movetext))
+	if len(tokens) > 0 && results[tokens[len(tokens)-1]] {
+		game.Result = tokens[len(tokens)-1]
+	}
+	return
+}
+
+// Parse reads the tag pairs and the movetext of a single game and checks that the game is complete.
+func Parse(pgn string) (game Game, err error) {
+	game, err = Read(pgn)
+	if err != nil {
+		return
+	}
+
+	if len(strings.Fields(stripAnnotations(game.Movetext))) == 0 {
+		err = ErrMissingMovetext
+		return
+	}
+
+	if game.Result == "" {
+		err = ErrMissingResult
+		return
+	}
+
+	if len(game.Moves()) == 0 {
+		err = ErrMissingMovetext
+		return
+	}
+
+	if resultTag, ok := game.Tags["Result"]; ok && resultTag != game.Result {
+		err = ErrResultMismatch
+		return
+	}
+
+	return
+}
+
+// Moves are the moves of the movetext in san, the move numbers, the comments, the variations,
+// the annotations and the result are dropped
+func (game Game) Moves() (moves []string) {
+	for _, token := range strings.Fields(stripAnnotations(game.Movetext)) {
+		token = moveNumberRegex.ReplaceAllString(token, "")
+		if token == "" || strings.HasPrefix(token, "$") || results[token] {
+			continue
+		}
+		moves = append(moves, token)
+	}
+	return
+}
+
+// TagPair writes the tag pair the way Read reads it
+func TagPair(name string, value string) string {
+	return "[" + name + " \"" + tagValueEscaper.Replace(value) + "\"]"
+}
+
+// stripAnnotations drops the comments in braces, the comments till the end of the line and the variations in parentheses
+func stripAnnotations(movetext string) string {
+	builder := strings.Builder{}
+	depthOfVariation := 0
+	inComment := false
+	inLineComment := false
+	for _, char := range movetext {
+		switch {
+		case inComment:
+			if char == '}' {
+				inComment = false
+			}
+		case inLineComment:
+			if char == '\n' {
+				inLineComment = false
+				builder.WriteRune(' ')
+			}
+		case char == '{':
+			inComment = true
+			builder.WriteRune(' ')
+		case char == ';':
+			inLineComment = true
+		case char == '(':
+			depthOfVariation++
+			builder.WriteRune(' ')
+		case char == ')':
+			if depthOfVariation > 0 {
+				depthOfVariation--
+			}
+		case depthOfVariation > 0:
+		default:
+			builder.WriteRune(char)
+		}
+	}
+	return builder.String()
+}
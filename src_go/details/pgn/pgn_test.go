@@ -0,0 +1,130 @@
+package pgn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const scholarsMate = `[Event "Club championship"]
+[Site "Yerevan"]
+[Date "2022.08.02"]
+[White "tigran-c-137"]
+[Black "Nezhmetdinov"]
+[Result "1-0"]
+
+1. e4 e5 2. Qh5 Nc6 3. Bc4 {the threat} Nf6 4. Qxf7# 1-0
+`
+
+const foolsMate = `[Event "Club championship"]
+[Site "Yerevan"]
+[Date "2022.08.03"]
+[White "Nezhmetdinov"]
+[Black "tigran-c-137"]
+[Result "0-1"]
+
+1. f3 e5
+2. g4 Qh4# 0-1
+`
+
+func Test_multi_game_pgn_is_split_into_separate_games(t *testing.T) {
+	multiGamePgn := "\ufeff" + scholarsMate + "\r\n\r\n" + foolsMate + "\n\n"
+
+	actualGames := Split(multiGamePgn)
+
+	assert.Equal(t, []string{scholarsMate, foolsMate}, actualGames)
+}
+
+func Test_game_without_tags_is_split_by_the_next_tag_section(t *testing.T) {
+	multiGamePgn := "1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0\n\n" + foolsMate
+
+	actualGames := Split(multiGamePgn)
+
+	assert.Equal(t, []string{"1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0\n", foolsMate}, actualGames)
+}
+
+func Test_complete_game_is_parsed(t *testing.T) {
+	actualGame, err := Parse(foolsMate)
+	assert.NoError(t, err)
+
+	expectedGame := Game{
+		Tags: map[string]string{
+			"Event":  "Club championship",
+			"Site":   "Yerevan",
+			"Date":   "2022.08.03",
+			"White":  "Nezhmetdinov",
+			"Black":  "tigran-c-137",
+			"Result": "0-1",
+		},
+		TagNames: []string{"Event", "Site", "Date", "White", "Black", "Result"},
+		Movetext: "1. f3 e5\n2. g4 Qh4# 0-1",
+		Result:   "0-1",
+	}
+
+	assert.Equal(t, expectedGame, actualGame)
+}
+
+func Test_incomplete_games_are_rejected(t *testing.T) {
+	tests := []struct {
+		name string
+		pgn  string
+		err  error
+	}{
+		{
+			name: "malformed tag",
+			pgn:  "[White tigran-c-137]\n\n1. e4 e5 1-0\n",
+			err:  ErrMalformedTag,
+		},
+		{
+			name: "only tags",
+			pgn:  "[White \"tigran-c-137\"]\n",
+			err:  ErrMissingMovetext,
+		},
+		{
+			name: "only result",
+			pgn:  "[White \"tigran-c-137\"]\n\n{no moves} *\n",
+			err:  ErrMissingMovetext,
+		},
+		{
+			name: "no termination marker",
+			pgn:  "[White \"tigran-c-137\"]\n\n1. e4 e5 2. Nf3\n",
+			err:  ErrMissingResult,
+		},
+		{
+			name: "result mismatch",
+			pgn:  "[Result \"1-0\"]\n\n1. e4 e5 0-1\n",
+			err:  ErrResultMismatch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.pgn)
+			assert.Equal(t, test.err, err)
+		})
+	}
+}
+
+func Test_moves_drop_the_comments_variations_and_annotations(t *testing.T) {
+	game, err := Parse(`[Event "Live Chess"]
+[Result "1-0"]
+
+1. e4 {[%clk 0:09:59.9]} 1... e5 $1 2. Nf3 (2. f4 exf4) 2... Nc6?! ; the knight
+3.Bb5 a6 1-0`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e4", "e5", "Nf3", "Nc6?!", "Bb5", "a6"}, game.Moves())
+}
+
+func Test_incomplete_game_is_read(t *testing.T) {
+	game, err := Read("[White \"tigran-c-137\"]\n\n1. e4 e5 2. Nf3\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "", game.Result)
+	assert.Equal(t, []string{"e4", "e5", "Nf3"}, game.Moves())
+}
+
+func Test_tag_pair_is_read_back(t *testing.T) {
+	tagPair := TagPair("Event", `the "quoted" \ event`)
+	game, err := Read(tagPair + "\n\n1. e4 *\n")
+	assert.NoError(t, err)
+	assert.Equal(t, `the "quoted" \ event`, game.Tag("Event"))
+}
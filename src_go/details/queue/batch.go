@@ -0,0 +1,31 @@
+package queue
+
+import "github.com/aws/aws-lambda-go/events"
+
+// SealErrors keeps the failures reported by the handler, an error or a panic of the whole batch
+// turns into failures of all its commands, so they are delivered again instead of being lost
+func SealErrors(unsafeHandling func(events.SQSEvent) (events.SQSEventResponse, error)) func(events.SQSEvent) (events.SQSEventResponse, error) {
+	return func(commands events.SQSEvent) (commandsProcessed events.SQSEventResponse, err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				commandsProcessed = failAll(commands)
+			}
+		}()
+
+		commandsProcessed, err = unsafeHandling(commands)
+		if err != nil {
+			commandsProcessed = failAll(commands)
+			err = nil
+		}
+		return
+	}
+}
+
+func failAll(commands events.SQSEvent) (commandsProcessed events.SQSEventResponse) {
+	for _, message := range commands.Records {
+		commandsProcessed.BatchItemFailures = append(commandsProcessed.BatchItemFailures, events.SQSBatchItemFailure{
+			ItemIdentifier: message.MessageId,
+		})
+	}
+	return
+}
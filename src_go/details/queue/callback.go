@@ -0,0 +1,10 @@
+package queue
+
+import "encoding/json"
+
+// DeliverCallbackCommand carries the final status of a download or a search to the callback url of the client
+type DeliverCallbackCommand struct {
+	CallbackUrl    string          `json:"callbackUrl"`
+	CallbackSecret string          `json:"callbackSecret"`
+	Payload        json.RawMessage `json:"payload"`
+}
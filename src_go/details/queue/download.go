@@ -1,5 +1,13 @@
 package queue
 
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/google/uuid"
+)
+
 type DownloadGamesCommand struct {
 	Username   string   `json:"username"`
 	UserId     string   `json:"userId"`
@@ -14,3 +22,20 @@ const (
 	ChessDotCom Platform = "CHESS_DOT_COM"
 	Lichess     Platform = "LICHESS"
 )
+
+// SendDownloadGamesCommand publishes the command with a deduplication id of its own. The commands of one archive
+// are sent by downloads, syncs and retries that may come within the deduplication window, none of them may be dropped
+func SendDownloadGamesCommand(svc *sqs.SQS, downloadGamesQueueUrl string, command DownloadGamesCommand) (err error) {
+	jsonBody, err := json.Marshal(command)
+	if err != nil {
+		return
+	}
+
+	_, err = svc.SendMessage(&sqs.SendMessageInput{
+		QueueUrl:               aws.String(downloadGamesQueueUrl),
+		MessageBody:            aws.String(string(jsonBody)),
+		MessageDeduplicationId: aws.String(uuid.New().String()),
+		MessageGroupId:         aws.String(command.UserId),
+	})
+	return
+}
@@ -1,3 +1,11 @@
 module github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue
 
 go 1.21.0
+
+require (
+	github.com/aws/aws-lambda-go v1.41.0
+	github.com/aws/aws-sdk-go v1.45.24
+	github.com/google/uuid v1.3.1
+)
+
+require github.com/jmespath/go-jmespath v0.4.0 // indirect
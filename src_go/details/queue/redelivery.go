@@ -0,0 +1,17 @@
+package queue
+
+import "strconv"
+
+// MaxReceiveCount is how many times a command is delivered before it is moved to the dead letter queue,
+// it has to match maxReceiveCount of the redrive policies in queue.yaml
+const MaxReceiveCount = 5
+
+// IsLastReceive tells whether the command will not be delivered again if it fails now,
+// approximateReceiveCount is the ApproximateReceiveCount attribute of the message
+func IsLastReceive(approximateReceiveCount string) bool {
+	receiveCount, err := strconv.Atoi(approximateReceiveCount)
+	if err != nil {
+		return false
+	}
+	return receiveCount >= MaxReceiveCount
+}
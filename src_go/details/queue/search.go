@@ -4,4 +4,27 @@ type SearchBoardCommand struct {
 	SearchId string `json:"searchId"`
 	Board    string `json:"board"`
 	UserId   string `json:"userId"`
+	// Variant is named by the rules of chess.com, the commands sent before it was introduced search the standard games
+	Variant string `json:"variant,omitempty"`
+	// MatchLimit stops the search once that many games are matched, the commands without it stop at 10
+	MatchLimit int `json:"matchLimit,omitempty"`
+	// Filter is optional, without it all the games of the variant are searched
+	Filter *SearchFilter `json:"filter,omitempty"`
+	// ArchiveId narrows the command down to one shard of the search, the commands without it search all the games of the user
+	ArchiveId string `json:"archiveId,omitempty"`
+}
+
+// SearchFilter narrows the search down to the games with the given metadata, the empty fields do not filter
+type SearchFilter struct {
+	// Username is the player the color, the opponent and the result are told for
+	Username string `json:"username"`
+	// Aliases are the other usernames the player has been cached under, the games played under them are the player's too
+	Aliases []string `json:"aliases,omitempty"`
+	// From and To are the unix seconds the games end within, To is exclusive
+	From      int64  `json:"from,omitempty"`
+	To        int64  `json:"to,omitempty"`
+	Color     string `json:"color,omitempty"`
+	Opponent  string `json:"opponent,omitempty"`
+	Result    string `json:"result,omitempty"`
+	TimeClass string `json:"timeClass,omitempty"`
 }
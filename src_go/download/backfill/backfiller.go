@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/pgn"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const maxGamesPerScan = 100
+
+// GameBackfiller extracts the structured fields of the games that were stored before they were known at ingestion.
+// Every game gets the rules at least, so the games without them are exactly the ones to backfill
+type GameBackfiller struct {
+	gamesTableName string
+	awsConfig      *aws.Config
+}
+
+func (backfiller *GameBackfiller) Backfill() (backfilled int, err error) {
+	config := zap.NewProductionConfig()
+	config.OutputPaths = []string{"stdout"}
+	timeEncoder := func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.UTC().Format("2006-01-02T15:04:05.000Z"))
+	}
+	config.EncoderConfig.EncodeTime = timeEncoder
+
+	logger, err := config.Build()
+	if err != nil {
+		return
+	}
+	defer logger.Sync()
+
+	awsSession, err := session.NewSession(backfiller.awsConfig)
+	if err != nil {
+		logger.Error("impossible to create an AWS session!", zap.Error(err))
+		return
+	}
+	dynamodbClient := dynamodb.New(awsSession)
+
+	skipped := 0
+	var lastKey map[string]*dynamodb.AttributeValue
+	for {
+		var scanOutput *dynamodb.ScanOutput
+		scanOutput, err = dynamodbClient.Scan(&dynamodb.ScanInput{
+			TableName:         aws.String(backfiller.gamesTableName),
+			FilterExpression:  aws.String("attribute_not_exists(rules)"),
+			Limit:             aws.Int64(maxGamesPerScan),
+			ExclusiveStartKey: lastKey,
+		})
+		if err != nil {
+			logger.Error("impossible to scan the games", zap.Error(err))
+			return
+		}
+
+		gameRecords := []games.GameRecord{}
+		err = dynamodbattribute.UnmarshalListOfMaps(scanOutput.Items, &gameRecords)
+		if err != nil {
+			logger.Error("impossible to unmarshal the games", zap.Error(err))
+			return
+		}
+
+		for _, gameRecord := range gameRecords {
+			var isBackfilled bool
+			isBackfilled, err = backfiller.backfillGame(dynamodbClient, logger, gameRecord)
+			if err != nil {
+				return
+			}
+			if isBackfilled {
+				backfilled++
+			} else {
+				skipped++
+			}
+		}
+
+		logger.Info("games backfilled so far", zap.Int("backfilled", backfilled), zap.Int("skipped", skipped))
+
+		lastKey = scanOutput.LastEvaluatedKey
+		if len(lastKey) == 0 {
+			break
+		}
+	}
+
+	logger.Info("backfill finished", zap.Int("backfilled", backfilled), zap.Int("skipped", skipped))
+	return
+}
+
+func (backfiller *GameBackfiller) backfillGame(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	gameRecord games.GameRecord,
+) (isBackfilled bool, err error) {
+	logger = logger.With(zap.String("userId", gameRecord.UserId), zap.String("gameId", gameRecord.GameId))
+
+	// a pgn that can not be parsed completely still gives the tags that were read before the failure
+	parsedPgn, errOfParsing := pgn.Parse(gameRecord.Pgn)
+	if errOfParsing != nil {
+		logger.Warn("pgn of the game is not complete", zap.Error(errOfParsing))
+	}
+	gameRecord = gameRecord.WithPgnTags(parsedPgn.Tags)
+
+	gameRecordItems, err := dynamodbattribute.MarshalMap(gameRecord)
+	if err != nil {
+		logger.Error("impossible to marshal the game record", zap.Error(err))
+		return
+	}
+
+	// the game could be downloaded again in the meantime, then it already has the fields and is not overwritten
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String(backfiller.gamesTableName),
+		Item:                gameRecordItems,
+		ConditionExpression: aws.String("attribute_exists(game_id) AND attribute_not_exists(rules)"),
+	})
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		logger.Info("game was changed since the scan, skipping it")
+		err = nil
+		return
+	}
+
+	if err != nil {
+		logger.Error("impossible to persist the backfilled game record", zap.Error(err))
+		return
+	}
+
+	isBackfilled = true
+	return
+}
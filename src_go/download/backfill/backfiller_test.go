@@ -0,0 +1,124 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var awsConfig = aws.Config{
+	Region:     aws.String("us-east-1"),
+	Endpoint:   aws.String("http://localhost:4566"), // this is the LocalStack endpoint for all services
+	DisableSSL: aws.Bool(true),
+}
+
+var backfiller = GameBackfiller{
+	gamesTableName: "chessfinder_dynamodb-games",
+	awsConfig:      &awsConfig,
+}
+
+var awsSession = session.Must(session.NewSession(&awsConfig))
+
+var dynamodbClient = dynamodb.New(awsSession)
+
+func Test_GameBackfiller_should_fill_the_structured_fields_of_the_games_stored_without_them(t *testing.T) {
+	userId := uuid.New().String()
+	archiveId := uuid.New().String()
+
+	oldGame := games.GameRecord{
+		UserId:       userId,
+		ArchiveId:    archiveId,
+		GameId:       "https://www.chess.com/game/live/53168947271",
+		Resource:     "https://www.chess.com/game/live/53168947271",
+		Pgn:          "[Event \"Live Chess\"]\n[Site \"Chess.com\"]\n[Round \"-\"]\n[White \"tigran-c-137\"]\n[Black \"akashvishwakarma000\"]\n[Result \"1-0\"]\n[ECO \"A40\"]\n[WhiteElo \"997\"]\n[BlackElo \"917\"]\n[TimeControl \"300\"]\n\n1. d4 {[%clk 0:04:48.8]} 1... b5 {[%clk 0:04:54.6]} 2. Nc3 {[%clk 0:04:40.2]} 1-0\n",
+		EndTimestamp: 1659429921,
+	}
+
+	backfilledGame := games.GameRecord{
+		UserId:       userId,
+		ArchiveId:    archiveId,
+		GameId:       "https://www.chess.com/game/live/53170213967",
+		Resource:     "https://www.chess.com/game/live/53170213967",
+		Pgn:          "[White \"philoz87\"]\n[Black \"tigran-c-137\"]\n[Result \"0-1\"]\n\n1. e4 e5 0-1\n",
+		EndTimestamp: 1659431445,
+		White:        "philoz87",
+		Black:        "tigran-c-137",
+		WhiteRating:  999,
+		BlackRating:  989,
+		Result:       "0-1",
+		Eco:          "C42",
+		TimeControl:  "300",
+		TimeClass:    games.Blitz,
+		Rules:        games.StandardRules,
+	}
+
+	err := backfiller.persistGames([]games.GameRecord{oldGame, backfilledGame})
+	assert.NoError(t, err)
+
+	backfilled, err := backfiller.Backfill()
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, backfilled, 1)
+
+	actualOldGame, err := backfiller.getGame(userId, oldGame.GameId)
+	assert.NoError(t, err)
+
+	expectedOldGame := oldGame
+	expectedOldGame.White = "tigran-c-137"
+	expectedOldGame.Black = "akashvishwakarma000"
+	expectedOldGame.WhiteRating = 997
+	expectedOldGame.BlackRating = 917
+	expectedOldGame.Result = "1-0"
+	expectedOldGame.Eco = "A40"
+	expectedOldGame.TimeControl = "300"
+	expectedOldGame.TimeClass = games.Blitz
+	expectedOldGame.Rules = games.StandardRules
+
+	assert.Equal(t, expectedOldGame, actualOldGame)
+
+	actualBackfilledGame, err := backfiller.getGame(userId, backfilledGame.GameId)
+	assert.NoError(t, err)
+	assert.Equal(t, backfilledGame, actualBackfilledGame)
+}
+
+func (backfiller *GameBackfiller) persistGames(gameRecords []games.GameRecord) (err error) {
+	for _, gameRecord := range gameRecords {
+		var gameRecordItems map[string]*dynamodb.AttributeValue
+		gameRecordItems, err = dynamodbattribute.MarshalMap(gameRecord)
+		if err != nil {
+			return
+		}
+		_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+			TableName: aws.String(backfiller.gamesTableName),
+			Item:      gameRecordItems,
+		})
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (backfiller *GameBackfiller) getGame(userId string, gameId string) (gameRecord games.GameRecord, err error) {
+	getItemOutput, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(backfiller.gamesTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"user_id": {
+				S: aws.String(userId),
+			},
+			"game_id": {
+				S: aws.String(gameId),
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+	err = dynamodbattribute.UnmarshalMap(getItemOutput.Item, &gameRecord)
+	return
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// main runs the backfill once, it is meant to be started by hand against the tables of an environment
+func main() {
+
+	gamesTableName, gamesTableNameExists := os.LookupEnv("GAMES_TABLE_NAME")
+	if !gamesTableNameExists {
+		panic(errors.New("GAMES_TABLE_NAME is missing"))
+	}
+
+	awsRegion, awsRegionExists := os.LookupEnv("AWS_REGION")
+	if !awsRegionExists {
+		panic(errors.New("AWS_REGION is missing"))
+	}
+
+	backfiller := GameBackfiller{
+		gamesTableName: gamesTableName,
+		awsConfig: &aws.Config{
+			Region: &awsRegion,
+		},
+	}
+
+	backfilled, err := backfiller.Backfill()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backfill failed after %v games: %v\n", backfilled, err)
+		os.Exit(1)
+	}
+	fmt.Printf("backfilled %v games\n", backfilled)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/downloads"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type DownloadCanceller struct {
+	awsConfig          *aws.Config
+	downloadsTableName string
+}
+
+// Cancel only marks the download as cancelled, the archives still in the queue are skipped by the game downloader
+func (canceller *DownloadCanceller) Cancel(event *events.APIGatewayV2HTTPRequest) (responseEvent events.APIGatewayV2HTTPResponse, err error) {
+
+	config := zap.NewProductionConfig()
+	config.OutputPaths = []string{"stdout"}
+	timeEncoder := func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.UTC().Format("2006-01-02T15:04:05.000Z"))
+	} // Log to stdout
+	config.EncoderConfig.EncodeTime = timeEncoder
+
+	// Create the logger from the configuration
+	logger, err := config.Build()
+	if err != nil {
+		panic(err)
+	}
+	logger = logger.With(zap.String("requestId", event.RequestContext.RequestID))
+	defer logger.Sync()
+
+	awsSession, err := session.NewSession(canceller.awsConfig)
+	if err != nil {
+		logger.Panic("impossible to create an AWS session!", zap.Error(err))
+	}
+
+	dynamodbClient := dynamodb.New(awsSession)
+
+	method := event.RequestContext.HTTP.Method
+	path := event.RequestContext.HTTP.Path
+
+	if path != "/api/faster/game" || method != "DELETE" {
+		logger.Panic("download canceller is attached to a wrong route!")
+	}
+
+	downloadId, downloadIdExists := event.QueryStringParameters["downloadId"]
+	if !downloadIdExists {
+		err = api.ValidationError{
+			Msg: "query parameter downloadId is missing",
+		}
+		return
+	}
+
+	logger = logger.With(zap.String("downloadId", downloadId))
+
+	downloadItems, err := dynamodbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"download_id": {
+				S: aws.String(downloadId),
+			},
+		},
+		TableName:           aws.String(canceller.downloadsTableName),
+		UpdateExpression:    aws.String("SET cancel_requested = :cancel_requested"),
+		ConditionExpression: aws.String("attribute_exists(download_id)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":cancel_requested": {
+				BOOL: aws.Bool(true),
+			},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+	})
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		logger.Error("no dowload request found!")
+		err = api.DownloadNotFound(downloadId)
+		return
+	}
+
+	if err != nil {
+		logger.Error("faild to cancel the download", zap.Error(err))
+		return
+	}
+
+	logger.Info("download is cancelled")
+
+	downloadRecord := downloads.DownloadRecord{}
+	err = dynamodbattribute.UnmarshalMap(downloadItems.Attributes, &downloadRecord)
+	if err != nil {
+		logger.Error("faild to unmarshal download record!", zap.Error(err))
+		return
+	}
+	downloadStatusResponse := DownloadStatusResponse{
+		DownloadId: downloadRecord.DownloadId,
+		Failed:     downloadRecord.Failed,
+		Succeed:    downloadRecord.Succeed,
+		Cancelled:  downloadRecord.Cancelled,
+		Done:       downloadRecord.Done,
+		Pending:    downloadRecord.Pending,
+		Total:      downloadRecord.Total,
+	}
+
+	responseBody, err := json.Marshal(downloadStatusResponse)
+	if err != nil {
+		logger.Error("faild to marshal download response", zap.Error(err))
+		return
+	}
+	responseEvent = events.APIGatewayV2HTTPResponse{
+		Body:       string(responseBody),
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}
+	return
+}
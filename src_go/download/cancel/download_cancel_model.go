@@ -0,0 +1,11 @@
+package main
+
+type DownloadStatusResponse struct {
+	DownloadId string `json:"downloadId"`
+	Failed     int    `json:"failed"`
+	Succeed    int    `json:"succeed"`
+	Cancelled  int    `json:"cancelled"`
+	Done       int    `json:"done"`
+	Pending    int    `json:"pending"`
+	Total      int    `json:"total"`
+}
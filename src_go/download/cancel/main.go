@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+)
+
+func main() {
+	downloadsTableName, downloadsTableNameExists := os.LookupEnv("DOWNLOADS_TABLE_NAME")
+	if !downloadsTableNameExists {
+		panic(errors.New("DOWNLOADS_TABLE_NAME is missing"))
+	}
+
+	awsRegion, awsRegionExists := os.LookupEnv("AWS_REGION")
+	if !awsRegionExists {
+		panic(errors.New("AWS_REGION is missing"))
+	}
+
+	canceller := DownloadCanceller{
+		downloadsTableName: downloadsTableName,
+		awsConfig: &aws.Config{
+			Region: &awsRegion,
+		},
+	}
+
+	lambda.Start(api.WithRecover(canceller.Cancel))
+}
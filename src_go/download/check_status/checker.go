@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -16,8 +17,11 @@ import (
 )
 
 type DownloadStatusChecker struct {
-	awsConfig          *aws.Config
-	downloadsTableName string
+	awsConfig                *aws.Config
+	downloadsTableName       string
+	archiveOutcomesTableName string
+	pollInterval             time.Duration
+	maxWait                  time.Duration
 }
 
 func (checker *DownloadStatusChecker) Check(event *events.APIGatewayV2HTTPRequest) (responseEvent events.APIGatewayV2HTTPResponse, err error) {
@@ -59,41 +63,50 @@ func (checker *DownloadStatusChecker) Check(event *events.APIGatewayV2HTTPReques
 		return
 	}
 
-	logger = logger.With(zap.String("downloadId", downloadId))
+	withDetails := false
+	details, detailsExists := event.QueryStringParameters["details"]
+	if detailsExists {
+		withDetails, err = strconv.ParseBool(details)
+		if err != nil {
+			err = api.ValidationError{
+				Msg: "query parameter details must be either true or false",
+			}
+			return
+		}
+	}
 
-	downloadItems, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
-		Key: map[string]*dynamodb.AttributeValue{
-			"download_id": {
-				S: aws.String(downloadId),
-			},
-		},
-		TableName: aws.String(checker.downloadsTableName),
-	})
+	logger = logger.With(zap.String("downloadId", downloadId))
 
+	downloadRecord, err := checker.getDownloadRecord(dynamodbClient, logger, downloadId)
 	if err != nil {
-		logger.Error("faild to get download record", zap.Error(err))
 		return
 	}
 
-	if downloadItems.Item == nil || len(downloadItems.Item) == 0 {
-		logger.Error("no dowload request found!", zap.String("downloadId", downloadId))
-		err = DownloadNotFound(downloadId)
-		return
+	// the client that knows the current version waits for the next one instead of polling
+	waitForChangeSince, waitForChangeSinceExists := event.QueryStringParameters["waitForChangeSince"]
+	if waitForChangeSinceExists {
+		downloadRecord, err = checker.waitForChange(dynamodbClient, logger, downloadRecord, waitForChangeSince)
+		if err != nil {
+			return
+		}
 	}
 
-	downloadRecord := downloads.DownloadRecord{}
-	err = dynamodbattribute.UnmarshalMap(downloadItems.Item, &downloadRecord)
-	if err != nil {
-		logger.Error("faild to unmarshal download record!", zap.Error(err))
-		return
-	}
 	downloadStatusResponse := DownloadStatusResponse{
 		DownloadId: downloadRecord.DownloadId,
 		Failed:     downloadRecord.Failed,
 		Succeed:    downloadRecord.Succeed,
+		Cancelled:  downloadRecord.Cancelled,
 		Done:       downloadRecord.Done,
 		Pending:    downloadRecord.Pending,
 		Total:      downloadRecord.Total,
+		Version:    downloadVersion(downloadRecord),
+	}
+
+	if withDetails {
+		downloadStatusResponse.Archives, err = checker.getArchiveOutcomes(dynamodbClient, logger, downloadId)
+		if err != nil {
+			return
+		}
 	}
 
 	responseBody, err := json.Marshal(downloadStatusResponse)
@@ -110,3 +123,111 @@ func (checker *DownloadStatusChecker) Check(event *events.APIGatewayV2HTTPReques
 	}
 	return
 }
+
+func (checker *DownloadStatusChecker) getDownloadRecord(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	downloadId string,
+) (downloadRecord downloads.DownloadRecord, err error) {
+	downloadItems, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"download_id": {
+				S: aws.String(downloadId),
+			},
+		},
+		TableName: aws.String(checker.downloadsTableName),
+	})
+
+	if err != nil {
+		logger.Error("faild to get download record", zap.Error(err))
+		return
+	}
+
+	if downloadItems.Item == nil || len(downloadItems.Item) == 0 {
+		logger.Error("no dowload request found!", zap.String("downloadId", downloadId))
+		err = api.DownloadNotFound(downloadId)
+		return
+	}
+
+	err = dynamodbattribute.UnmarshalMap(downloadItems.Item, &downloadRecord)
+	if err != nil {
+		logger.Error("faild to unmarshal download record!", zap.Error(err))
+		return
+	}
+	return
+}
+
+// waitForChange rereads the download until its version differs from the one the client knows,
+// the download is over or the wait runs out, then the latest state is returned
+func (checker *DownloadStatusChecker) waitForChange(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	downloadRecord downloads.DownloadRecord,
+	knownVersion string,
+) (latestDownloadRecord downloads.DownloadRecord, err error) {
+	latestDownloadRecord = downloadRecord
+	waitUntil := time.Now().Add(checker.maxWait)
+	polls := 0
+	for downloadVersion(latestDownloadRecord) == knownVersion && latestDownloadRecord.Done < latestDownloadRecord.Total {
+		if !time.Now().Add(checker.pollInterval).Before(waitUntil) {
+			break
+		}
+		time.Sleep(checker.pollInterval)
+		polls++
+		latestDownloadRecord, err = checker.getDownloadRecord(dynamodbClient, logger, downloadRecord.DownloadId)
+		if err != nil {
+			return
+		}
+	}
+	logger.Info("waited for the change of the download", zap.Int("polls", polls), zap.Bool("changed", downloadVersion(latestDownloadRecord) != knownVersion))
+	return
+}
+
+func (checker *DownloadStatusChecker) getArchiveOutcomes(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	downloadId string,
+) (archiveOutcomes []ArchiveOutcomeResponse, err error) {
+	archiveOutcomes = []ArchiveOutcomeResponse{}
+	var lastKey map[string]*dynamodb.AttributeValue
+	for {
+		var response *dynamodb.QueryOutput
+		response, err = dynamodbClient.Query(&dynamodb.QueryInput{
+			TableName:              aws.String(checker.archiveOutcomesTableName),
+			KeyConditionExpression: aws.String("download_id = :download_id"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":download_id": {
+					S: aws.String(downloadId),
+				},
+			},
+			ExclusiveStartKey: lastKey,
+		})
+		if err != nil {
+			logger.Error("faild to query archive outcomes", zap.Error(err))
+			return
+		}
+
+		archiveOutcomeRecords := []downloads.ArchiveOutcomeRecord{}
+		err = dynamodbattribute.UnmarshalListOfMaps(response.Items, &archiveOutcomeRecords)
+		if err != nil {
+			logger.Error("faild to unmarshal archive outcomes", zap.Error(err))
+			return
+		}
+
+		for _, archiveOutcomeRecord := range archiveOutcomeRecords {
+			archiveOutcomes = append(archiveOutcomes, ArchiveOutcomeResponse{
+				ArchiveId:      archiveOutcomeRecord.ArchiveId,
+				State:          string(archiveOutcomeRecord.State),
+				ErrorCode:      archiveOutcomeRecord.ErrorCode,
+				GamesAdded:     archiveOutcomeRecord.GamesAdded,
+				DurationMillis: archiveOutcomeRecord.DurationMillis,
+			})
+		}
+
+		lastKey = response.LastEvaluatedKey
+		if len(lastKey) == 0 {
+			break
+		}
+	}
+	return
+}
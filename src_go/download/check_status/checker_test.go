@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go/aws"
@@ -22,8 +23,11 @@ var awsConfig = aws.Config{
 }
 
 var statusChecker = DownloadStatusChecker{
-	awsConfig:          &awsConfig,
-	downloadsTableName: "chessfinder_dynamodb-downloads",
+	awsConfig:                &awsConfig,
+	downloadsTableName:       "chessfinder_dynamodb-downloads",
+	archiveOutcomesTableName: "chessfinder_dynamodb-archiveOutcomes",
+	pollInterval:             100 * time.Millisecond,
+	maxWait:                  2 * time.Second,
 }
 
 var awsSession = session.Must(session.NewSession(&awsConfig))
@@ -48,7 +52,8 @@ func Test_download_task_status_is_delivered_if_there_is_a_task_for_given_id(t *t
 	dowloadRecord := downloads.DownloadRecord{
 		DownloadId: downloadId,
 		Succeed:    2,
-		Failed:     5,
+		Failed:     4,
+		Cancelled:  1,
 		Done:       7,
 		Pending:    3,
 		Total:      10,
@@ -66,7 +71,182 @@ func Test_download_task_status_is_delivered_if_there_is_a_task_for_given_id(t *t
 	actualResponse, err := statusChecker.Check(&event)
 	assert.NoError(t, err)
 
-	expectedResponseBody := fmt.Sprintf(`{"downloadId":"%v","failed":5,"succeed":2,"done":7,"pending":3,"total":10}`, downloadId)
+	expectedResponseBody := fmt.Sprintf(`{"downloadId":"%v","failed":4,"succeed":2,"cancelled":1,"done":7,"pending":3,"total":10,"version":"2-4-1-3-10"}`, downloadId)
+
+	assert.JSONEq(t, expectedResponseBody, actualResponse.Body, "Expected download status is not met!")
+	assert.Equal(t, 200, actualResponse.StatusCode, "Expected status code is not met!")
+}
+
+func Test_download_task_status_lists_outcomes_of_archives_if_details_are_requested(t *testing.T) {
+	var err error
+	downloadId := uuid.New().String()
+
+	event := events.APIGatewayV2HTTPRequest{
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "GET",
+				Path:   "/api/faster/game",
+			},
+		},
+		QueryStringParameters: map[string]string{
+			"downloadId": downloadId,
+			"details":    "true",
+		},
+	}
+
+	dowloadRecord := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Succeed:    1,
+		Failed:     1,
+		Done:       2,
+		Pending:    0,
+		Total:      2,
+	}
+
+	item, err := dynamodbattribute.MarshalMap(dowloadRecord)
+	assert.NoError(t, err)
+
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(statusChecker.downloadsTableName),
+	})
+	assert.NoError(t, err)
+
+	archiveOutcomes := []downloads.ArchiveOutcomeRecord{
+		{
+			DownloadId:     downloadId,
+			ArchiveId:      "https://api.chess.com/pub/player/tigran-c-137/games/2022/07",
+			State:          downloads.ArchiveSucceeded,
+			GamesAdded:     12,
+			DurationMillis: 830,
+		},
+		{
+			DownloadId:     downloadId,
+			ArchiveId:      "https://api.chess.com/pub/player/tigran-c-137/games/2022/08",
+			State:          downloads.ArchiveFailed,
+			ErrorCode:      "RATE_LIMITED",
+			GamesAdded:     0,
+			DurationMillis: 4200,
+		},
+	}
+	for _, archiveOutcome := range archiveOutcomes {
+		item, err = dynamodbattribute.MarshalMap(archiveOutcome)
+		assert.NoError(t, err)
+
+		_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+			Item:      item,
+			TableName: aws.String(statusChecker.archiveOutcomesTableName),
+		})
+		assert.NoError(t, err)
+	}
+
+	actualResponse, err := statusChecker.Check(&event)
+	assert.NoError(t, err)
+
+	expectedResponseBody := fmt.Sprintf(
+		`{
+			"downloadId":"%v","failed":1,"succeed":1,"cancelled":0,"done":2,"pending":0,"total":2,"version":"1-1-0-0-2",
+			"archives":[
+				{"archiveId":"https://api.chess.com/pub/player/tigran-c-137/games/2022/07","state":"SUCCEEDED","gamesAdded":12,"durationMillis":830},
+				{"archiveId":"https://api.chess.com/pub/player/tigran-c-137/games/2022/08","state":"FAILED","errorCode":"RATE_LIMITED","gamesAdded":0,"durationMillis":4200}
+			]
+		}`,
+		downloadId,
+	)
+
+	assert.JSONEq(t, expectedResponseBody, actualResponse.Body, "Expected download status is not met!")
+	assert.Equal(t, 200, actualResponse.StatusCode, "Expected status code is not met!")
+}
+
+func Test_download_task_status_is_delivered_once_it_changes_if_the_client_waits_for_a_change(t *testing.T) {
+	var err error
+	downloadId := uuid.New().String()
+
+	event := events.APIGatewayV2HTTPRequest{
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "GET",
+				Path:   "/api/faster/game",
+			},
+		},
+		QueryStringParameters: map[string]string{
+			"downloadId":         downloadId,
+			"waitForChangeSince": "0-0-0-2-2",
+		},
+	}
+
+	dowloadRecord := downloads.NewDownloadRecord(downloadId, 2)
+
+	item, err := dynamodbattribute.MarshalMap(dowloadRecord)
+	assert.NoError(t, err)
+
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(statusChecker.downloadsTableName),
+	})
+	assert.NoError(t, err)
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		_, _ = dynamodbClient.UpdateItem(&dynamodb.UpdateItemInput{
+			TableName: aws.String(statusChecker.downloadsTableName),
+			Key: map[string]*dynamodb.AttributeValue{
+				"download_id": {
+					S: aws.String(downloadId),
+				},
+			},
+			UpdateExpression: aws.String("SET succeed = :one, done = :one, pending = :one"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":one": {
+					N: aws.String("1"),
+				},
+			},
+		})
+	}()
+
+	actualResponse, err := statusChecker.Check(&event)
+	assert.NoError(t, err)
+
+	expectedResponseBody := fmt.Sprintf(`{"downloadId":"%v","failed":0,"succeed":1,"cancelled":0,"done":1,"pending":1,"total":2,"version":"1-0-0-1-2"}`, downloadId)
+
+	assert.JSONEq(t, expectedResponseBody, actualResponse.Body, "Expected download status is not met!")
+	assert.Equal(t, 200, actualResponse.StatusCode, "Expected status code is not met!")
+}
+
+func Test_download_task_status_is_delivered_unchanged_after_the_wait_if_nothing_happens(t *testing.T) {
+	var err error
+	downloadId := uuid.New().String()
+
+	event := events.APIGatewayV2HTTPRequest{
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "GET",
+				Path:   "/api/faster/game",
+			},
+		},
+		QueryStringParameters: map[string]string{
+			"downloadId":         downloadId,
+			"waitForChangeSince": "0-0-0-2-2",
+		},
+	}
+
+	dowloadRecord := downloads.NewDownloadRecord(downloadId, 2)
+
+	item, err := dynamodbattribute.MarshalMap(dowloadRecord)
+	assert.NoError(t, err)
+
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(statusChecker.downloadsTableName),
+	})
+	assert.NoError(t, err)
+
+	startedAt := time.Now()
+	actualResponse, err := statusChecker.Check(&event)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(startedAt), statusChecker.maxWait-statusChecker.pollInterval)
+
+	expectedResponseBody := fmt.Sprintf(`{"downloadId":"%v","failed":0,"succeed":0,"cancelled":0,"done":0,"pending":2,"total":2,"version":"0-0-0-2-2"}`, downloadId)
 
 	assert.JSONEq(t, expectedResponseBody, actualResponse.Body, "Expected download status is not met!")
 	assert.Equal(t, 200, actualResponse.StatusCode, "Expected status code is not met!")
@@ -3,21 +3,38 @@ package main
 import (
 	"fmt"
 
-	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/downloads"
 )
 
 type DownloadStatusResponse struct {
 	DownloadId string `json:"downloadId"`
 	Failed     int    `json:"failed"`
 	Succeed    int    `json:"succeed"`
+	Cancelled  int    `json:"cancelled"`
 	Done       int    `json:"done"`
 	Pending    int    `json:"pending"`
 	Total      int    `json:"total"`
+	// Version changes whenever any of the counters does, it is given back as waitForChangeSince to wait for the progress
+	Version string `json:"version"`
+	// Archives is filled only on request, a download can span hundreds of months
+	Archives []ArchiveOutcomeResponse `json:"archives,omitempty"`
 }
 
-func DownloadNotFound(downloadId string) api.BusinessError {
-	return api.BusinessError{
-		Msg:  fmt.Sprintf("Download request %v not found", downloadId),
-		Code: "DOWNLOAD_REQUEST_NOT_FOUND",
-	}
+type ArchiveOutcomeResponse struct {
+	ArchiveId      string `json:"archiveId"`
+	State          string `json:"state"`
+	ErrorCode      string `json:"errorCode,omitempty"`
+	GamesAdded     int    `json:"gamesAdded"`
+	DurationMillis int64  `json:"durationMillis"`
+}
+
+func downloadVersion(downloadRecord downloads.DownloadRecord) string {
+	return fmt.Sprintf(
+		"%v-%v-%v-%v-%v",
+		downloadRecord.Succeed,
+		downloadRecord.Failed,
+		downloadRecord.Cancelled,
+		downloadRecord.Pending,
+		downloadRecord.Total,
+	)
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"os"
+	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
@@ -15,13 +16,21 @@ func main() {
 		panic(errors.New("DOWNLOADS_TABLE_NAME is missing"))
 	}
 
+	archiveOutcomesTableName, archiveOutcomesTableNameExists := os.LookupEnv("ARCHIVE_OUTCOMES_TABLE_NAME")
+	if !archiveOutcomesTableNameExists {
+		panic(errors.New("ARCHIVE_OUTCOMES_TABLE_NAME is missing"))
+	}
+
 	awsRegion, awsRegionExists := os.LookupEnv("AWS_REGION")
 	if !awsRegionExists {
 		panic(errors.New("AWS_REGION is missing"))
 	}
 
 	checker := DownloadStatusChecker{
-		downloadsTableName: downloadsTableName,
+		downloadsTableName:       downloadsTableName,
+		archiveOutcomesTableName: archiveOutcomesTableName,
+		pollInterval:             time.Second,
+		maxWait:                  20 * time.Second,
 		awsConfig: &aws.Config{
 			Region: &awsRegion,
 		},
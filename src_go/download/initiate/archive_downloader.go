@@ -1,12 +1,9 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
-	"io"
-	"net/http"
+	"errors"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -17,9 +14,12 @@ import (
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/batcher"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/callback"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/archives"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/downloads"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/users"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/lichess"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -27,12 +27,15 @@ import (
 )
 
 type ArchiveDownloader struct {
-	chessDotComUrl        string
-	awsConfig             *aws.Config
-	usersTableName        string
-	archivesTableName     string
-	downloadsTableName    string
-	downloadGamesQueueUrl string
+	chessDotComConfig        chessdotcom.Config
+	lichessConfig            lichess.Config
+	awsConfig                *aws.Config
+	usersTableName           string
+	usersByPlayerIdIndexName string
+	archivesTableName        string
+	downloadsTableName       string
+	downloadGamesQueueUrl    string
+	deliverCallbacksQueueUrl string
 }
 
 func (downloader *ArchiveDownloader) DownloadArchiveAndDistributeDonwloadGameCommands(
@@ -60,7 +63,16 @@ func (downloader *ArchiveDownloader) DownloadArchiveAndDistributeDonwloadGameCom
 	}
 	dynamodbClient := dynamodb.New(awsSession)
 	svc := sqs.New(awsSession)
-	chessDotComClient := &http.Client{}
+	chessDotComClient, err := chessdotcom.NewClient(downloader.chessDotComConfig)
+	if err != nil {
+		logger.Panic("impossible to create a chess.com client!", zap.Error(err))
+		return
+	}
+	lichessClient, err := lichess.NewClient(downloader.lichessConfig)
+	if err != nil {
+		logger.Panic("impossible to create a lichess client!", zap.Error(err))
+		return
+	}
 
 	method := event.RequestContext.HTTP.Method
 	path := event.RequestContext.HTTP.Path
@@ -76,17 +88,65 @@ func (downloader *ArchiveDownloader) DownloadArchiveAndDistributeDonwloadGameCom
 		return
 	}
 
+	downloadRequest.Username = users.NormalizeUsername(downloadRequest.Username)
 	logger = logger.With(zap.String("username", downloadRequest.Username), zap.String("platform", downloadRequest.Platform))
 
-	profile, err := downloader.getAndPersistUser(dynamodbClient, chessDotComClient, logger, downloadRequest)
+	monthRange, err := archives.ParseMonthRange(downloadRequest.From, downloadRequest.To)
 	if err != nil {
+		logger.Error("invalid range of months!", zap.Error(err))
+		err = InvalidMonthRange(err)
+		return
+	}
+
+	if downloadRequest.CallbackUrl != "" && callback.ValidateUrl(downloadRequest.CallbackUrl) != nil {
+		logger.Info("invalid callback url", zap.String("callbackUrl", downloadRequest.CallbackUrl))
+		err = InvalidCallbackUrl(downloadRequest)
 		return
 	}
 
-	logger = logger.With(zap.String("userId", profile.UserId))
+	var profile users.UserRecord
+	var archiveUrls []string
+	switch users.Platform(downloadRequest.Platform) {
+	case users.ChessDotCom:
+		profile, err = downloader.getAndPersistUser(dynamodbClient, chessDotComClient, logger, downloadRequest)
+		if err != nil {
+			return
+		}
+
+		logger = logger.With(zap.String("userId", profile.UserId))
 
-	archivesFromChessDotCom, err := downloader.getArchivesFromChessDotCom(logger, profile)
-	if err != nil {
+		var archivesFromChessDotCom chessdotcom.Archives
+		archivesFromChessDotCom, err = downloader.getArchivesFromChessDotCom(chessDotComClient, logger, profile)
+		if err != nil {
+			return
+		}
+		archiveUrls = archivesFromChessDotCom.Archives
+	case users.Lichess:
+		var lichessProfile lichess.Profile
+		lichessProfile, err = downloader.getLichessProfile(lichessClient, logger, downloadRequest)
+		if err != nil {
+			return
+		}
+
+		profile = users.UserRecord{
+			Username: downloadRequest.Username,
+			UserId:   lichessProfile.UserId(),
+			Platform: users.Lichess,
+		}
+
+		logger = logger.With(zap.String("userId", profile.UserId))
+
+		err = downloader.persistUser(dynamodbClient, logger, profile)
+		if err != nil {
+			return
+		}
+
+		createdAt := time.UnixMilli(lichessProfile.CreatedAt)
+		archiveUrls = archives.LichessArchives(downloader.lichessConfig.Url, profile.Username, createdAt, time.Now())
+		logger.Info("archives resolved from lichess", zap.Int("existingArchivesCount", len(archiveUrls)))
+	default:
+		logger.Error("unsupported platform!")
+		err = UnsupportedPlatform(downloadRequest)
 		return
 	}
 
@@ -95,15 +155,29 @@ func (downloader *ArchiveDownloader) DownloadArchiveAndDistributeDonwloadGameCom
 		return
 	}
 
-	missingArchiveUrls := resolveMissingArchives(archivesFromChessDotCom, archivesFromDb)
+	archiveUrls = monthRange.FilterUrls(archiveUrls)
+	archivesFromDb = monthRange.FilterRecords(archivesFromDb)
+	logger.Info("archives scoped to the range of months", zap.String("from", downloadRequest.From), zap.String("to", downloadRequest.To), zap.Int("archivesInRange", len(archiveUrls)))
+
+	missingArchiveUrls := archives.ResolveMissing(archiveUrls, archivesFromDb)
 	missingArchives, err := downloader.persistMissingArchives(dynamodbClient, logger, profile, missingArchiveUrls)
 	if err != nil {
 		return
 	}
 
-	archivesToDownload := resolveArchivesToDownload(archivesFromDb)
+	archivesToDownload := archives.ResolveToDownload(archivesFromDb)
 	downloadId := uuid.New().String()
 	downloadRecord := downloads.NewDownloadRecord(downloadId, len(missingArchives)+len(archivesToDownload))
+	if downloadRequest.CallbackUrl != "" {
+		downloadRecord.CallbackUrl = downloadRequest.CallbackUrl
+		downloadRecord.CallbackSecret, err = callback.NewSecret()
+		if err != nil {
+			logger.Error("impossible to generate the callback secret!", zap.Error(err))
+			return
+		}
+		// with nothing to download the download is complete right away, no worker would notify about it
+		downloadRecord.Notified = downloadRecord.Total == 0
+	}
 
 	downloadRecorItems, err := dynamodbattribute.MarshalMap(downloadRecord)
 	if err != nil {
@@ -125,8 +199,19 @@ func (downloader *ArchiveDownloader) DownloadArchiveAndDistributeDonwloadGameCom
 		return
 	}
 
+	if downloadRecord.CallbackUrl != "" && downloadRecord.Total == 0 {
+		errOfCallback := callback.Enqueue(svc, downloader.deliverCallbacksQueueUrl, downloadRecord.CallbackUrl, downloadRecord.CallbackSecret, callback.DownloadCompleted{
+			Event:      callback.DownloadCompletedEvent,
+			DownloadId: downloadId,
+		})
+		if errOfCallback != nil {
+			logger.Error("impossible to enqueue the callback of the completed download!", zap.Error(errOfCallback))
+		}
+	}
+
 	downloadResponse := DownloadResponse{
-		DownloadId: downloadId,
+		DownloadId:     downloadId,
+		CallbackSecret: downloadRecord.CallbackSecret,
 	}
 
 	jsonBody, err := json.Marshal(downloadResponse)
@@ -148,63 +233,125 @@ func (downloader *ArchiveDownloader) DownloadArchiveAndDistributeDonwloadGameCom
 
 func (downloader ArchiveDownloader) getAndPersistUser(
 	dynamodbClient *dynamodb.DynamoDB,
-	chessDotComClient *http.Client,
+	chessDotComClient *chessdotcom.Client,
 	logger *zap.Logger,
 	downloadRequest DownloadRequest,
 ) (userRecord users.UserRecord, err error) {
-	url := downloader.chessDotComUrl + "/pub/player/" + downloadRequest.Username
-	logger = logger.With(zap.String("url", url))
+	logger.Info("requesting chess.com for profile")
+	profile, err := chessDotComClient.Profile(logger, downloadRequest.Username)
 
-	request, err := http.NewRequest("GET", url, bytes.NewBuffer([]byte{}))
+	if errors.Is(err, chessdotcom.ErrNotFound) || errors.Is(err, chessdotcom.ErrGone) {
+		logger.Error("profile not found on chess.com!", zap.Error(err))
+		err = ProfileNotFound(downloadRequest)
+		return
+	}
 
 	if err != nil {
-		logger.Error("impossible to create a request to chess.com!")
+		logger.Error("impossible to get the profile from chess.com!", zap.Error(err))
+		err = api.ServiceOverloaded
 		return
 	}
 
-	logger.Info("requesting chess.com for profile")
-	response, err := chessDotComClient.Do(request)
+	logger.Info("profile found!")
+
+	userId, err := downloader.resolveUserId(dynamodbClient, logger, profile)
 	if err != nil {
 		return
 	}
 
-	defer response.Body.Close()
+	userRecord = users.UserRecord{
+		Username: downloadRequest.Username,
+		UserId:   userId,
+		Platform: users.ChessDotCom,
+		PlayerId: profile.PlayerId,
+	}
 
-	responseBodyBytes, err := io.ReadAll(response.Body)
-	if err != nil {
-		logger.Error("impossible to read the response body from chess.com!", zap.Error(err))
+	err = downloader.persistUser(dynamodbClient, logger, userRecord)
+	return
+}
+
+// resolveUserId keeps the user id of a chess.com player stable after renames.
+// The @id of a profile contains the current username, so a player who has been cached under another username
+// is recognised by the player id and keeps the user id, and so the archives and games, of the first username.
+func (downloader ArchiveDownloader) resolveUserId(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	profile chessdotcom.Profile,
+) (userId string, err error) {
+	userId = profile.UserId
+	if profile.PlayerId == 0 {
 		return
 	}
 
-	responseBodyString := string(responseBodyBytes)
+	aliasesItems, err := dynamodbClient.Query(&dynamodb.QueryInput{
+		TableName:              aws.String(downloader.usersTableName),
+		IndexName:              aws.String(downloader.usersByPlayerIdIndexName),
+		KeyConditionExpression: aws.String("player_id = :player_id"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":player_id": {
+				N: aws.String(strconv.FormatInt(profile.PlayerId, 10)),
+			},
+		},
+		Limit: aws.Int64(1),
+	})
 
-	if response.StatusCode == 404 {
-		logger.Error("profile not found on chess.com!", zap.String("responseBody", responseBodyString))
-		err = ProfileNotFound(downloadRequest)
+	if err != nil {
+		logger.Error("impossible to get the aliases of the player", zap.Error(err))
 		return
 	}
 
-	if response.StatusCode != 200 {
-		logger.Error("unexpected status code from chess.com!", zap.Int("statusCode", response.StatusCode), zap.String("responseBody", responseBodyString))
-		err = api.ServiceOverloaded
+	if len(aliasesItems.Items) == 0 {
 		return
 	}
 
-	profile := ChessDotComProfile{}
-	err = json.Unmarshal([]byte(responseBodyString), &profile)
+	alias := users.UserRecord{}
+	err = dynamodbattribute.UnmarshalMap(aliasesItems.Items[0], &alias)
 	if err != nil {
-		logger.Error("impossible to unmarshal the response body from chess.com!", zap.Error(err), zap.String("responseBody", responseBodyString), zap.String("url", url))
+		logger.Error("impossible to unmarshal the alias of the player", zap.Error(err))
 		return
 	}
 
-	logger.Info("profile found!")
+	if alias.Platform == users.ChessDotCom && alias.UserId != "" {
+		userId = alias.UserId
+		if userId != profile.UserId {
+			logger.Info("player is known under another username", zap.String("alias", alias.Username), zap.String("userId", userId))
+		}
+	}
+	return
+}
 
-	userRecord = users.UserRecord{
-		Username: downloadRequest.Username,
-		UserId:   profile.UserId,
-		Platform: users.ChessDotCom,
+func (downloader ArchiveDownloader) getLichessProfile(
+	lichessClient *lichess.Client,
+	logger *zap.Logger,
+	downloadRequest DownloadRequest,
+) (profile lichess.Profile, err error) {
+	logger.Info("requesting lichess for profile")
+	profile, err = lichessClient.Profile(logger, downloadRequest.Username)
+	if errors.Is(err, lichess.ErrNotFound) {
+		logger.Error("profile not found on lichess!")
+		err = ProfileNotFound(downloadRequest)
+		return
+	}
+	if errors.Is(err, lichess.ErrClosed) {
+		logger.Info("profile is closed on lichess")
+		err = ProfileClosed(downloadRequest)
+		return
 	}
+	if err != nil {
+		logger.Error("impossible to get the profile from lichess!", zap.Error(err))
+		err = api.ServiceOverloaded
+		return
+	}
+
+	logger.Info("profile found!")
+	return
+}
 
+func (downloader ArchiveDownloader) persistUser(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	userRecord users.UserRecord,
+) (err error) {
 	userRecordItems, err := dynamodbattribute.MarshalMap(userRecord)
 
 	if err != nil {
@@ -227,47 +374,18 @@ func (downloader ArchiveDownloader) getAndPersistUser(
 }
 
 func (downloader ArchiveDownloader) getArchivesFromChessDotCom(
+	chessDotComClient *chessdotcom.Client,
 	logger *zap.Logger,
 	user users.UserRecord,
-) (archives ChessDotComArchives, err error) {
-	url := downloader.chessDotComUrl + "/pub/player/" + user.Username + "/games/archives"
-	logger = logger.With(zap.String("url", url))
+) (archives chessdotcom.Archives, err error) {
 	logger.Info("requesting chess.com for archives")
-	request, err := http.NewRequest("GET", url, bytes.NewBuffer([]byte{}))
-	if err != nil {
-		logger.Error("impossible to create a request to chess.com!")
-		return
-	}
-
-	response, err := http.DefaultClient.Do(request)
-	if err != nil {
-		logger.Error("impossible to request chess.com!")
-		return
-	}
-
-	defer response.Body.Close()
-
-	responseBodyBytes, err := io.ReadAll(response.Body)
+	archives, err = chessDotComClient.Archives(logger, user.Username)
 	if err != nil {
-		logger.Error("impossible to read the response body from chess.com!", zap.Error(err))
-		return
-	}
-
-	responseBodyString := string(responseBodyBytes)
-
-	if response.StatusCode != 200 {
-		logger.Error("unexpected status code from chess.com", zap.Int("statusCode", response.StatusCode), zap.String("responseBody", responseBodyString))
+		logger.Error("impossible to get the archives from chess.com!", zap.Error(err))
 		err = api.ServiceOverloaded
 		return
 	}
 
-	archives = ChessDotComArchives{}
-	err = json.Unmarshal([]byte(responseBodyString), &archives)
-	if err != nil {
-		logger.Error("impossible to unmarshal the response body from chess.com!", zap.Error(err), zap.String("responseBody", responseBodyString))
-		return
-	}
-
 	logger.Info("archives found from chess.com", zap.Int("existingArchivesCount", len(archives.Archives)))
 
 	return
@@ -312,41 +430,6 @@ func (downloader ArchiveDownloader) getArchivesFromDb(
 	return
 }
 
-func resolveMissingArchives(
-	archivesFromChessDotCom ChessDotComArchives,
-	archivesFromDb []archives.ArchiveRecord,
-) (missingArchives []string) {
-	existingArchives := make(map[string]archives.ArchiveRecord, len(archivesFromDb))
-	for _, archiveFromDb := range archivesFromDb {
-		existingArchives[archiveFromDb.ArchiveId] = archiveFromDb
-	}
-
-	missingArchives = make([]string, 0)
-	for _, archiveFromChessDotCom := range archivesFromChessDotCom.Archives {
-		if _, ok := existingArchives[archiveFromChessDotCom]; !ok {
-			missingArchives = append(missingArchives, archiveFromChessDotCom)
-		}
-	}
-	return
-}
-
-func resolveArchivesToDownload(
-	archivesFromDb []archives.ArchiveRecord,
-) (archivesToDownload []archives.ArchiveRecord) {
-	archivesToDownload = make([]archives.ArchiveRecord, 0)
-	for _, archiveFromDb := range archivesFromDb {
-		if archiveFromDb.DownloadedAt == nil {
-			archivesToDownload = append(archivesToDownload, archiveFromDb)
-			continue
-		}
-		archiveHasGamesTill := time.Date(archiveFromDb.Year, time.Month(archiveFromDb.Month), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
-		if archiveFromDb.DownloadedAt.ToTime().Before(archiveHasGamesTill) {
-			archivesToDownload = append(archivesToDownload, archiveFromDb)
-		}
-	}
-	return
-}
-
 func (downloader ArchiveDownloader) persistMissingArchives(
 	dynamodbClient *dynamodb.DynamoDB,
 	logger *zap.Logger,
@@ -357,21 +440,11 @@ func (downloader ArchiveDownloader) persistMissingArchives(
 
 	for _, missingArchiveUrl := range missingArchiveUrls {
 		logger := logger.With(zap.String("archiveId", missingArchiveUrl))
-		archiveSegments := strings.Split(missingArchiveUrl, "/")
-		maybeYear := archiveSegments[len(archiveSegments)-2]
-		maybeMonth := archiveSegments[len(archiveSegments)-1]
 
-		var year int
-		year, err = strconv.Atoi(maybeYear)
+		var year, month int
+		year, month, err = archives.Month(missingArchiveUrl)
 		if err != nil {
-			logger.Error("impossible to parse the year!", zap.Error(err))
-			return
-		}
-
-		var month int
-		month, err = strconv.Atoi(maybeMonth)
-		if err != nil {
-			logger.Error("impossible to parse the month!", zap.Error(err))
+			logger.Error("impossible to parse the year and the month!", zap.Error(err))
 			return
 		}
 
@@ -452,29 +525,15 @@ func (downloader ArchiveDownloader) publishDownloadGameCommands(
 	logger.Info("publishing download game commands ...")
 
 	for _, archive := range allArchives {
-		logger := logger.With(zap.String("archiveId", archive.ArchiveId))
-		command := queue.DownloadGamesCommand{
+		err = queue.SendDownloadGamesCommand(svc, downloader.downloadGamesQueueUrl, queue.DownloadGamesCommand{
 			Username:   user.Username,
-			Platform:   "CHESS_DOT_COM",
+			Platform:   queue.Platform(user.Platform),
 			ArchiveId:  archive.ArchiveId,
 			UserId:     archive.UserId,
 			DownloadId: downloadRecords.DownloadId,
-		}
-		var jsonBody []byte
-		jsonBody, err = json.Marshal(command)
-		if err != nil {
-			logger.Error("impossible to marshal the download game command!", zap.Error(err))
-			return err
-		}
-
-		_, err = svc.SendMessage(&sqs.SendMessageInput{
-			QueueUrl:               aws.String(downloader.downloadGamesQueueUrl),
-			MessageBody:            aws.String(string(jsonBody)),
-			MessageDeduplicationId: aws.String(archive.ArchiveId),
-			MessageGroupId:         aws.String(archive.UserId),
 		})
 		if err != nil {
-			logger.Error("impossible to publish the download game command!", zap.Error(err))
+			logger.Error("impossible to publish the download game command!", zap.String("archiveId", archive.ArchiveId), zap.Error(err))
 			return
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,10 +14,13 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/archives"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/downloads"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/users"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/lichess"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -30,12 +34,15 @@ var awsConfig = aws.Config{
 }
 
 var downloader = ArchiveDownloader{
-	downloadGamesQueueUrl: "http://localhost:4566/000000000000/chessfinder_sqs-DownloadGames.fifo",
-	chessDotComUrl:        "http://0.0.0.0:18443",
-	usersTableName:        "chessfinder_dynamodb-users",
-	archivesTableName:     "chessfinder_dynamodb-archives",
-	downloadsTableName:    "chessfinder_dynamodb-downloads",
-	awsConfig:             &awsConfig,
+	downloadGamesQueueUrl:    "http://localhost:4566/000000000000/chessfinder_sqs-DownloadGames.fifo",
+	deliverCallbacksQueueUrl: "http://localhost:4566/000000000000/chessfinder_sqs-DeliverCallbacks",
+	chessDotComConfig:        chessdotcom.DefaultConfig("http://0.0.0.0:18443"),
+	lichessConfig:            lichess.DefaultConfig("http://0.0.0.0:18443"),
+	usersTableName:           "chessfinder_dynamodb-users",
+	usersByPlayerIdIndexName: "chessfinder_dynamodb-usersByPlayerId",
+	archivesTableName:        "chessfinder_dynamodb-archives",
+	downloadsTableName:       "chessfinder_dynamodb-downloads",
+	awsConfig:                &awsConfig,
 }
 
 var awsSession = session.Must(session.NewSession(&awsConfig))
@@ -51,10 +58,11 @@ func Test_ArchiveDownloader_should_emit_DownloadGameCommands_for_all_missing_arc
 
 	username := uuid.New().String()
 	userId := fmt.Sprintf("https://api.chess.com/pub/player/%v", username)
+	playerId := int64(uuid.New().ID())
 
 	usersProfileReponseBody := fmt.Sprintf(
 		`{
-      "player_id": 191338281,
+      "player_id": %v,
       "@id": "%v",
       "url": "https://www.chess.com/member/%v",
       "username": "%v",
@@ -67,6 +75,7 @@ func Test_ArchiveDownloader_should_emit_DownloadGameCommands_for_all_missing_arc
       "verified": false,
       "league": "Champion"
     }`,
+		playerId,
 		userId,
 		username,
 		username,
@@ -145,13 +154,14 @@ func Test_ArchiveDownloader_should_emit_DownloadGameCommands_for_all_missing_arc
 	assert.NoError(t, err)
 	assert.Equal(t, true, verifyGetArchivesStub, "Stub of getting archives was not called!")
 
-	actualUserRecord, err := downloader.getUserRecord(username)
+	actualUserRecord, err := downloader.getUserRecord(username, users.ChessDotCom)
 	assert.NoError(t, err)
 
 	expectedUserRecord := users.UserRecord{
 		UserId:   userId,
 		Platform: "CHESS_DOT_COM",
 		Username: username,
+		PlayerId: playerId,
 	}
 
 	assert.Equal(t, expectedUserRecord, actualUserRecord)
@@ -247,6 +257,7 @@ func Test_ArchiveDownloader_should_emit_DownloadGameCommands_for_partially_downl
 
 	username := uuid.New().String()
 	userId := fmt.Sprintf("https://api.chess.com/pub/player/%v", username)
+	playerId := int64(uuid.New().ID())
 
 	archiveId_2021_10 := fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2021/10", username)
 
@@ -296,7 +307,7 @@ func Test_ArchiveDownloader_should_emit_DownloadGameCommands_for_partially_downl
 
 	usersProfileReponseBody := fmt.Sprintf(
 		`{
-      "player_id": 191338281,
+      "player_id": %v,
       "@id": "%v",
       "url": "https://www.chess.com/member/%v",
       "username": "%v",
@@ -309,6 +320,7 @@ func Test_ArchiveDownloader_should_emit_DownloadGameCommands_for_partially_downl
       "verified": false,
       "league": "Champion"
     }`,
+		playerId,
 		userId,
 		username,
 		username,
@@ -387,13 +399,14 @@ func Test_ArchiveDownloader_should_emit_DownloadGameCommands_for_partially_downl
 
 	assert.Equal(t, true, verifyGetArchivesStub, "Stub of getting archives was not called!")
 
-	actualUserRecord, err := downloader.getUserRecord(username)
+	actualUserRecord, err := downloader.getUserRecord(username, users.ChessDotCom)
 	assert.NoError(t, err)
 
 	expectedUser := users.UserRecord{
 		UserId:   userId,
 		Platform: "CHESS_DOT_COM",
 		Username: username,
+		PlayerId: playerId,
 	}
 
 	assert.Equal(t, expectedUser, actualUserRecord)
@@ -426,7 +439,398 @@ func Test_ArchiveDownloader_should_emit_DownloadGameCommands_for_partially_downl
 
 }
 
-func (archviveDownloader ArchiveDownloader) getUserRecord(userId string) (user users.UserRecord, err error) {
+func Test_ArchiveDownloader_should_emit_DownloadGameCommands_for_monthly_archives_of_lichess_user(t *testing.T) {
+	var err error
+	defer wiremockClient.Reset()
+
+	username := uuid.New().String()
+	userId := fmt.Sprintf("https://lichess.org/@/%v", username)
+
+	now := time.Now().UTC()
+	currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	createdAt := currentMonth.AddDate(0, -2, 3)
+
+	usersProfileReponseBody := fmt.Sprintf(
+		`{
+			"id": "%v",
+			"username": "%v",
+			"createdAt": %v,
+			"seenAt": %v,
+			"url": "https://lichess.org/@/%v"
+		}`,
+		username,
+		username,
+		createdAt.UnixMilli(),
+		now.UnixMilli(),
+		username,
+	)
+
+	getUsersProfileStub := wiremock.Get(wiremock.URLPathEqualTo(fmt.Sprintf("/api/user/%v", username))).
+		WillReturnResponse(
+			wiremock.NewResponse().
+				WithBody(usersProfileReponseBody).
+				WithHeader("Content-Type", "application/json").
+				WithStatus(http.StatusOK),
+		)
+	err = wiremockClient.StubFor(getUsersProfileStub)
+	assert.NoError(t, err)
+
+	event := events.APIGatewayV2HTTPRequest{
+		Body: fmt.Sprintf(`{"username":"%v", "platform": "LICHESS"}`, username),
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "POST",
+				Path:   "/api/faster/game",
+			},
+		},
+	}
+
+	actualResponse, err := downloader.DownloadArchiveAndDistributeDonwloadGameCommands(&event)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, actualResponse.StatusCode, "Response status code is not 200!")
+
+	actualDownloadResponse := DownloadResponse{}
+	err = json.Unmarshal([]byte(actualResponse.Body), &actualDownloadResponse)
+	assert.NoError(t, err)
+
+	downloadId := actualDownloadResponse.DownloadId
+
+	actualDownloadRecord, err := downloader.getDownloadRecord(downloadId)
+	assert.NoError(t, err)
+
+	expectedDownloadRecord := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Failed:     0,
+		Succeed:    0,
+		Done:       0,
+		Pending:    3,
+		Total:      3,
+	}
+
+	assert.Equal(t, expectedDownloadRecord, actualDownloadRecord)
+
+	verifyGetUsersProfileStub, err := wiremockClient.Verify(getUsersProfileStub.Request(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, true, verifyGetUsersProfileStub, fmt.Sprintf("Stub of getting user profile %v was not called!", username))
+
+	actualUserRecord, err := downloader.getUserRecord(username, users.Lichess)
+	assert.NoError(t, err)
+
+	expectedUser := users.UserRecord{
+		UserId:   userId,
+		Platform: users.Lichess,
+		Username: username,
+	}
+
+	assert.Equal(t, expectedUser, actualUserRecord)
+
+	expectedCommands := []queue.DownloadGamesCommand{}
+	for month := currentMonth.AddDate(0, -2, 0); !month.After(currentMonth); month = month.AddDate(0, 1, 0) {
+		archiveId := fmt.Sprintf("http://0.0.0.0:18443/api/games/user/%v/%d/%02d", username, month.Year(), int(month.Month()))
+
+		actualArchive, err := downloader.getArchiveRecord(userId, archiveId)
+		assert.NoError(t, err)
+		assert.Equal(t, month.Year(), actualArchive.Year)
+		assert.Equal(t, int(month.Month()), actualArchive.Month)
+
+		expectedCommands = append(expectedCommands, queue.DownloadGamesCommand{
+			Username:   username,
+			Platform:   queue.Lichess,
+			UserId:     userId,
+			ArchiveId:  archiveId,
+			DownloadId: downloadId,
+		})
+	}
+
+	actualCommands, err := downloader.getCommands()
+	assert.NoError(t, err)
+
+	assert.ElementsMatch(t, expectedCommands, actualCommands, "Commands are not equal!")
+}
+
+func Test_ArchiveDownloader_should_emit_DownloadGameCommands_only_for_archives_in_requested_range(t *testing.T) {
+	var err error
+	defer wiremockClient.Reset()
+
+	username := uuid.New().String()
+	userId := fmt.Sprintf("https://api.chess.com/pub/player/%v", username)
+	playerId := int64(uuid.New().ID())
+
+	usersProfileReponseBody := fmt.Sprintf(
+		`{
+      "player_id": %v,
+      "@id": "%v",
+      "url": "https://www.chess.com/member/%v",
+      "username": "%v",
+      "followers": 10,
+      "country": "https://api.chess.com/pub/country/AM",
+      "last_online": 1678264516,
+      "joined": 1658920370,
+      "status": "premium",
+      "is_streamer": false,
+      "verified": false,
+      "league": "Champion"
+    }`,
+		playerId,
+		userId,
+		username,
+		username,
+	)
+
+	getUsersProfileStub := wiremock.Get(wiremock.URLPathEqualTo(fmt.Sprintf("/pub/player/%v", username))).
+		WillReturnResponse(
+			wiremock.NewResponse().
+				WithBody(usersProfileReponseBody).
+				WithHeader("Content-Type", "application/json").
+				WithStatus(http.StatusOK),
+		)
+	err = wiremockClient.StubFor(getUsersProfileStub)
+	assert.NoError(t, err)
+
+	archivesResponseBody := fmt.Sprintf(
+		`{
+			"archives": [
+				"https://api.chess.com/pub/player/%v/games/2021/10",
+				"https://api.chess.com/pub/player/%v/games/2021/11",
+				"https://api.chess.com/pub/player/%v/games/2021/12"
+			]
+		}`, username, username, username,
+	)
+
+	getArchivesStub := wiremock.Get(wiremock.URLPathEqualTo(fmt.Sprintf("/pub/player/%v/games/archives", username))).
+		WillReturnResponse(
+			wiremock.NewResponse().
+				WithBody(archivesResponseBody).
+				WithHeader("Content-Type", "application/json").
+				WithStatus(http.StatusOK),
+		)
+	err = wiremockClient.StubFor(getArchivesStub)
+	assert.NoError(t, err)
+
+	event := events.APIGatewayV2HTTPRequest{
+		Body: fmt.Sprintf(`{"username":"%v", "platform": "CHESS_DOT_COM", "from": "2021-11", "to": "2021-11"}`, username),
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "POST",
+				Path:   "/api/faster/game",
+			},
+		},
+	}
+
+	actualResponse, err := downloader.DownloadArchiveAndDistributeDonwloadGameCommands(&event)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, actualResponse.StatusCode, "Response status code is not 200!")
+
+	actualDownloadResponse := DownloadResponse{}
+	err = json.Unmarshal([]byte(actualResponse.Body), &actualDownloadResponse)
+	assert.NoError(t, err)
+
+	downloadId := actualDownloadResponse.DownloadId
+
+	downloadRequestStatus, err := downloader.getDownloadRecord(downloadId)
+	assert.NoError(t, err)
+
+	expectedDownloadRecord := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Failed:     0,
+		Succeed:    0,
+		Done:       0,
+		Pending:    1,
+		Total:      1,
+	}
+
+	assert.Equal(t, expectedDownloadRecord, downloadRequestStatus, "Download request status is not equal!")
+
+	archiveId_2021_10 := fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2021/10", username)
+	archive_2021_10, err := downloader.getArchiveRecord(userId, archiveId_2021_10)
+	assert.NoError(t, err)
+	assert.Equal(t, archives.ArchiveRecord{}, archive_2021_10, fmt.Sprintf("Archive %v is out of the range and should not be persisted!", archiveId_2021_10))
+
+	archiveId_2021_11 := fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2021/11", username)
+
+	actualCommands, err := downloader.getCommands()
+	assert.NoError(t, err)
+
+	expectedCommands := []queue.DownloadGamesCommand{
+		{
+			Username:   username,
+			Platform:   queue.Platform("CHESS_DOT_COM"),
+			UserId:     userId,
+			ArchiveId:  archiveId_2021_11,
+			DownloadId: downloadId,
+		},
+	}
+
+	assert.Equal(t, expectedCommands, actualCommands, "Commands are not equal!")
+}
+
+func Test_ArchiveDownloader_should_reject_an_inverted_range_of_months(t *testing.T) {
+	event := events.APIGatewayV2HTTPRequest{
+		Body: `{"username":"tigran-c-137", "platform": "CHESS_DOT_COM", "from": "2022-03", "to": "2021-11"}`,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "POST",
+				Path:   "/api/faster/game",
+			},
+		},
+	}
+
+	_, err := downloader.DownloadArchiveAndDistributeDonwloadGameCommands(&event)
+	assert.Equal(t, api.ValidationError{Msg: "Invalid range of months: from 2022-03 is after to 2021-11"}, err)
+}
+
+func Test_ArchiveDownloader_should_reject_a_callback_url_that_is_not_http(t *testing.T) {
+	event := events.APIGatewayV2HTTPRequest{
+		Body: `{"username":"tigran-c-137", "platform": "CHESS_DOT_COM", "callbackUrl": "ftp://example.com/done"}`,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "POST",
+				Path:   "/api/faster/game",
+			},
+		},
+	}
+
+	_, err := downloader.DownloadArchiveAndDistributeDonwloadGameCommands(&event)
+	assert.Equal(t, api.ValidationError{Msg: "Callback url ftp://example.com/done has to be an absolute https url of a public host!"}, err)
+}
+
+func Test_ArchiveDownloader_should_reject_unsupported_platform(t *testing.T) {
+	event := events.APIGatewayV2HTTPRequest{
+		Body: `{"username":"tigran-c-137", "platform": "FICS"}`,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "POST",
+				Path:   "/api/faster/game",
+			},
+		},
+	}
+
+	_, err := downloader.DownloadArchiveAndDistributeDonwloadGameCommands(&event)
+	assert.Equal(t, api.ValidationError{Msg: "Platform FICS is not supported!"}, err)
+}
+
+func Test_ArchiveDownloader_should_keep_the_user_id_and_archives_of_a_renamed_player(t *testing.T) {
+	var err error
+	defer wiremockClient.Reset()
+
+	previousUsername := uuid.New().String()
+	previousUserId := fmt.Sprintf("https://api.chess.com/pub/player/%v", previousUsername)
+	username := uuid.New().String()
+	userId := fmt.Sprintf("https://api.chess.com/pub/player/%v", username)
+	playerId := int64(uuid.New().ID())
+
+	err = downloader.persistUserRecord(users.UserRecord{
+		Username: previousUsername,
+		Platform: users.ChessDotCom,
+		UserId:   previousUserId,
+		PlayerId: playerId,
+	})
+	assert.NoError(t, err)
+
+	downloadedAt := db.Zuludatetime(time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC))
+	previousArchiveId_2021_10 := fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2021/10", previousUsername)
+	previousArchive_2021_10 := archives.ArchiveRecord{
+		UserId:       previousUserId,
+		ArchiveId:    previousArchiveId_2021_10,
+		Resource:     previousArchiveId_2021_10,
+		Year:         2021,
+		Month:        10,
+		DownloadedAt: &downloadedAt,
+		Downloaded:   7,
+	}
+	err = downloader.persistArchiveRecord(previousArchive_2021_10)
+	assert.NoError(t, err)
+
+	usersProfileReponseBody := fmt.Sprintf(
+		`{
+      "player_id": %v,
+      "@id": "%v",
+      "url": "https://www.chess.com/member/%v",
+      "username": "%v"
+    }`,
+		playerId,
+		userId,
+		username,
+		username,
+	)
+
+	getUsersProfileStub := wiremock.Get(wiremock.URLPathEqualTo(fmt.Sprintf("/pub/player/%v", username))).
+		WillReturnResponse(
+			wiremock.NewResponse().
+				WithBody(usersProfileReponseBody).
+				WithHeader("Content-Type", "application/json").
+				WithStatus(http.StatusOK),
+		)
+	err = wiremockClient.StubFor(getUsersProfileStub)
+	assert.NoError(t, err)
+
+	archivesResponseBody := fmt.Sprintf(
+		`{
+			"archives": [
+				"https://api.chess.com/pub/player/%v/games/2021/10",
+				"https://api.chess.com/pub/player/%v/games/2021/11"
+			]
+		}`, username, username,
+	)
+
+	getArchivesStub := wiremock.Get(wiremock.URLPathEqualTo(fmt.Sprintf("/pub/player/%v/games/archives", username))).
+		WillReturnResponse(
+			wiremock.NewResponse().
+				WithBody(archivesResponseBody).
+				WithHeader("Content-Type", "application/json").
+				WithStatus(http.StatusOK),
+		)
+	err = wiremockClient.StubFor(getArchivesStub)
+	assert.NoError(t, err)
+
+	event := events.APIGatewayV2HTTPRequest{
+		Body: fmt.Sprintf(`{"username":"%v", "platform": "CHESS_DOT_COM"}`, strings.ToUpper(username)),
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "POST",
+				Path:   "/api/faster/game",
+			},
+		},
+	}
+
+	actualResponse, err := downloader.DownloadArchiveAndDistributeDonwloadGameCommands(&event)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, actualResponse.StatusCode, "Response status code is not 200!")
+
+	actualDownloadResponse := DownloadResponse{}
+	err = json.Unmarshal([]byte(actualResponse.Body), &actualDownloadResponse)
+	assert.NoError(t, err)
+
+	actualUserRecord, err := downloader.getUserRecord(username, users.ChessDotCom)
+	assert.NoError(t, err)
+
+	expectedUserRecord := users.UserRecord{
+		UserId:   previousUserId,
+		Platform: users.ChessDotCom,
+		Username: username,
+		PlayerId: playerId,
+	}
+
+	assert.Equal(t, expectedUserRecord, actualUserRecord)
+
+	archiveId_2021_11 := fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2021/11", username)
+	archive_2021_11, err := downloader.getArchiveRecord(previousUserId, archiveId_2021_11)
+	assert.NoError(t, err)
+	assert.Equal(t, 2021, archive_2021_11.Year)
+	assert.Equal(t, 11, archive_2021_11.Month)
+
+	archiveId_2021_10 := fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2021/10", username)
+	archive_2021_10, err := downloader.getArchiveRecord(previousUserId, archiveId_2021_10)
+	assert.NoError(t, err)
+	assert.Equal(t, archives.ArchiveRecord{}, archive_2021_10, "Archive of the previous username has to be reused!")
+
+	downloadRecord, err := downloader.getDownloadRecord(actualDownloadResponse.DownloadId)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, downloadRecord.Total)
+}
+
+func (archviveDownloader ArchiveDownloader) getUserRecord(userId string, platform users.Platform) (user users.UserRecord, err error) {
 
 	userRecordQuery := &dynamodb.GetItemInput{
 		TableName: aws.String(archviveDownloader.usersTableName),
@@ -435,7 +839,7 @@ func (archviveDownloader ArchiveDownloader) getUserRecord(userId string) (user u
 				S: aws.String(userId),
 			},
 			"platform": {
-				S: aws.String("CHESS_DOT_COM"),
+				S: aws.String(string(platform)),
 			},
 		},
 	}
@@ -504,6 +908,19 @@ func (downloader ArchiveDownloader) getDownloadRecord(downloadId string) (downlo
 	return
 }
 
+func (downloader ArchiveDownloader) persistUserRecord(user users.UserRecord) (err error) {
+	userItem, err := dynamodbattribute.MarshalMap(user)
+	if err != nil {
+		return
+	}
+
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(downloader.usersTableName),
+		Item:      userItem,
+	})
+	return
+}
+
 func (downloader ArchiveDownloader) persistArchiveRecord(archive archives.ArchiveRecord) (err error) {
 
 	archiveItem, err := dynamodbattribute.MarshalMap(archive)
@@ -9,10 +9,17 @@ import (
 type DownloadRequest struct {
 	Username string `json:"username"`
 	Platform string `json:"platform"`
+	// From and To are optional year-months like 2023-01, only the archives between them are downloaded
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// CallbackUrl is optional, the final status of the download is posted there once all archives are done
+	CallbackUrl string `json:"callbackUrl,omitempty"`
 }
 
 type DownloadResponse struct {
 	DownloadId string `json:"downloadId"`
+	// CallbackSecret verifies the signatures of the callbacks, it is given only together with a callback url
+	CallbackSecret string `json:"callbackSecret,omitempty"`
 }
 
 func NoGameAvailable(user DownloadRequest) api.BusinessError {
@@ -28,3 +35,28 @@ func ProfileNotFound(user DownloadRequest) api.BusinessError {
 		Code: "PROFILE_NOT_FOUND",
 	}
 }
+
+func ProfileClosed(user DownloadRequest) api.BusinessError {
+	return api.BusinessError{
+		Msg:  fmt.Sprintf("Profile %v is closed and its games are not available anymore!", user.Username),
+		Code: "PROFILE_CLOSED",
+	}
+}
+
+func UnsupportedPlatform(user DownloadRequest) api.ValidationError {
+	return api.ValidationError{
+		Msg: fmt.Sprintf("Platform %v is not supported!", user.Platform),
+	}
+}
+
+func InvalidMonthRange(err error) api.ValidationError {
+	return api.ValidationError{
+		Msg: fmt.Sprintf("Invalid range of months: %v", err),
+	}
+}
+
+func InvalidCallbackUrl(downloadRequest DownloadRequest) api.ValidationError {
+	return api.ValidationError{
+		Msg: fmt.Sprintf("Callback url %v has to be an absolute https url of a public host!", downloadRequest.CallbackUrl),
+	}
+}
@@ -7,7 +7,10 @@ require (
 	github.com/aws/aws-sdk-go v1.45.24
 	github.com/chessfinder/chessfinder-faster-backend/src_go/details/api v0.0.0-20230921201148-2f6c15cfb0c9
 	github.com/chessfinder/chessfinder-faster-backend/src_go/details/batcher v0.0.0-00010101000000-000000000000
+	github.com/chessfinder/chessfinder-faster-backend/src_go/details/callback v0.0.0-00010101000000-000000000000
+	github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom v0.0.0-00010101000000-000000000000
 	github.com/chessfinder/chessfinder-faster-backend/src_go/details/db v0.0.0-00010101000000-000000000000
+	github.com/chessfinder/chessfinder-faster-backend/src_go/details/lichess v0.0.0-00010101000000-000000000000
 	github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue v0.0.0-00010101000000-000000000000
 	github.com/stretchr/testify v1.8.4
 	github.com/wiremock/go-wiremock v1.8.0
@@ -15,13 +18,14 @@ require (
 )
 
 require (
+	github.com/chessfinder/chessfinder-faster-backend/src_go/details/fetch v0.0.0-00010101000000-000000000000 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 require (
-	github.com/google/uuid v1.2.0
+	github.com/google/uuid v1.3.1
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	go.uber.org/multierr v1.10.0 // indirect
 )
@@ -33,3 +37,11 @@ replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue =
 replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/batcher => ../../details/batcher
 
 replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/db => ../../details/db
+
+replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom => ../../details/chessdotcom
+
+replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/fetch => ../../details/fetch
+
+replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/callback => ../../details/callback
+
+replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/lichess => ../../details/lichess
@@ -2,11 +2,15 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/lichess"
 )
 
 func main() {
@@ -26,27 +30,65 @@ func main() {
 		panic(errors.New("USERS_TABLE_NAME is missing"))
 	}
 
+	usersByPlayerIdIndexName, usersByPlayerIdIndexNameExists := os.LookupEnv("USERS_BY_PLAYER_ID_INDEX_NAME")
+	if !usersByPlayerIdIndexNameExists {
+		panic(errors.New("USERS_BY_PLAYER_ID_INDEX_NAME is missing"))
+	}
+
 	chessDotComUrl, chessDotComUrlExists := os.LookupEnv("CHESS_DOT_COM_URL")
 	if !chessDotComUrlExists {
 		panic(errors.New("CHESS_DOT_COM_URL is missing"))
 	}
 
+	chessDotComConfig := chessdotcom.DefaultConfig(chessDotComUrl)
+	chessDotComTimeout, chessDotComTimeoutExists := os.LookupEnv("CHESS_DOT_COM_TIMEOUT")
+	if chessDotComTimeoutExists {
+		timeout, err := time.ParseDuration(chessDotComTimeout)
+		if err != nil {
+			panic(fmt.Errorf("CHESS_DOT_COM_TIMEOUT is invalid: %w", err))
+		}
+		chessDotComConfig.Timeout = timeout
+	}
+
+	lichessUrl, lichessUrlExists := os.LookupEnv("LICHESS_URL")
+	if !lichessUrlExists {
+		panic(errors.New("LICHESS_URL is missing"))
+	}
+
+	lichessConfig := lichess.DefaultConfig(lichessUrl)
+	lichessTimeout, lichessTimeoutExists := os.LookupEnv("LICHESS_TIMEOUT")
+	if lichessTimeoutExists {
+		timeout, err := time.ParseDuration(lichessTimeout)
+		if err != nil {
+			panic(fmt.Errorf("LICHESS_TIMEOUT is invalid: %w", err))
+		}
+		lichessConfig.Timeout = timeout
+	}
+
 	downloadGamesQueueUrl, downloadGamesQueueUrlExists := os.LookupEnv("DOWNLOAD_GAMES_QUEUE_URL")
 	if !downloadGamesQueueUrlExists {
 		panic(errors.New("DOWNLOAD_GAMES_QUEUE_URL is missing"))
 	}
 
+	deliverCallbacksQueueUrl, deliverCallbacksQueueUrlExists := os.LookupEnv("DELIVER_CALLBACKS_QUEUE_URL")
+	if !deliverCallbacksQueueUrlExists {
+		panic(errors.New("DELIVER_CALLBACKS_QUEUE_URL is missing"))
+	}
+
 	awsRegion, awsRegionExists := os.LookupEnv("AWS_REGION")
 	if !awsRegionExists {
 		panic(errors.New("AWS_REGION is missing"))
 	}
 
 	checker := ArchiveDownloader{
-		downloadsTableName:    downloadsTableName,
-		usersTableName:        usersTableName,
-		archivesTableName:     archivesTableName,
-		downloadGamesQueueUrl: downloadGamesQueueUrl,
-		chessDotComUrl:        chessDotComUrl,
+		downloadsTableName:       downloadsTableName,
+		usersTableName:           usersTableName,
+		usersByPlayerIdIndexName: usersByPlayerIdIndexName,
+		archivesTableName:        archivesTableName,
+		downloadGamesQueueUrl:    downloadGamesQueueUrl,
+		deliverCallbacksQueueUrl: deliverCallbacksQueueUrl,
+		chessDotComConfig:        chessDotComConfig,
+		lichessConfig:            lichessConfig,
 		awsConfig: &aws.Config{
 			Region: &awsRegion,
 		},
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const maxGamesPerScan = 100
+
+// PgnMigrator compresses the pgn of the games that were stored as a plain string before the compression,
+// the games are read transparently in both forms, so the migration can run while the service is up
+type PgnMigrator struct {
+	gamesTableName string
+	awsConfig      *aws.Config
+}
+
+func (migrator *PgnMigrator) Migrate() (migrated int, err error) {
+	config := zap.NewProductionConfig()
+	config.OutputPaths = []string{"stdout"}
+	timeEncoder := func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.UTC().Format("2006-01-02T15:04:05.000Z"))
+	}
+	config.EncoderConfig.EncodeTime = timeEncoder
+
+	logger, err := config.Build()
+	if err != nil {
+		return
+	}
+	defer logger.Sync()
+
+	awsSession, err := session.NewSession(migrator.awsConfig)
+	if err != nil {
+		logger.Error("impossible to create an AWS session!", zap.Error(err))
+		return
+	}
+	dynamodbClient := dynamodb.New(awsSession)
+
+	skipped := 0
+	var lastKey map[string]*dynamodb.AttributeValue
+	for {
+		var scanOutput *dynamodb.ScanOutput
+		scanOutput, err = dynamodbClient.Scan(&dynamodb.ScanInput{
+			TableName:            aws.String(migrator.gamesTableName),
+			ProjectionExpression: aws.String("user_id, game_id, pgn"),
+			FilterExpression:     aws.String("attribute_type(pgn, :string)"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":string": {
+					S: aws.String(dynamodb.ScalarAttributeTypeS),
+				},
+			},
+			Limit:             aws.Int64(maxGamesPerScan),
+			ExclusiveStartKey: lastKey,
+		})
+		if err != nil {
+			logger.Error("impossible to scan the games", zap.Error(err))
+			return
+		}
+
+		for _, gameItem := range scanOutput.Items {
+			var isMigrated bool
+			isMigrated, err = migrator.migrateGame(dynamodbClient, logger, gameItem)
+			if err != nil {
+				return
+			}
+			if isMigrated {
+				migrated++
+			} else {
+				skipped++
+			}
+		}
+
+		logger.Info("games migrated so far", zap.Int("migrated", migrated), zap.Int("skipped", skipped))
+
+		lastKey = scanOutput.LastEvaluatedKey
+		if len(lastKey) == 0 {
+			break
+		}
+	}
+
+	logger.Info("migration finished", zap.Int("migrated", migrated), zap.Int("skipped", skipped))
+	return
+}
+
+func (migrator *PgnMigrator) migrateGame(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	gameItem map[string]*dynamodb.AttributeValue,
+) (isMigrated bool, err error) {
+	logger = logger.With(zap.String("userId", aws.StringValue(gameItem["user_id"].S)), zap.String("gameId", aws.StringValue(gameItem["game_id"].S)))
+
+	plainPgn := gameItem["pgn"]
+	encodedPgn, err := games.EncodePgn(aws.StringValue(plainPgn.S))
+	if err != nil {
+		logger.Error("impossible to encode the pgn", zap.Error(err))
+		return
+	}
+
+	// only the pgn is rewritten and only if nobody has rewritten it since the scan
+	_, err = dynamodbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(migrator.gamesTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"user_id": gameItem["user_id"],
+			"game_id": gameItem["game_id"],
+		},
+		ConditionExpression: aws.String("pgn = :plain_pgn"),
+		UpdateExpression:    aws.String("SET pgn = :encoded_pgn"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":plain_pgn": plainPgn,
+			":encoded_pgn": {
+				B: encodedPgn,
+			},
+		},
+	})
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		logger.Info("game was changed since the scan, skipping it")
+		err = nil
+		return
+	}
+
+	if err != nil {
+		logger.Error("impossible to rewrite the pgn of the game", zap.Error(err))
+		return
+	}
+
+	isMigrated = true
+	return
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var awsConfig = aws.Config{
+	Region:     aws.String("us-east-1"),
+	Endpoint:   aws.String("http://localhost:4566"), // this is the LocalStack endpoint for all services
+	DisableSSL: aws.Bool(true),
+}
+
+var migrator = PgnMigrator{
+	gamesTableName: "chessfinder_dynamodb-games",
+	awsConfig:      &awsConfig,
+}
+
+var awsSession = session.Must(session.NewSession(&awsConfig))
+
+var dynamodbClient = dynamodb.New(awsSession)
+
+func Test_PgnMigrator_should_compress_the_pgn_stored_as_a_plain_string_in_place(t *testing.T) {
+	userId := uuid.New().String()
+	gameId := "https://www.chess.com/game/live/53168947271"
+	pgn := "[Event \"Live Chess\"]\n[White \"tigran-c-137\"]\n[Black \"akashvishwakarma000\"]\n[Result \"1-0\"]\n\n1. d4 {[%clk 0:04:48.8]} 1... b5 {[%clk 0:04:54.6]} 2. Nc3 {[%clk 0:04:40.2]} 1-0\n"
+
+	plainGameItems := map[string]*dynamodb.AttributeValue{
+		"user_id":       {S: aws.String(userId)},
+		"archive_id":    {S: aws.String(uuid.New().String())},
+		"game_id":       {S: aws.String(gameId)},
+		"resource":      {S: aws.String(gameId)},
+		"pgn":           {S: aws.String(pgn)},
+		"end_timestamp": {N: aws.String("1659429921")},
+		"white":         {S: aws.String("tigran-c-137")},
+	}
+
+	_, err := dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(migrator.gamesTableName),
+		Item:      plainGameItems,
+	})
+	assert.NoError(t, err)
+
+	migrated, err := migrator.Migrate()
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, migrated, 1)
+
+	actualGameItems, err := migrator.getGameItems(userId, gameId)
+	assert.NoError(t, err)
+
+	assert.Nil(t, actualGameItems["pgn"].S)
+	assert.Equal(t, games.PgnDeflated, actualGameItems["pgn"].B[0])
+	assert.Equal(t, plainGameItems["white"], actualGameItems["white"])
+
+	actualGame := games.GameRecord{}
+	err = dynamodbattribute.UnmarshalMap(actualGameItems, &actualGame)
+	assert.NoError(t, err)
+	assert.Equal(t, pgn, actualGame.Pgn)
+	assert.Equal(t, "tigran-c-137", actualGame.White)
+}
+
+func (migrator *PgnMigrator) getGameItems(userId string, gameId string) (gameItems map[string]*dynamodb.AttributeValue, err error) {
+	getItemOutput, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(migrator.gamesTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"user_id": {
+				S: aws.String(userId),
+			},
+			"game_id": {
+				S: aws.String(gameId),
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+	gameItems = getItemOutput.Item
+	return
+}
@@ -3,10 +3,6 @@ package main
 import (
 	"encoding/json"
 	"errors"
-	"io"
-	"net/http"
-	"net/url"
-	"strconv"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -14,23 +10,29 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
-	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/batcher"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/archives"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/downloads"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/users"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/lichess"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 type GameDownloader struct {
-	chessDotComUrl               string
-	downloadsTableName           string
-	archivesTableName            string
-	gamesTableName               string
-	gamesByEndTimestampIndexName string
-	awsConfig                    *aws.Config
+	chessDotComConfig        chessdotcom.Config
+	lichessConfig            lichess.Config
+	downloadsTableName       string
+	archiveOutcomesTableName string
+	archivesTableName        string
+	gamesTableName           string
+	gamesByArchiveIndexName  string
+	deliverCallbacksQueueUrl string
+	awsConfig                *aws.Config
 }
 
 func (downloader *GameDownloader) Download(commands events.SQSEvent) (commandsProcessed events.SQSEventResponse, err error) {
@@ -52,20 +54,57 @@ func (downloader *GameDownloader) Download(commands events.SQSEvent) (commandsPr
 		return
 	}
 	dynamodbClient := dynamodb.New(awsSession)
-	chessDotComClient := &http.Client{}
+	svc := sqs.New(awsSession)
+	chessDotComClient, err := chessdotcom.NewClient(downloader.chessDotComConfig)
+	if err != nil {
+		logger.Error("impossible to create a chess.com client!", zap.Error(err))
+		return
+	}
+	lichessClient, err := lichess.NewClient(downloader.lichessConfig)
+	if err != nil {
+		logger.Error("impossible to create a lichess client!", zap.Error(err))
+		return
+	}
 
 	logger.Info("Processing commands in total", zap.Int("commands", len(commands.Records)))
 
+	permanentFailures := 0
+	failedGroups := map[string]struct{}{}
 	for _, message := range commands.Records {
-		_, _ = downloader.processSingle(&message, dynamodbClient, chessDotComClient, logger)
+		// commands of a message group are delivered in order, the ones after a failed command wait for it
+		messageGroupId := message.Attributes["MessageGroupId"]
+		if _, isGroupFailed := failedGroups[messageGroupId]; isGroupFailed {
+			commandsProcessed.BatchItemFailures = append(commandsProcessed.BatchItemFailures, events.SQSBatchItemFailure{
+				ItemIdentifier: message.MessageId,
+			})
+			continue
+		}
+
+		commandFailure, errOfCommand := downloader.processSingle(&message, dynamodbClient, svc, chessDotComClient, lichessClient, logger)
+		if commandFailure != nil {
+			failedGroups[messageGroupId] = struct{}{}
+			commandsProcessed.BatchItemFailures = append(commandsProcessed.BatchItemFailures, *commandFailure)
+			continue
+		}
+		if errOfCommand != nil {
+			permanentFailures++
+		}
 	}
+
+	logger.Info(
+		"commands processed",
+		zap.Int("permanentFailures", permanentFailures),
+		zap.Int("redelivered", len(commandsProcessed.BatchItemFailures)),
+	)
 	return
 }
 
 func (downloader *GameDownloader) processSingle(
 	message *events.SQSMessage,
 	dynamodbClient *dynamodb.DynamoDB,
-	chessDotComClient *http.Client,
+	svc *sqs.SQS,
+	chessDotComClient *chessdotcom.Client,
+	lichessClient *lichess.Client,
 	logger *zap.Logger,
 ) (commandProcessed *events.SQSBatchItemFailure, err error) {
 	command := queue.DownloadGamesCommand{}
@@ -83,68 +122,120 @@ func (downloader *GameDownloader) processSingle(
 
 	logger.Info("Processing command")
 
-	incrementDownloadStatus := func(incrementSuccess bool) (err error) {
+	startedAt := time.Now()
+	gamesAdded := 0
 
-		logger.Info("incrementing the download status")
-		downloadRecord := downloads.DownloadRecord{}
-		downloadRecordItems, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
-			TableName: aws.String(downloader.downloadsTableName),
-			Key: map[string]*dynamodb.AttributeValue{
-				"download_id": {
-					S: aws.String(command.DownloadId),
-				},
-			},
-		})
+	// incrementDownloadStatus records the outcome of the archive and counts it in the download in one transaction,
+	// the outcome is the idempotency guard, an archive is counted again only after it has been claimed for a retry.
+	// A failure to count or to notify is returned, so the command is delivered again,
+	// and the redelivered command notifies about the completed download even if the archive is already counted
+	incrementDownloadStatus := func(state downloads.ArchiveState, cause error) (err error) {
 
+		logger.Info("incrementing the download status")
+		archiveOutcome := downloads.ArchiveOutcomeRecord{
+			DownloadId:     command.DownloadId,
+			ArchiveId:      command.ArchiveId,
+			UserId:         command.UserId,
+			Username:       command.Username,
+			Platform:       users.Platform(command.Platform),
+			State:          state,
+			ErrorCode:      errorCode(cause),
+			GamesAdded:     gamesAdded,
+			DurationMillis: time.Since(startedAt).Milliseconds(),
+		}
+		archiveOutcomeItems, err := dynamodbattribute.MarshalMap(archiveOutcome)
 		if err != nil {
-			logger.Error("impossible to get the download record", zap.Error(err))
+			logger.Error("impossible to marshal the archive outcome", zap.Error(err))
 			return
 		}
 
-		if downloadRecordItems.Item == nil || len(downloadRecordItems.Item) == 0 {
-			logger.Error("download record not found")
-			return
+		counter := "failed"
+		switch state {
+		case downloads.ArchiveSucceeded:
+			counter = "succeed"
+		case downloads.ArchiveCancelled:
+			counter = "cancelled"
 		}
 
-		err = dynamodbattribute.UnmarshalMap(downloadRecordItems.Item, &downloadRecord)
-		if err != nil {
-			logger.Error("impossible to unmarshal the download record", zap.Error(err))
-			return
-		}
+		_, err = dynamodbClient.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+			TransactItems: []*dynamodb.TransactWriteItem{
+				{
+					Put: &dynamodb.Put{
+						TableName:           aws.String(downloader.archiveOutcomesTableName),
+						Item:                archiveOutcomeItems,
+						ConditionExpression: aws.String("attribute_not_exists(download_id) OR #state = :retrying"),
+						ExpressionAttributeNames: map[string]*string{
+							"#state": aws.String("state"),
+						},
+						ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+							":retrying": {
+								S: aws.String(string(downloads.ArchiveRetrying)),
+							},
+						},
+					},
+				},
+				{
+					Update: &dynamodb.Update{
+						TableName: aws.String(downloader.downloadsTableName),
+						Key: map[string]*dynamodb.AttributeValue{
+							"download_id": {
+								S: aws.String(command.DownloadId),
+							},
+						},
+						UpdateExpression:    aws.String("ADD pending :decrement, done :increment, #counter :increment"),
+						ConditionExpression: aws.String("done < total"),
+						ExpressionAttributeNames: map[string]*string{
+							"#counter": aws.String(counter),
+						},
+						ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+							":increment": {
+								N: aws.String("1"),
+							},
+							":decrement": {
+								N: aws.String("-1"),
+							},
+						},
+					},
+				},
+			},
+		})
 
-		if downloadRecord.Total <= downloadRecord.Done {
-			logger.Error("inconsitent download record", zap.Int("total", downloadRecord.Total), zap.Int("done", downloadRecord.Done))
-			return
+		var transactionCanceled *dynamodb.TransactionCanceledException
+		if errors.As(err, &transactionCanceled) {
+			reasons := transactionCanceled.CancellationReasons
+			if len(reasons) > 0 && aws.StringValue(reasons[0].Code) == "ConditionalCheckFailed" {
+				logger.Info("archive is already counted in the download")
+				err = downloader.notifyIfCompleted(dynamodbClient, svc, logger, command.DownloadId)
+				return
+			}
+			if len(reasons) > 1 && aws.StringValue(reasons[1].Code) == "ConditionalCheckFailed" {
+				logger.Error("download record not found or all its archives are already counted")
+				err = downloader.notifyIfCompleted(dynamodbClient, svc, logger, command.DownloadId)
+				return
+			}
 		}
 
-		downloadRecord.Pending--
-		if incrementSuccess {
-			downloadRecord.Succeed++
-		} else {
-			downloadRecord.Failed++
+		if err != nil {
+			logger.Error("impossible to update the download record", zap.Error(err))
+			return
 		}
-		downloadRecord.Done++
 
-		updatedDownloadRecordItems, err := dynamodbattribute.MarshalMap(downloadRecord)
+		err = downloader.notifyIfCompleted(dynamodbClient, svc, logger, command.DownloadId)
+		return
+	}
 
+	unsafeProcessSingle := func() (err error) {
+		cancelRequested, err := downloader.isCancelRequested(dynamodbClient, logger, command.DownloadId)
 		if err != nil {
-			logger.Error("impossible to marshal the download record", zap.Error(err))
 			return
 		}
 
-		_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
-			TableName: aws.String(downloader.downloadsTableName),
-			Item:      updatedDownloadRecordItems,
-		})
-
-		if err != nil {
-			logger.Error("impossible to update the download record", zap.Error(err))
+		if cancelRequested {
+			logger.Info("download is cancelled, skipping the archive")
+			err = incrementDownloadStatus(downloads.ArchiveCancelled, nil)
 			return
 		}
-		return
-	}
 
-	unsafeProcessSingle := func() (err error) {
 		archiveRecord := archives.ArchiveRecord{}
 		archiveRecordItems, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
 			TableName: aws.String(downloader.archivesTableName),
@@ -165,10 +256,7 @@ func (downloader *GameDownloader) processSingle(
 
 		if archiveRecordItems.Item == nil || len(archiveRecordItems.Item) == 0 {
 			logger.Error("archive record not found")
-			errOfIncrement := incrementDownloadStatus(true)
-			if errOfIncrement != nil {
-				logger.Error("impossible to increment the download status", zap.Error(err))
-			}
+			err = incrementDownloadStatus(downloads.ArchiveSucceeded, nil)
 			return
 		}
 
@@ -179,206 +267,282 @@ func (downloader *GameDownloader) processSingle(
 		}
 
 		archiveHasGamesTill := time.Date(archiveRecord.Year, time.Month(archiveRecord.Month), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
-		if archiveRecord.DownloadedAt != nil && !archiveRecord.DownloadedAt.ToTime().Before(archiveHasGamesTill) {
+		if archiveRecord.Uploaded || archiveRecord.DownloadedAt != nil && !archiveRecord.DownloadedAt.ToTime().Before(archiveHasGamesTill) {
 			logger.Info("archive already downloaded")
-			errOfIncrement := incrementDownloadStatus(true)
-			if errOfIncrement != nil {
-				logger.Error("impossible to increment the download status", zap.Error(err))
-			}
+			err = incrementDownloadStatus(downloads.ArchiveSucceeded, nil)
 			return
 		}
 
 		now := time.Now()
-		chessDotComGames := ChessDotComGames{}
-		// make this validation while reading envarionment variables
-		requestUrl, err := url.ParseRequestURI(downloader.chessDotComUrl)
-		if err != nil {
-			logger.Error("impossible to parse the chess.com url", zap.Error(err))
-			return
+
+		// the stored games are read with the first downloaded game, an archive that is not modified does not touch the games table
+		var storedGameIds map[string]struct{}
+
+		missingGames := newGameWriter(dynamodbClient, downloader.gamesTableName, logger)
+		downloadedGames := 0
+		persistIfMissing := func(gameRecord games.GameRecord) (err error) {
+			if storedGameIds == nil {
+				storedGameIds, err = downloader.getStoredGameIds(dynamodbClient, logger, command.ArchiveId)
+				if err != nil {
+					return
+				}
+			}
+			downloadedGames++
+			if _, isStored := storedGameIds[gameRecord.GameId]; isStored {
+				return
+			}
+			// the same game can not be put twice into one batch
+			storedGameIds[gameRecord.GameId] = struct{}{}
+			return missingGames.add(gameRecord)
 		}
-		monthInString := strconv.Itoa(archiveRecord.Month)
-		if len(monthInString) == 1 {
-			monthInString = "0" + monthInString
+
+		logger.Info("persisiting missing games while downloading")
+
+		var validators chessdotcom.Validators
+		switch command.Platform {
+		case queue.Lichess:
+			err = downloader.downloadLichessGames(lichessClient, logger, command, archiveRecord, persistIfMissing)
+		default:
+			validators, err = downloader.downloadChessDotComGames(chessDotComClient, logger, command, archiveRecord, persistIfMissing)
 		}
-		requestUrl.Path = "/pub/player/" + command.Username + "/games/" + strconv.Itoa(archiveRecord.Year) + "/" + monthInString
-		url := requestUrl.String()
-		logger.Info("requesting games", zap.String("url", url))
+		gamesAdded = missingGames.written
 
-		downloadGamesRequest, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			logger.Error("Error while creating request to download games", zap.Error(err))
+		if errors.Is(err, chessdotcom.ErrNotModified) {
+			logger.Info("archive has not been modified since the last download")
+			nowInZulu := db.Zuludatetime(now)
+			archiveRecord.DownloadedAt = &nowInZulu
+			err = downloader.persistArchiveRecord(dynamodbClient, logger, archiveRecord)
+			if err != nil {
+				return
+			}
+			err = incrementDownloadStatus(downloads.ArchiveSucceeded, nil)
 			return
 		}
-		downloadGamesRequest.Header["Accept"] = []string{"application/json"}
-		response, err := chessDotComClient.Do(downloadGamesRequest)
+
 		if err != nil {
-			logger.Error("impossible to get the games", zap.Error(err))
 			return
 		}
-		defer response.Body.Close()
 
-		responseBodyBytes, err := io.ReadAll(response.Body)
+		err = missingGames.flush()
+		gamesAdded = missingGames.written
 		if err != nil {
-			logger.Error("impossible to read the response body from chess.com!", zap.Error(err))
 			return
 		}
 
-		responseBodyString := string(responseBodyBytes)
+		logger = logger.With(zap.Int("allDownloadedGames", downloadedGames), zap.Int("missingGames", missingGames.written))
 
-		if response.StatusCode != 200 {
-			logger.Error("unexpected status code from chess.com", zap.Int("statusCode", response.StatusCode), zap.String("responseBody", responseBodyString))
-			err = errors.New("unexpected status code from chess.com")
+		if downloadedGames == 0 {
+			logger.Info("no games found")
+			err = incrementDownloadStatus(downloads.ArchiveSucceeded, nil)
 			return
 		}
 
-		err = json.Unmarshal(responseBodyBytes, &chessDotComGames)
+		logger.Info("missing games persisted", zap.Int("batches", missingGames.batches))
+
+		nowInZulu := db.Zuludatetime(now)
+		archiveRecord.DownloadedAt = &nowInZulu
+		archiveRecord.Downloaded = len(storedGameIds)
+		archiveRecord.ETag = validators.ETag
+		archiveRecord.LastModified = validators.LastModified
+
+		err = downloader.persistArchiveRecord(dynamodbClient, logger, archiveRecord)
 		if err != nil {
-			logger.Error("impossible to unmarshal the games", zap.Error(err))
 			return
 		}
 
-		logger = logger.With(zap.Int("allDownloadedGames", len(chessDotComGames.Games)))
+		err = incrementDownloadStatus(downloads.ArchiveSucceeded, nil)
 
-		if len(chessDotComGames.Games) == 0 {
-			logger.Info("no games found")
-			errOfIncrement := incrementDownloadStatus(true)
-			if errOfIncrement != nil {
-				logger.Error("impossible to increment the download status", zap.Error(err))
+		return
+	}
+
+	err = unsafeProcessSingle()
+	if err != nil {
+		logger.Error("impossible to process the command", zap.Error(err))
+		isLastReceive := queue.IsLastReceive(message.Attributes["ApproximateReceiveCount"])
+		if isRetryable(err) {
+			// the command goes to the dead letter queue after the last receive
+			commandProcessed = &events.SQSBatchItemFailure{ItemIdentifier: message.MessageId}
+			if !isLastReceive {
+				logger.Info("command will be delivered again")
+				return
+			}
+		}
+		errOfIncrement := incrementDownloadStatus(downloads.ArchiveFailed, err)
+		if errOfIncrement != nil {
+			logger.Error("impossible to increment the download status", zap.Error(errOfIncrement))
+			if isRetryable(errOfIncrement) && !isLastReceive {
+				logger.Info("command will be delivered again to count the archive")
+				commandProcessed = &events.SQSBatchItemFailure{ItemIdentifier: message.MessageId}
 			}
-			return
 		}
+		return
+	}
+
+	return
+}
 
-		latestDownloadedGameRecord := games.GameRecord{}
-		latestDownloadedGameRecordItems, err := dynamodbClient.Query(&dynamodb.QueryInput{
+func (downloader *GameDownloader) isCancelRequested(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	downloadId string,
+) (cancelRequested bool, err error) {
+	downloadRecordItems, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(downloader.downloadsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"download_id": {
+				S: aws.String(downloadId),
+			},
+		},
+		ProjectionExpression: aws.String("cancel_requested"),
+	})
+	if err != nil {
+		logger.Error("impossible to get the download record", zap.Error(err))
+		return
+	}
+
+	downloadRecord := downloads.DownloadRecord{}
+	err = dynamodbattribute.UnmarshalMap(downloadRecordItems.Item, &downloadRecord)
+	if err != nil {
+		logger.Error("impossible to unmarshal the download record", zap.Error(err))
+		return
+	}
+
+	cancelRequested = downloadRecord.CancelRequested
+	return
+}
+
+// getStoredGameIds reads only the keys of the games already stored for the archive,
+// games that were persisted by an interrupted download are considered stored as well
+func (downloader *GameDownloader) getStoredGameIds(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	archiveId string,
+) (storedGameIds map[string]struct{}, err error) {
+	storedGameIds = map[string]struct{}{}
+	var lastKey map[string]*dynamodb.AttributeValue
+
+	for {
+		var storedGameIdsItems *dynamodb.QueryOutput
+		storedGameIdsItems, err = dynamodbClient.Query(&dynamodb.QueryInput{
 			TableName:              aws.String(downloader.gamesTableName),
-			IndexName:              aws.String(downloader.gamesByEndTimestampIndexName),
+			IndexName:              aws.String(downloader.gamesByArchiveIndexName),
 			KeyConditionExpression: aws.String("archive_id = :archive_id"),
 			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
 				":archive_id": {
-					S: aws.String(command.ArchiveId),
+					S: aws.String(archiveId),
 				},
 			},
-			ScanIndexForward: aws.Bool(false),
-			Limit:            aws.Int64(1),
+			ProjectionExpression: aws.String("game_id"),
+			ExclusiveStartKey:    lastKey,
 		})
 
 		if err != nil {
-			logger.Error("impossible to get the latest downloaded game", zap.Error(err))
+			logger.Error("impossible to get the stored games of the archive", zap.Error(err))
 			return
 		}
 
-		if len(latestDownloadedGameRecordItems.Items) > 0 {
-			err = dynamodbattribute.UnmarshalMap(latestDownloadedGameRecordItems.Items[0], &latestDownloadedGameRecord)
-			if err != nil {
-				logger.Error("impossible to unmarshal the latest downloaded game", zap.Error(err))
-				return
-			}
-		}
-
-		missingGameRecords := []games.GameRecord{}
-		for _, chessDotComGame := range chessDotComGames.Games {
-			if chessDotComGame.EndTime > latestDownloadedGameRecord.EndTimestamp {
-				pgnString := string(chessDotComGame.Pgn)
-				gameRecord := games.GameRecord{
-					UserId:       command.UserId,
-					ArchiveId:    command.ArchiveId,
-					GameId:       chessDotComGame.Url,
-					Resource:     chessDotComGame.Url,
-					Pgn:          pgnString,
-					EndTimestamp: chessDotComGame.EndTime,
-				}
-				missingGameRecords = append(missingGameRecords, gameRecord)
+		for _, storedGameIdItems := range storedGameIdsItems.Items {
+			gameId := storedGameIdItems["game_id"]
+			if gameId != nil && gameId.S != nil {
+				storedGameIds[*gameId.S] = struct{}{}
 			}
 		}
 
-		logger = logger.With(zap.Int("missingGames", len(missingGameRecords)))
-		logger.Info("persisiting missing games")
-
-		missingGameRecordWriteRequests := []*dynamodb.WriteRequest{}
-		for _, missingGameRecord := range missingGameRecords {
-			var missingGameRecordItems map[string]*dynamodb.AttributeValue
-			missingGameRecordItems, err = dynamodbattribute.MarshalMap(missingGameRecord)
-			if err != nil {
-				logger.Error("impossible to marshal the missing game record", zap.Error(err))
-				return
-			}
-			writeRequest := &dynamodb.WriteRequest{
-				PutRequest: &dynamodb.PutRequest{
-					Item: missingGameRecordItems,
-				},
-			}
-			missingGameRecordWriteRequests = append(missingGameRecordWriteRequests, writeRequest)
+		lastKey = storedGameIdsItems.LastEvaluatedKey
+		if len(lastKey) == 0 {
+			break
 		}
+	}
 
-		missingGameRecordWriteRequestsMatrix := batcher.Batcher(missingGameRecordWriteRequests, 25)
-
-		logger.Info("trying to persist missing games in batches", zap.Int("batches", len(missingGameRecordWriteRequestsMatrix)))
-
-		for bacthNumber, batch := range missingGameRecordWriteRequestsMatrix {
-			logger := logger.With(zap.Int("batchNumber", bacthNumber+1), zap.Int("batchSize", len(batch)))
-			logger.Info("trying to persist a batch of missing games")
-
-			unprocessedWriteRequests := map[string][]*dynamodb.WriteRequest{
-				downloader.gamesTableName: batch,
-			}
-
-			for len(unprocessedWriteRequests) > 0 {
-				logger.Info("trying to persist missing games in one iteration")
-				var writeOutput *dynamodb.BatchWriteItemOutput
-				writeOutput, err = dynamodbClient.BatchWriteItem(&dynamodb.BatchWriteItemInput{
-					RequestItems: unprocessedWriteRequests,
-				})
+	logger.Info("stored games of the archive are found", zap.Int("storedGames", len(storedGameIds)))
+	return
+}
 
-				if err != nil {
-					logger.Error("impossible to persist the missing game records", zap.Error(err))
-					return
-				}
+func (downloader *GameDownloader) persistArchiveRecord(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	archiveRecord archives.ArchiveRecord,
+) (err error) {
+	logger.Info("updating the archive record")
 
-				unprocessedWriteRequests = writeOutput.UnprocessedItems
-				time.Sleep(time.Millisecond * 100)
-			}
-		}
+	updatedArchiveRecordItems, err := dynamodbattribute.MarshalMap(archiveRecord)
 
-		nowInZulu := db.Zuludatetime(now)
-		archiveRecord.DownloadedAt = &nowInZulu
-		archiveRecord.Downloaded += int(len(missingGameRecords))
+	if err != nil {
+		logger.Error("impossible to marshal the archive record", zap.Error(err))
+		return
+	}
 
-		logger.Info("updating the archive record")
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(downloader.archivesTableName),
+		Item:      updatedArchiveRecordItems,
+	})
 
-		updatedArchiveRecordItems, err := dynamodbattribute.MarshalMap(archiveRecord)
+	if err != nil {
+		logger.Error("impossible to update the archive record", zap.Error(err))
+		return
+	}
+	return
+}
 
-		if err != nil {
-			logger.Error("impossible to marshal the archive record", zap.Error(err))
-			return
-		}
+func (downloader *GameDownloader) downloadChessDotComGames(
+	chessDotComClient *chessdotcom.Client,
+	logger *zap.Logger,
+	command queue.DownloadGamesCommand,
+	archiveRecord archives.ArchiveRecord,
+	consume func(gameRecord games.GameRecord) error,
+) (validators chessdotcom.Validators, err error) {
+	logger.Info("requesting games")
+	cachedValidators := chessdotcom.Validators{
+		ETag:         archiveRecord.ETag,
+		LastModified: archiveRecord.LastModified,
+	}
+	validators, err = chessDotComClient.StreamGames(logger, command.Username, archiveRecord.Year, archiveRecord.Month, cachedValidators, func(chessDotComGame chessdotcom.Game) error {
+		return consume(chessDotComGameRecord(command, chessDotComGame))
+	})
 
-		_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
-			TableName: aws.String(downloader.archivesTableName),
-			Item:      updatedArchiveRecordItems,
-		})
+	if errors.Is(err, chessdotcom.ErrNotModified) {
+		return
+	}
 
-		if err != nil {
-			logger.Error("impossible to update the archive record", zap.Error(err))
-			return
-		}
+	// chess.com does not keep the archives of closed accounts, there is nothing left to download then
+	if errors.Is(err, chessdotcom.ErrNotFound) || errors.Is(err, chessdotcom.ErrGone) {
+		logger.Info("archive is not available on chess.com anymore", zap.Error(err))
+		err = nil
+		return
+	}
 
-		errOfIncrement := incrementDownloadStatus(true)
-		if errOfIncrement != nil {
-			logger.Error("impossible to increment the download status", zap.Error(err))
-		}
+	if err != nil {
+		logger.Error("impossible to get the games", zap.Error(err))
+		return
+	}
+	return
+}
 
+func (downloader *GameDownloader) downloadLichessGames(
+	lichessClient *lichess.Client,
+	logger *zap.Logger,
+	command queue.DownloadGamesCommand,
+	archiveRecord archives.ArchiveRecord,
+	consume func(gameRecord games.GameRecord) error,
+) (err error) {
+	since := time.Date(archiveRecord.Year, time.Month(archiveRecord.Month), 1, 0, 0, 0, 0, time.UTC)
+	until := since.AddDate(0, 1, 0)
+
+	logger.Info("requesting games")
+	err = lichessClient.StreamGames(logger, command.Username, since, until, func(lichessGame lichess.Game) error {
+		return consume(lichessGameRecord(command, lichessGame))
+	})
+
+	// lichess does not export the games of closed accounts, there is nothing left to download then
+	if errors.Is(err, lichess.ErrNotFound) || errors.Is(err, lichess.ErrClosed) {
+		logger.Info("games are not available on lichess anymore", zap.Error(err))
+		err = nil
 		return
 	}
 
-	err = unsafeProcessSingle()
 	if err != nil {
-		logger.Error("impossible to process the command", zap.Error(err))
-		errOfIncrement := incrementDownloadStatus(false)
-		if errOfIncrement != nil {
-			logger.Error("impossible to increment the download status", zap.Error(err))
-		}
+		logger.Error("impossible to get the games", zap.Error(err))
 		return
 	}
-
 	return
 }
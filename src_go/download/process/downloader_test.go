@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"testing"
 	"time"
 
@@ -13,10 +16,15 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/archives"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/downloads"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/lichess"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/pgn"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/wiremock/go-wiremock"
@@ -29,12 +37,15 @@ var awsConfig = aws.Config{
 }
 
 var downloader = GameDownloader{
-	chessDotComUrl:               "http://0.0.0.0:18443",
-	downloadsTableName:           "chessfinder_dynamodb-downloads",
-	archivesTableName:            "chessfinder_dynamodb-archives",
-	gamesTableName:               "chessfinder_dynamodb-games",
-	gamesByEndTimestampIndexName: "chessfinder_dynamodb-gamesByEndTimestamp",
-	awsConfig:                    &awsConfig,
+	chessDotComConfig:        chessdotcom.DefaultConfig("http://0.0.0.0:18443"),
+	lichessConfig:            lichess.DefaultConfig("http://0.0.0.0:18443"),
+	downloadsTableName:       "chessfinder_dynamodb-downloads",
+	archiveOutcomesTableName: "chessfinder_dynamodb-archiveOutcomes",
+	archivesTableName:        "chessfinder_dynamodb-archives",
+	gamesTableName:           "chessfinder_dynamodb-games",
+	gamesByArchiveIndexName:  "chessfinder_dynamodb-gamesByArchive",
+	deliverCallbacksQueueUrl: "http://localhost:4566/000000000000/chessfinder_sqs-DeliverCallbacks",
+	awsConfig:                &awsConfig,
 }
 var awsSession = session.Must(session.NewSession(&awsConfig))
 var dynamodbClient = dynamodb.New(awsSession)
@@ -99,6 +110,15 @@ func Test_when_archive_is_partially_downloaded_CommitDownloader_should_download_
 		Resource:     "https://www.chess.com/game/live/53169604577",
 		Pgn:          "[Event \"Live Chess\"]\n[Site \"Chess.com\"]\n[Date \"2022.08.02\"]\n[Round \"-\"]\n[White \"N-60\"]\n[Black \"tigran-c-137\"]\n[Result \"1-0\"]\n[CurrentPosition \"5Q1k/pbp3pp/1p6/4P1q1/2B5/8/PPP3PP/R6K b - -\"]\n[Timezone \"UTC\"]\n[ECO \"C34\"]\n[ECOUrl \"https://www.chess.com/openings/Kings-Gambit-Accepted-Schallopp-Defense-4.e5\"]\n[UTCDate \"2022.08.02\"]\n[UTCTime \"08:58:14\"]\n[WhiteElo \"1067\"]\n[BlackElo \"990\"]\n[TimeControl \"300\"]\n[Termination \"N-60 won by checkmate\"]\n[StartTime \"08:58:14\"]\n[EndDate \"2022.08.02\"]\n[EndTime \"09:04:04\"]\n[Link \"https://www.chess.com/game/live/53169604577\"]\n\n1. e4 {[%clk 0:04:57.7]} 1... e5 {[%clk 0:04:58.9]} 2. f4 {[%clk 0:04:54.8]} 2... exf4 {[%clk 0:04:57.2]} 3. Nf3 {[%clk 0:04:53.7]} 3... Nf6 {[%clk 0:04:50.9]} 4. e5 {[%clk 0:04:50.6]} 4... Ng4 {[%clk 0:04:07.6]} 5. d4 {[%clk 0:04:46]} 5... Be7 {[%clk 0:03:50.5]} 6. Bc4 {[%clk 0:04:36.6]} 6... O-O {[%clk 0:03:44.1]} 7. Bxf4 {[%clk 0:04:33.5]} 7... d5 {[%clk 0:03:31.9]} 8. Bd3 {[%clk 0:04:29.3]} 8... f6 {[%clk 0:03:25.3]} 9. O-O {[%clk 0:04:23.3]} 9... fxe5 {[%clk 0:03:20]} 10. Bxe5 {[%clk 0:04:21.6]} 10... Nxe5 {[%clk 0:03:11.7]} 11. Nxe5 {[%clk 0:04:19.3]} 11... Nd7 {[%clk 0:02:53]} 12. Nc3 {[%clk 0:04:10.5]} 12... Nxe5 {[%clk 0:02:50.4]} 13. dxe5 {[%clk 0:04:08.7]} 13... Bc5+ {[%clk 0:02:04.9]} 14. Kh1 {[%clk 0:04:05.3]} 14... b6 {[%clk 0:01:58]} 15. Nxd5 {[%clk 0:04:02.9]} 15... Bb7 {[%clk 0:01:44.7]} 16. Nf4 {[%clk 0:03:51.8]} 16... Rxf4 {[%clk 0:01:19.7]} 17. Rxf4 {[%clk 0:03:46.7]} 17... Qg5 {[%clk 0:01:18.7]} 18. Qf1 {[%clk 0:03:30.9]} 18... Rf8 {[%clk 0:01:06.8]} 19. Rxf8+ {[%clk 0:03:25.7]} 19... Bxf8 {[%clk 0:01:04.9]} 20. Bc4+ {[%clk 0:03:21]} 20... Kh8 {[%clk 0:01:02.4]} 21. Qxf8# {[%clk 0:03:18.1]} 1-0\n",
 		EndTimestamp: 1659431044,
+		White:        "N-60",
+		Black:        "tigran-c-137",
+		WhiteRating:  1067,
+		BlackRating:  990,
+		Result:       "1-0",
+		Eco:          "C34",
+		TimeControl:  "300",
+		TimeClass:    "blitz",
+		Rules:        "chess",
 	}
 
 	newGame2 := games.GameRecord{
@@ -108,6 +128,15 @@ func Test_when_archive_is_partially_downloaded_CommitDownloader_should_download_
 		Resource:     "https://www.chess.com/game/live/53170160741",
 		Pgn:          "[Event \"Live Chess\"]\n[Site \"Chess.com\"]\n[Date \"2022.08.02\"]\n[Round \"-\"]\n[White \"yayancito24\"]\n[Black \"tigran-c-137\"]\n[Result \"1-0\"]\n[CurrentPosition \"6k1/pp4p1/7p/3n4/2BN4/8/PP1b1PPP/5K2 b - -\"]\n[Timezone \"UTC\"]\n[ECO \"C20\"]\n[ECOUrl \"https://www.chess.com/openings/Kings-Pawn-Opening-Wayward-Queen-Kiddie-Countergambit\"]\n[UTCDate \"2022.08.02\"]\n[UTCTime \"09:04:12\"]\n[WhiteElo \"898\"]\n[BlackElo \"980\"]\n[TimeControl \"300\"]\n[Termination \"yayancito24 won by resignation\"]\n[StartTime \"09:04:12\"]\n[EndDate \"2022.08.02\"]\n[EndTime \"09:09:02\"]\n[Link \"https://www.chess.com/game/live/53170160741\"]\n\n1. e4 {[%clk 0:05:00]} 1... e5 {[%clk 0:04:59.9]} 2. Qh5 {[%clk 0:04:59.2]} 2... Nf6 {[%clk 0:04:54.3]} 3. Qxe5+ {[%clk 0:04:57.4]} 3... Qe7 {[%clk 0:04:47.1]} 4. Qxe7+ {[%clk 0:04:56.4]} 4... Bxe7 {[%clk 0:04:45.4]} 5. d3 {[%clk 0:04:56.3]} 5... O-O {[%clk 0:04:43]} 6. Bg5 {[%clk 0:04:55.6]} 6... h6 {[%clk 0:04:39.1]} 7. Bxf6 {[%clk 0:04:54.6]} 7... Bxf6 {[%clk 0:04:38.1]} 8. c3 {[%clk 0:04:53.7]} 8... c6 {[%clk 0:04:32.5]} 9. Nf3 {[%clk 0:04:53]} 9... d5 {[%clk 0:04:30.8]} 10. e5 {[%clk 0:04:48.2]} 10... Re8 {[%clk 0:04:25.5]} 11. d4 {[%clk 0:04:47.1]} 11... Bg4 {[%clk 0:04:10.1]} 12. Be2 {[%clk 0:04:45.3]} 12... Bxf3 {[%clk 0:04:08.3]} 13. Bxf3 {[%clk 0:04:44.3]} 13... Bg5 {[%clk 0:03:37.6]} 14. Na3 {[%clk 0:04:39]} 14... f6 {[%clk 0:03:36.5]} 15. O-O {[%clk 0:04:31.9]} 15... fxe5 {[%clk 0:03:33.8]} 16. dxe5 {[%clk 0:04:30.9]} 16... Rxe5 {[%clk 0:03:30.4]} 17. Rfe1 {[%clk 0:04:30.8]} 17... Rxe1+ {[%clk 0:03:17.8]} 18. Rxe1 {[%clk 0:04:30.7]} 18... Nd7 {[%clk 0:03:12.4]} 19. Nc2 {[%clk 0:04:21.9]} 19... Nb6 {[%clk 0:02:35.5]} 20. Nd4 {[%clk 0:04:19.6]} 20... c5 {[%clk 0:02:28.5]} 21. Ne6 {[%clk 0:04:17.8]} 21... Bd2 {[%clk 0:02:24.9]} 22. Re2 {[%clk 0:04:13.9]} 22... Re8 {[%clk 0:02:21]} 23. Kf1 {[%clk 0:04:11.5]} 23... d4 {[%clk 0:01:58.2]} 24. cxd4 {[%clk 0:04:04.2]} 24... cxd4 {[%clk 0:01:54.3]} 25. Nxd4 {[%clk 0:04:03]} 25... Rxe2 {[%clk 0:01:46.3]} 26. Bxe2 {[%clk 0:03:59.8]} 26... Nd5 {[%clk 0:01:41.2]} 27. Bc4 {[%clk 0:03:56.5]} 1-0\n",
 		EndTimestamp: 1659431342,
+		White:        "yayancito24",
+		Black:        "tigran-c-137",
+		WhiteRating:  898,
+		BlackRating:  980,
+		Result:       "1-0",
+		Eco:          "C20",
+		TimeControl:  "300",
+		TimeClass:    "blitz",
+		Rules:        "chess",
 	}
 
 	newGame3 := games.GameRecord{
@@ -117,6 +146,15 @@ func Test_when_archive_is_partially_downloaded_CommitDownloader_should_download_
 		Resource:     "https://www.chess.com/game/live/53170213967",
 		Pgn:          "[Event \"Live Chess\"]\n[Site \"Chess.com\"]\n[Date \"2022.08.02\"]\n[Round \"-\"]\n[White \"philoz87\"]\n[Black \"tigran-c-137\"]\n[Result \"0-1\"]\n[CurrentPosition \"r2q1rk1/ppp2pp1/2n4B/3pP3/2B3b1/8/PPP2PPP/RN3RK1 w - -\"]\n[Timezone \"UTC\"]\n[ECO \"C42\"]\n[ECOUrl \"https://www.chess.com/openings/Petrovs-Defense-Urusov-Gambit\"]\n[UTCDate \"2022.08.02\"]\n[UTCTime \"09:09:05\"]\n[WhiteElo \"999\"]\n[BlackElo \"989\"]\n[TimeControl \"300\"]\n[Termination \"tigran-c-137 won by resignation\"]\n[StartTime \"09:09:05\"]\n[EndDate \"2022.08.02\"]\n[EndTime \"09:10:45\"]\n[Link \"https://www.chess.com/game/live/53170213967\"]\n\n1. e4 {[%clk 0:05:00]} 1... e5 {[%clk 0:04:59.9]} 2. Bc4 {[%clk 0:04:58.1]} 2... Nf6 {[%clk 0:04:55.5]} 3. Nf3 {[%clk 0:04:56.6]} 3... h6 {[%clk 0:04:54.4]} 4. d4 {[%clk 0:04:55.5]} 4... exd4 {[%clk 0:04:49.1]} 5. Nxd4 {[%clk 0:04:52.6]} 5... Bc5 {[%clk 0:04:46.4]} 6. O-O {[%clk 0:04:45.6]} 6... O-O {[%clk 0:04:44.2]} 7. e5 {[%clk 0:04:42.1]} 7... Bxd4 {[%clk 0:04:38.7]} 8. Qxd4 {[%clk 0:04:40.4]} 8... Nc6 {[%clk 0:04:37.6]} 9. Qf4 {[%clk 0:04:30.6]} 9... Ng4 {[%clk 0:04:13.3]} 10. Qxg4 {[%clk 0:04:27.5]} 10... d5 {[%clk 0:04:12.4]} 11. Bxh6 {[%clk 0:04:20.4]} 11... Bxg4 {[%clk 0:04:08.5]} 0-1\n",
 		EndTimestamp: 1659431445,
+		White:        "philoz87",
+		Black:        "tigran-c-137",
+		WhiteRating:  999,
+		BlackRating:  989,
+		Result:       "0-1",
+		Eco:          "C42",
+		TimeControl:  "300",
+		TimeClass:    "blitz",
+		Rules:        "chess",
 	}
 
 	err = downloader.persistGames([]games.GameRecord{existingGame1, existingGame2, existingGame3})
@@ -244,6 +282,15 @@ func Test_when_archive_is_not_downloaded_CommitDownloader_should_download_all_ga
 		Resource:     "https://www.chess.com/game/live/53168947271",
 		Pgn:          "[Event \"Live Chess\"]\n[Site \"Chess.com\"]\n[Date \"2022.08.02\"]\n[Round \"-\"]\n[White \"tigran-c-137\"]\n[Black \"akashvishwakarma000\"]\n[Result \"1-0\"]\n[CurrentPosition \"rn2k1nr/1b1p1ppp/pqp1p3/8/1b1P4/2NBPN2/PPPB1PPP/R2QK2R b KQkq -\"]\n[Timezone \"UTC\"]\n[ECO \"A40\"]\n[ECOUrl \"https://www.chess.com/openings/Queens-Pawn-Opening-Polish-Defense\"]\n[UTCDate \"2022.08.02\"]\n[UTCTime \"08:43:00\"]\n[WhiteElo \"997\"]\n[BlackElo \"917\"]\n[TimeControl \"300\"]\n[Termination \"tigran-c-137 won - game abandoned\"]\n[StartTime \"08:43:00\"]\n[EndDate \"2022.08.02\"]\n[EndTime \"08:45:21\"]\n[Link \"https://www.chess.com/game/live/53168947271\"]\n\n1. d4 {[%clk 0:04:48.8]} 1... b5 {[%clk 0:04:54.6]} 2. Nc3 {[%clk 0:04:40.2]} 2... Bb7 {[%clk 0:04:52.4]} 3. Nxb5 {[%clk 0:04:32.6]} 3... a6 {[%clk 0:04:51.5]} 4. Nc3 {[%clk 0:04:29.1]} 4... c6 {[%clk 0:04:49.9]} 5. e3 {[%clk 0:04:26.4]} 5... Qb6 {[%clk 0:04:37.4]} 6. Nf3 {[%clk 0:04:17.2]} 6... e6 {[%clk 0:04:35.9]} 7. Bd3 {[%clk 0:04:08.4]} 7... Bb4 {[%clk 0:04:35]} 8. Bd2 {[%clk 0:03:48.5]} 1-0\n",
 		EndTimestamp: 1659429921,
+		White:        "tigran-c-137",
+		Black:        "akashvishwakarma000",
+		WhiteRating:  997,
+		BlackRating:  917,
+		Result:       "1-0",
+		Eco:          "A40",
+		TimeControl:  "300",
+		TimeClass:    "blitz",
+		Rules:        "chess",
 	}
 
 	newGame2 := games.GameRecord{
@@ -253,6 +300,15 @@ func Test_when_archive_is_not_downloaded_CommitDownloader_should_download_all_ga
 		Resource:     "https://www.chess.com/game/live/53168974013",
 		Pgn:          "[Event \"Live Chess\"]\n[Site \"Chess.com\"]\n[Date \"2022.08.02\"]\n[Round \"-\"]\n[White \"tigran-c-137\"]\n[Black \"NguoiandanhVN\"]\n[Result \"0-1\"]\n[CurrentPosition \"r3r1k1/2p1nppp/1p2p3/p7/P2P1N2/qP1QP3/5PPP/5RK1 w - -\"]\n[Timezone \"UTC\"]\n[ECO \"D31\"]\n[ECOUrl \"https://www.chess.com/openings/Queens-Gambit-Declined-Queens-Knight-Variation-3...dxc4\"]\n[UTCDate \"2022.08.02\"]\n[UTCTime \"08:45:28\"]\n[WhiteElo \"988\"]\n[BlackElo \"983\"]\n[TimeControl \"300\"]\n[Termination \"NguoiandanhVN won by resignation\"]\n[StartTime \"08:45:28\"]\n[EndDate \"2022.08.02\"]\n[EndTime \"08:49:00\"]\n[Link \"https://www.chess.com/game/live/53168974013\"]\n\n1. d4 {[%clk 0:05:00]} 1... d5 {[%clk 0:04:59.5]} 2. c4 {[%clk 0:04:58.9]} 2... e6 {[%clk 0:04:59.2]} 3. Nc3 {[%clk 0:04:56]} 3... dxc4 {[%clk 0:04:57.9]} 4. e3 {[%clk 0:04:54.8]} 4... Nf6 {[%clk 0:04:55.1]} 5. Bxc4 {[%clk 0:04:47.1]} 5... b6 {[%clk 0:04:53.8]} 6. Nf3 {[%clk 0:04:42.1]} 6... Be7 {[%clk 0:04:52.5]} 7. O-O {[%clk 0:04:34.2]} 7... O-O {[%clk 0:04:50.4]} 8. b3 {[%clk 0:04:22.8]} 8... Nc6 {[%clk 0:04:48.3]} 9. Nb1 {[%clk 0:04:17.6]} 9... a5 {[%clk 0:04:43.2]} 10. a4 {[%clk 0:04:06.8]} 10... Ba6 {[%clk 0:04:39.8]} 11. Nbd2 {[%clk 0:03:49.1]} 11... Bxc4 {[%clk 0:04:37.7]} 12. Nxc4 {[%clk 0:03:47.9]} 12... Nb4 {[%clk 0:04:36.3]} 13. Ba3 {[%clk 0:03:42.1]} 13... Ne4 {[%clk 0:04:32.3]} 14. Nfd2 {[%clk 0:03:33.6]} 14... Nxd2 {[%clk 0:04:30.6]} 15. Nxd2 {[%clk 0:03:27.5]} 15... Qd5 {[%clk 0:04:16.5]} 16. Nf3 {[%clk 0:03:11.3]} 16... Rfe8 {[%clk 0:04:14]} 17. Ne1 {[%clk 0:03:09.6]} 17... Nc6 {[%clk 0:04:06.9]} 18. Nd3 {[%clk 0:02:58.1]} 18... Bxa3 {[%clk 0:04:04.5]} 19. Rxa3 {[%clk 0:02:56.5]} 19... Ne7 {[%clk 0:03:59.6]} 20. Nf4 {[%clk 0:02:54.6]} 20... Qd6 {[%clk 0:03:54]} 21. Qd3 {[%clk 0:02:51.7]} 21... Qxa3 {[%clk 0:03:52.2]} 0-1\n",
 		EndTimestamp: 1659430140,
+		White:        "tigran-c-137",
+		Black:        "NguoiandanhVN",
+		WhiteRating:  988,
+		BlackRating:  983,
+		Result:       "0-1",
+		Eco:          "D31",
+		TimeControl:  "300",
+		TimeClass:    "blitz",
+		Rules:        "chess",
 	}
 
 	newGame3 := games.GameRecord{
@@ -262,6 +318,15 @@ func Test_when_archive_is_not_downloaded_CommitDownloader_should_download_all_ga
 		Resource:     "https://www.chess.com/game/live/53169013011",
 		Pgn:          "[Event \"Live Chess\"]\n[Site \"Chess.com\"]\n[Date \"2022.08.02\"]\n[Round \"-\"]\n[White \"tigran-c-137\"]\n[Black \"VincentVeganin\"]\n[Result \"1-0\"]\n[CurrentPosition \"5Q2/7p/6pk/1R6/p7/2P1P2P/2q2PP1/6K1 b - -\"]\n[Timezone \"UTC\"]\n[ECO \"A40\"]\n[ECOUrl \"https://www.chess.com/openings/Modern-Defense-with-1-d4-2.Nc3-Bg7\"]\n[UTCDate \"2022.08.02\"]\n[UTCTime \"08:49:01\"]\n[WhiteElo \"997\"]\n[BlackElo \"1027\"]\n[TimeControl \"300\"]\n[Termination \"tigran-c-137 won by checkmate\"]\n[StartTime \"08:49:01\"]\n[EndDate \"2022.08.02\"]\n[EndTime \"08:57:23\"]\n[Link \"https://www.chess.com/game/live/53169013011\"]\n\n1. d4 {[%clk 0:05:00]} 1... g6 {[%clk 0:04:59.9]} 2. Nc3 {[%clk 0:04:57.9]} 2... Bg7 {[%clk 0:04:59]} 3. Nf3 {[%clk 0:04:55.2]} 3... Nc6 {[%clk 0:04:57]} 4. e3 {[%clk 0:04:51.4]} 4... d6 {[%clk 0:04:51.6]} 5. Bb5 {[%clk 0:04:49.2]} 5... Bd7 {[%clk 0:04:46.2]} 6. O-O {[%clk 0:04:47.5]} 6... a6 {[%clk 0:04:45.4]} 7. Bxc6 {[%clk 0:04:29.2]} 7... Bxc6 {[%clk 0:04:45.3]} 8. d5 {[%clk 0:04:28.4]} 8... Bxc3 {[%clk 0:04:23.5]} 9. dxc6 {[%clk 0:04:17.4]} 9... Bxb2 {[%clk 0:04:21.5]} 10. Bxb2 {[%clk 0:03:55.5]} 10... Nf6 {[%clk 0:04:17.8]} 11. Bxf6 {[%clk 0:03:51.1]} 11... exf6 {[%clk 0:04:15.3]} 12. cxb7 {[%clk 0:03:50.2]} 12... Rb8 {[%clk 0:04:14.7]} 13. Rb1 {[%clk 0:03:49.4]} 13... O-O {[%clk 0:04:12]} 14. Rb3 {[%clk 0:03:38.7]} 14... Qd7 {[%clk 0:03:52.4]} 15. Qd4 {[%clk 0:03:27.9]} 15... c5 {[%clk 0:03:43.8]} 16. Qxf6 {[%clk 0:03:22.2]} 16... Rxb7 {[%clk 0:03:34.1]} 17. Rxb7 {[%clk 0:03:10.1]} 17... Qxb7 {[%clk 0:03:34]} 18. Qxd6 {[%clk 0:03:08.9]} 18... Rb8 {[%clk 0:03:19.7]} 19. h3 {[%clk 0:03:03.5]} 19... c4 {[%clk 0:03:15.8]} 20. c3 {[%clk 0:02:24.2]} 20... Qb2 {[%clk 0:03:00.8]} 21. Qd2 {[%clk 0:02:19]} 21... Qb6 {[%clk 0:02:38.9]} 22. Qd4 {[%clk 0:02:12.9]} 22... Qb2 {[%clk 0:02:28.9]} 23. Qxc4 {[%clk 0:01:58]} 23... a5 {[%clk 0:02:24.1]} 24. Ne5 {[%clk 0:01:46.6]} 24... Qc2 {[%clk 0:02:12.4]} 25. Qxf7+ {[%clk 0:01:44]} 25... Kh8 {[%clk 0:02:07.1]} 26. Qf6+ {[%clk 0:01:18]} 26... Kg8 {[%clk 0:02:03.9]} 27. Qe6+ {[%clk 0:01:16.5]} 27... Kh8 {[%clk 0:02:01.5]} 28. Nf7+ {[%clk 0:01:13.4]} 28... Kg8 {[%clk 0:01:58]} 29. Nh6+ {[%clk 0:00:59.3]} 29... Kg7 {[%clk 0:01:36.7]} 30. Qe5+ {[%clk 0:00:51.4]} 30... Kxh6 {[%clk 0:01:31.3]} 31. Qxb8 {[%clk 0:00:50.1]} 31... Qxa2 {[%clk 0:01:28.4]} 32. Rb1 {[%clk 0:00:43.8]} 32... a4 {[%clk 0:01:25.1]} 33. Rb5 {[%clk 0:00:41.5]} 33... Qc2 {[%clk 0:01:18]} 34. Qf8# {[%clk 0:00:37.1]} 1-0\n",
 		EndTimestamp: 1659430643,
+		White:        "tigran-c-137",
+		Black:        "VincentVeganin",
+		WhiteRating:  997,
+		BlackRating:  1027,
+		Result:       "1-0",
+		Eco:          "A40",
+		TimeControl:  "300",
+		TimeClass:    "blitz",
+		Rules:        "chess",
 	}
 
 	newGame4 := games.GameRecord{
@@ -271,6 +336,15 @@ func Test_when_archive_is_not_downloaded_CommitDownloader_should_download_all_ga
 		Resource:     "https://www.chess.com/game/live/53169604577",
 		Pgn:          "[Event \"Live Chess\"]\n[Site \"Chess.com\"]\n[Date \"2022.08.02\"]\n[Round \"-\"]\n[White \"N-60\"]\n[Black \"tigran-c-137\"]\n[Result \"1-0\"]\n[CurrentPosition \"5Q1k/pbp3pp/1p6/4P1q1/2B5/8/PPP3PP/R6K b - -\"]\n[Timezone \"UTC\"]\n[ECO \"C34\"]\n[ECOUrl \"https://www.chess.com/openings/Kings-Gambit-Accepted-Schallopp-Defense-4.e5\"]\n[UTCDate \"2022.08.02\"]\n[UTCTime \"08:58:14\"]\n[WhiteElo \"1067\"]\n[BlackElo \"990\"]\n[TimeControl \"300\"]\n[Termination \"N-60 won by checkmate\"]\n[StartTime \"08:58:14\"]\n[EndDate \"2022.08.02\"]\n[EndTime \"09:04:04\"]\n[Link \"https://www.chess.com/game/live/53169604577\"]\n\n1. e4 {[%clk 0:04:57.7]} 1... e5 {[%clk 0:04:58.9]} 2. f4 {[%clk 0:04:54.8]} 2... exf4 {[%clk 0:04:57.2]} 3. Nf3 {[%clk 0:04:53.7]} 3... Nf6 {[%clk 0:04:50.9]} 4. e5 {[%clk 0:04:50.6]} 4... Ng4 {[%clk 0:04:07.6]} 5. d4 {[%clk 0:04:46]} 5... Be7 {[%clk 0:03:50.5]} 6. Bc4 {[%clk 0:04:36.6]} 6... O-O {[%clk 0:03:44.1]} 7. Bxf4 {[%clk 0:04:33.5]} 7... d5 {[%clk 0:03:31.9]} 8. Bd3 {[%clk 0:04:29.3]} 8... f6 {[%clk 0:03:25.3]} 9. O-O {[%clk 0:04:23.3]} 9... fxe5 {[%clk 0:03:20]} 10. Bxe5 {[%clk 0:04:21.6]} 10... Nxe5 {[%clk 0:03:11.7]} 11. Nxe5 {[%clk 0:04:19.3]} 11... Nd7 {[%clk 0:02:53]} 12. Nc3 {[%clk 0:04:10.5]} 12... Nxe5 {[%clk 0:02:50.4]} 13. dxe5 {[%clk 0:04:08.7]} 13... Bc5+ {[%clk 0:02:04.9]} 14. Kh1 {[%clk 0:04:05.3]} 14... b6 {[%clk 0:01:58]} 15. Nxd5 {[%clk 0:04:02.9]} 15... Bb7 {[%clk 0:01:44.7]} 16. Nf4 {[%clk 0:03:51.8]} 16... Rxf4 {[%clk 0:01:19.7]} 17. Rxf4 {[%clk 0:03:46.7]} 17... Qg5 {[%clk 0:01:18.7]} 18. Qf1 {[%clk 0:03:30.9]} 18... Rf8 {[%clk 0:01:06.8]} 19. Rxf8+ {[%clk 0:03:25.7]} 19... Bxf8 {[%clk 0:01:04.9]} 20. Bc4+ {[%clk 0:03:21]} 20... Kh8 {[%clk 0:01:02.4]} 21. Qxf8# {[%clk 0:03:18.1]} 1-0\n",
 		EndTimestamp: 1659431044,
+		White:        "N-60",
+		Black:        "tigran-c-137",
+		WhiteRating:  1067,
+		BlackRating:  990,
+		Result:       "1-0",
+		Eco:          "C34",
+		TimeControl:  "300",
+		TimeClass:    "blitz",
+		Rules:        "chess",
 	}
 
 	newGame5 := games.GameRecord{
@@ -280,6 +354,15 @@ func Test_when_archive_is_not_downloaded_CommitDownloader_should_download_all_ga
 		Resource:     "https://www.chess.com/game/live/53170160741",
 		Pgn:          "[Event \"Live Chess\"]\n[Site \"Chess.com\"]\n[Date \"2022.08.02\"]\n[Round \"-\"]\n[White \"yayancito24\"]\n[Black \"tigran-c-137\"]\n[Result \"1-0\"]\n[CurrentPosition \"6k1/pp4p1/7p/3n4/2BN4/8/PP1b1PPP/5K2 b - -\"]\n[Timezone \"UTC\"]\n[ECO \"C20\"]\n[ECOUrl \"https://www.chess.com/openings/Kings-Pawn-Opening-Wayward-Queen-Kiddie-Countergambit\"]\n[UTCDate \"2022.08.02\"]\n[UTCTime \"09:04:12\"]\n[WhiteElo \"898\"]\n[BlackElo \"980\"]\n[TimeControl \"300\"]\n[Termination \"yayancito24 won by resignation\"]\n[StartTime \"09:04:12\"]\n[EndDate \"2022.08.02\"]\n[EndTime \"09:09:02\"]\n[Link \"https://www.chess.com/game/live/53170160741\"]\n\n1. e4 {[%clk 0:05:00]} 1... e5 {[%clk 0:04:59.9]} 2. Qh5 {[%clk 0:04:59.2]} 2... Nf6 {[%clk 0:04:54.3]} 3. Qxe5+ {[%clk 0:04:57.4]} 3... Qe7 {[%clk 0:04:47.1]} 4. Qxe7+ {[%clk 0:04:56.4]} 4... Bxe7 {[%clk 0:04:45.4]} 5. d3 {[%clk 0:04:56.3]} 5... O-O {[%clk 0:04:43]} 6. Bg5 {[%clk 0:04:55.6]} 6... h6 {[%clk 0:04:39.1]} 7. Bxf6 {[%clk 0:04:54.6]} 7... Bxf6 {[%clk 0:04:38.1]} 8. c3 {[%clk 0:04:53.7]} 8... c6 {[%clk 0:04:32.5]} 9. Nf3 {[%clk 0:04:53]} 9... d5 {[%clk 0:04:30.8]} 10. e5 {[%clk 0:04:48.2]} 10... Re8 {[%clk 0:04:25.5]} 11. d4 {[%clk 0:04:47.1]} 11... Bg4 {[%clk 0:04:10.1]} 12. Be2 {[%clk 0:04:45.3]} 12... Bxf3 {[%clk 0:04:08.3]} 13. Bxf3 {[%clk 0:04:44.3]} 13... Bg5 {[%clk 0:03:37.6]} 14. Na3 {[%clk 0:04:39]} 14... f6 {[%clk 0:03:36.5]} 15. O-O {[%clk 0:04:31.9]} 15... fxe5 {[%clk 0:03:33.8]} 16. dxe5 {[%clk 0:04:30.9]} 16... Rxe5 {[%clk 0:03:30.4]} 17. Rfe1 {[%clk 0:04:30.8]} 17... Rxe1+ {[%clk 0:03:17.8]} 18. Rxe1 {[%clk 0:04:30.7]} 18... Nd7 {[%clk 0:03:12.4]} 19. Nc2 {[%clk 0:04:21.9]} 19... Nb6 {[%clk 0:02:35.5]} 20. Nd4 {[%clk 0:04:19.6]} 20... c5 {[%clk 0:02:28.5]} 21. Ne6 {[%clk 0:04:17.8]} 21... Bd2 {[%clk 0:02:24.9]} 22. Re2 {[%clk 0:04:13.9]} 22... Re8 {[%clk 0:02:21]} 23. Kf1 {[%clk 0:04:11.5]} 23... d4 {[%clk 0:01:58.2]} 24. cxd4 {[%clk 0:04:04.2]} 24... cxd4 {[%clk 0:01:54.3]} 25. Nxd4 {[%clk 0:04:03]} 25... Rxe2 {[%clk 0:01:46.3]} 26. Bxe2 {[%clk 0:03:59.8]} 26... Nd5 {[%clk 0:01:41.2]} 27. Bc4 {[%clk 0:03:56.5]} 1-0\n",
 		EndTimestamp: 1659431342,
+		White:        "yayancito24",
+		Black:        "tigran-c-137",
+		WhiteRating:  898,
+		BlackRating:  980,
+		Result:       "1-0",
+		Eco:          "C20",
+		TimeControl:  "300",
+		TimeClass:    "blitz",
+		Rules:        "chess",
 	}
 
 	newGame6 := games.GameRecord{
@@ -289,6 +372,15 @@ func Test_when_archive_is_not_downloaded_CommitDownloader_should_download_all_ga
 		Resource:     "https://www.chess.com/game/live/53170213967",
 		Pgn:          "[Event \"Live Chess\"]\n[Site \"Chess.com\"]\n[Date \"2022.08.02\"]\n[Round \"-\"]\n[White \"philoz87\"]\n[Black \"tigran-c-137\"]\n[Result \"0-1\"]\n[CurrentPosition \"r2q1rk1/ppp2pp1/2n4B/3pP3/2B3b1/8/PPP2PPP/RN3RK1 w - -\"]\n[Timezone \"UTC\"]\n[ECO \"C42\"]\n[ECOUrl \"https://www.chess.com/openings/Petrovs-Defense-Urusov-Gambit\"]\n[UTCDate \"2022.08.02\"]\n[UTCTime \"09:09:05\"]\n[WhiteElo \"999\"]\n[BlackElo \"989\"]\n[TimeControl \"300\"]\n[Termination \"tigran-c-137 won by resignation\"]\n[StartTime \"09:09:05\"]\n[EndDate \"2022.08.02\"]\n[EndTime \"09:10:45\"]\n[Link \"https://www.chess.com/game/live/53170213967\"]\n\n1. e4 {[%clk 0:05:00]} 1... e5 {[%clk 0:04:59.9]} 2. Bc4 {[%clk 0:04:58.1]} 2... Nf6 {[%clk 0:04:55.5]} 3. Nf3 {[%clk 0:04:56.6]} 3... h6 {[%clk 0:04:54.4]} 4. d4 {[%clk 0:04:55.5]} 4... exd4 {[%clk 0:04:49.1]} 5. Nxd4 {[%clk 0:04:52.6]} 5... Bc5 {[%clk 0:04:46.4]} 6. O-O {[%clk 0:04:45.6]} 6... O-O {[%clk 0:04:44.2]} 7. e5 {[%clk 0:04:42.1]} 7... Bxd4 {[%clk 0:04:38.7]} 8. Qxd4 {[%clk 0:04:40.4]} 8... Nc6 {[%clk 0:04:37.6]} 9. Qf4 {[%clk 0:04:30.6]} 9... Ng4 {[%clk 0:04:13.3]} 10. Qxg4 {[%clk 0:04:27.5]} 10... d5 {[%clk 0:04:12.4]} 11. Bxh6 {[%clk 0:04:20.4]} 11... Bxg4 {[%clk 0:04:08.5]} 0-1\n",
 		EndTimestamp: 1659431445,
+		White:        "philoz87",
+		Black:        "tigran-c-137",
+		WhiteRating:  999,
+		BlackRating:  989,
+		Result:       "0-1",
+		Eco:          "C42",
+		TimeControl:  "300",
+		TimeClass:    "blitz",
+		Rules:        "chess",
 	}
 
 	downloadId := uuid.New().String()
@@ -346,6 +438,8 @@ func Test_when_archive_is_not_downloaded_CommitDownloader_should_download_all_ga
 	assert.Equal(t, 6, actualArchive.Downloaded)
 	assert.True(t, startOfChecking.After(actualArchive.DownloadedAt.ToTime()))
 	assert.True(t, startOfTest.Before(actualArchive.DownloadedAt.ToTime()))
+	assert.Equal(t, chessDotComArchiveETag, actualArchive.ETag)
+	assert.Equal(t, chessDotComArchiveLastModified, actualArchive.LastModified)
 
 	actualGames, err := downloader.getAllGames(userId)
 	assert.NoError(t, err)
@@ -375,45 +469,592 @@ func Test_when_archive_is_not_downloaded_CommitDownloader_should_download_all_ga
 
 	assert.Equal(t, expectedDownload, actualDownload)
 
+	actualArchiveOutcome, err := downloader.getArchiveOutcome(downloadId, archiveId)
+	assert.NoError(t, err)
+
+	assert.Equal(t, downloads.ArchiveSucceeded, actualArchiveOutcome.State)
+	assert.Equal(t, "", actualArchiveOutcome.ErrorCode)
+	assert.Equal(t, 6, actualArchiveOutcome.GamesAdded)
+
 	verifyDownloadedCall, err := wiremockClient.Verify(stubDownload.Request(), 1)
 	assert.NoError(t, err)
-	assert.True(t, verifyDownloadedCall)
+	assert.True(t, verifyDownloadedCall)
+}
+
+func Test_when_archive_is_fully_downloaded_CommitDownloader_should_skip_the_process(t *testing.T) {
+	defer wiremockClient.Reset()
+
+	var err error
+	username := uuid.New().String()
+	userId := uuid.New().String()
+
+	archiveResource := fmt.Sprintf("http://0.0.0.0:18443/pub/player/%s/2022/08", username)
+	archiveId := archiveResource
+	assert.NoError(t, err)
+
+	lastDownloadedAt := db.Zuludatetime(time.Date(2023, 8, 2, 8, 45, 21, 0, time.UTC))
+
+	archiveRecord := archives.ArchiveRecord{
+		UserId:       userId,
+		ArchiveId:    archiveId,
+		Resource:     archiveResource,
+		Year:         2022,
+		Month:        8,
+		DownloadedAt: &lastDownloadedAt,
+		Downloaded:   6,
+	}
+
+	err = downloader.persistArchive(archiveRecord)
+	assert.NoError(t, err)
+
+	downloadId := uuid.New().String()
+	downloadRecord := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Succeed:    3,
+		Failed:     0,
+		Done:       3,
+		Pending:    2,
+		Total:      5,
+	}
+
+	err = downloader.persistDownload(downloadRecord)
+	assert.NoError(t, err)
+
+	stubDownload, err := downloader.stubChessDotCom(username, "2022", "08")
+	assert.NoError(t, err)
+
+	err = wiremockClient.StubFor(stubDownload)
+	assert.NoError(t, err)
+
+	command :=
+		events.SQSMessage{
+			Body: fmt.Sprintf(
+				`
+				{
+					"username": "%s",
+					"userId": "%s",
+					"platform": "CHESS_DOT_COM",
+					"archiveId": "%s",
+					"downloadId": "%s",
+					"hehe": "💣"
+				}
+			`,
+				username,
+				userId,
+				archiveId,
+				downloadId,
+			),
+			MessageId: "1",
+		}
+
+	actualCommandsProcessed, err := downloader.Download(events.SQSEvent{Records: []events.SQSMessage{command}})
+	assert.NoError(t, err)
+	expectedCommandsProcessed := events.SQSEventResponse{
+		BatchItemFailures: nil,
+	}
+	assert.Equal(t, expectedCommandsProcessed, actualCommandsProcessed)
+
+	actualArchive, err := downloader.getArchive(userId, archiveId)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 6, actualArchive.Downloaded)
+	assert.Equal(t, lastDownloadedAt, *actualArchive.DownloadedAt)
+
+	actualDownload, err := downloader.getDownload(downloadId)
+	assert.NoError(t, err)
+
+	expectedDownload := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Succeed:    4,
+		Failed:     0,
+		Done:       4,
+		Pending:    1,
+		Total:      5,
+	}
+
+	assert.Equal(t, expectedDownload, actualDownload)
+
+	verifyDownloadedCall, err := wiremockClient.Verify(stubDownload.Request(), 0)
+	assert.NoError(t, err)
+	assert.True(t, verifyDownloadedCall)
+}
+
+func Test_when_archive_can_not_be_counted_CommitDownloader_should_redeliver_the_command(t *testing.T) {
+	var err error
+	username := uuid.New().String()
+	userId := uuid.New().String()
+
+	archiveResource := fmt.Sprintf("http://0.0.0.0:18443/pub/player/%s/2022/08", username)
+	archiveId := archiveResource
+
+	lastDownloadedAt := db.Zuludatetime(time.Date(2023, 8, 2, 8, 45, 21, 0, time.UTC))
+
+	archiveRecord := archives.ArchiveRecord{
+		UserId:       userId,
+		ArchiveId:    archiveId,
+		Resource:     archiveResource,
+		Year:         2022,
+		Month:        8,
+		DownloadedAt: &lastDownloadedAt,
+		Downloaded:   6,
+	}
+
+	err = downloader.persistArchive(archiveRecord)
+	assert.NoError(t, err)
+
+	downloadId := uuid.New().String()
+	downloadRecord := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Succeed:    3,
+		Failed:     0,
+		Done:       3,
+		Pending:    2,
+		Total:      5,
+	}
+
+	err = downloader.persistDownload(downloadRecord)
+	assert.NoError(t, err)
+
+	command := events.SQSMessage{
+		Body: fmt.Sprintf(
+			`{"username": "%s", "userId": "%s", "platform": "CHESS_DOT_COM", "archiveId": "%s", "downloadId": "%s"}`,
+			username,
+			userId,
+			archiveId,
+			downloadId,
+		),
+		MessageId: "1",
+		Attributes: map[string]string{
+			"ApproximateReceiveCount": "1",
+		},
+	}
+
+	// the outcomes of the archives can not be stored, so the archive can not be counted either
+	downloaderWithoutOutcomes := downloader
+	downloaderWithoutOutcomes.archiveOutcomesTableName = "chessfinder_dynamodb-missing"
+
+	actualCommandsProcessed, err := downloaderWithoutOutcomes.Download(events.SQSEvent{Records: []events.SQSMessage{command}})
+	assert.NoError(t, err)
+	expectedCommandsProcessed := events.SQSEventResponse{
+		BatchItemFailures: []events.SQSBatchItemFailure{{ItemIdentifier: "1"}},
+	}
+	assert.Equal(t, expectedCommandsProcessed, actualCommandsProcessed)
+
+	actualDownload, err := downloader.getDownload(downloadId)
+	assert.NoError(t, err)
+	assert.Equal(t, downloadRecord, actualDownload)
+
+	actualCommandsProcessed, err = downloader.Download(events.SQSEvent{Records: []events.SQSMessage{command}})
+	assert.NoError(t, err)
+	assert.Equal(t, events.SQSEventResponse{BatchItemFailures: nil}, actualCommandsProcessed)
+
+	actualDownload, err = downloader.getDownload(downloadId)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, actualDownload.Succeed)
+	assert.Equal(t, 1, actualDownload.Pending)
+}
+
+func Test_when_command_is_delivered_twice_CommitDownloader_should_count_the_archive_once(t *testing.T) {
+	defer wiremockClient.Reset()
+
+	var err error
+	username := uuid.New().String()
+	userId := uuid.New().String()
+
+	archiveResource := fmt.Sprintf("http://0.0.0.0:18443/pub/player/%s/2022/08", username)
+	archiveId := archiveResource
+
+	lastDownloadedAt := db.Zuludatetime(time.Date(2023, 8, 2, 8, 45, 21, 0, time.UTC))
+
+	archiveRecord := archives.ArchiveRecord{
+		UserId:       userId,
+		ArchiveId:    archiveId,
+		Resource:     archiveResource,
+		Year:         2022,
+		Month:        8,
+		DownloadedAt: &lastDownloadedAt,
+		Downloaded:   6,
+	}
+
+	err = downloader.persistArchive(archiveRecord)
+	assert.NoError(t, err)
+
+	downloadId := uuid.New().String()
+	downloadRecord := downloads.NewDownloadRecord(downloadId, 2)
+
+	err = downloader.persistDownload(downloadRecord)
+	assert.NoError(t, err)
+
+	command :=
+		events.SQSMessage{
+			Body: fmt.Sprintf(
+				`
+				{
+					"username": "%s",
+					"userId": "%s",
+					"platform": "CHESS_DOT_COM",
+					"archiveId": "%s",
+					"downloadId": "%s"
+				}
+			`,
+				username,
+				userId,
+				archiveId,
+				downloadId,
+			),
+			MessageId: "1",
+		}
+
+	redeliveredCommand := command
+	redeliveredCommand.MessageId = "2"
+
+	actualCommandsProcessed, err := downloader.Download(events.SQSEvent{Records: []events.SQSMessage{command, redeliveredCommand}})
+	assert.NoError(t, err)
+	assert.Equal(t, events.SQSEventResponse{BatchItemFailures: nil}, actualCommandsProcessed)
+
+	actualDownload, err := downloader.getDownload(downloadId)
+	assert.NoError(t, err)
+
+	expectedDownload := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Succeed:    1,
+		Failed:     0,
+		Done:       1,
+		Pending:    1,
+		Total:      2,
+	}
+
+	assert.Equal(t, expectedDownload, actualDownload)
+}
+
+func Test_when_the_last_archive_is_counted_CommitDownloader_should_enqueue_the_callback_of_the_download_once(t *testing.T) {
+	defer wiremockClient.Reset()
+
+	var err error
+	username := uuid.New().String()
+	userId := uuid.New().String()
+
+	archiveResource := fmt.Sprintf("http://0.0.0.0:18443/pub/player/%s/2022/08", username)
+	archiveId := archiveResource
+
+	lastDownloadedAt := db.Zuludatetime(time.Date(2023, 8, 2, 8, 45, 21, 0, time.UTC))
+
+	archiveRecord := archives.ArchiveRecord{
+		UserId:       userId,
+		ArchiveId:    archiveId,
+		Resource:     archiveResource,
+		Year:         2022,
+		Month:        8,
+		DownloadedAt: &lastDownloadedAt,
+		Downloaded:   6,
+	}
+
+	err = downloader.persistArchive(archiveRecord)
+	assert.NoError(t, err)
+
+	downloadId := uuid.New().String()
+	downloadRecord := downloads.NewDownloadRecord(downloadId, 1)
+	downloadRecord.CallbackUrl = "https://example.com/chessfinder/done"
+	downloadRecord.CallbackSecret = "secret"
+
+	err = downloader.persistDownload(downloadRecord)
+	assert.NoError(t, err)
+
+	command :=
+		events.SQSMessage{
+			Body: fmt.Sprintf(
+				`
+				{
+					"username": "%s",
+					"userId": "%s",
+					"platform": "CHESS_DOT_COM",
+					"archiveId": "%s",
+					"downloadId": "%s"
+				}
+			`,
+				username,
+				userId,
+				archiveId,
+				downloadId,
+			),
+			MessageId: "1",
+		}
+
+	redeliveredCommand := command
+	redeliveredCommand.MessageId = "2"
+
+	actualCommandsProcessed, err := downloader.Download(events.SQSEvent{Records: []events.SQSMessage{command, redeliveredCommand}})
+	assert.NoError(t, err)
+	assert.Equal(t, events.SQSEventResponse{BatchItemFailures: nil}, actualCommandsProcessed)
+
+	actualDownload, err := downloader.getDownload(downloadId)
+	assert.NoError(t, err)
+	assert.True(t, actualDownload.Notified)
+
+	actualCallbacks, err := downloader.receiveCallbacks(downloadId)
+	assert.NoError(t, err)
+
+	expectedCallbacks := []queue.DeliverCallbackCommand{
+		{
+			CallbackUrl:    "https://example.com/chessfinder/done",
+			CallbackSecret: "secret",
+			Payload:        json.RawMessage(fmt.Sprintf(`{"event":"DOWNLOAD_COMPLETED","downloadId":"%s","failed":0,"succeed":1,"cancelled":0,"done":1,"pending":0,"total":1}`, downloadId)),
+		},
+	}
+
+	assert.Equal(t, expectedCallbacks, actualCallbacks)
+}
+
+func Test_when_download_is_cancelled_CommitDownloader_should_skip_the_archive_and_count_it_as_cancelled(t *testing.T) {
+	defer wiremockClient.Reset()
+
+	var err error
+	username := uuid.New().String()
+	userId := uuid.New().String()
+
+	archiveResource := fmt.Sprintf("http://0.0.0.0:18443/pub/player/%s/2022/08", username)
+	archiveId := archiveResource
+
+	archiveRecord := archives.ArchiveRecord{
+		UserId:     userId,
+		ArchiveId:  archiveId,
+		Resource:   archiveResource,
+		Year:       2022,
+		Month:      8,
+		Downloaded: 0,
+	}
+
+	err = downloader.persistArchive(archiveRecord)
+	assert.NoError(t, err)
+
+	downloadId := uuid.New().String()
+	downloadRecord := downloads.DownloadRecord{
+		DownloadId:      downloadId,
+		Succeed:         3,
+		Failed:          0,
+		Done:            3,
+		Pending:         2,
+		Total:           5,
+		CancelRequested: true,
+	}
+
+	err = downloader.persistDownload(downloadRecord)
+	assert.NoError(t, err)
+
+	stubDownload, err := downloader.stubChessDotCom(username, "2022", "08")
+	assert.NoError(t, err)
+
+	err = wiremockClient.StubFor(stubDownload)
+	assert.NoError(t, err)
+
+	command :=
+		events.SQSMessage{
+			Body: fmt.Sprintf(
+				`
+				{
+					"username": "%s",
+					"userId": "%s",
+					"platform": "CHESS_DOT_COM",
+					"archiveId": "%s",
+					"downloadId": "%s"
+				}
+			`,
+				username,
+				userId,
+				archiveId,
+				downloadId,
+			),
+			MessageId: "1",
+		}
+
+	actualCommandsProcessed, err := downloader.Download(events.SQSEvent{Records: []events.SQSMessage{command}})
+	assert.NoError(t, err)
+	expectedCommandsProcessed := events.SQSEventResponse{
+		BatchItemFailures: nil,
+	}
+	assert.Equal(t, expectedCommandsProcessed, actualCommandsProcessed)
+
+	actualArchive, err := downloader.getArchive(userId, archiveId)
+	assert.NoError(t, err)
+	assert.Equal(t, archiveRecord, actualArchive)
+
+	actualDownload, err := downloader.getDownload(downloadId)
+	assert.NoError(t, err)
+
+	expectedDownload := downloads.DownloadRecord{
+		DownloadId:      downloadId,
+		Succeed:         3,
+		Failed:          0,
+		Cancelled:       1,
+		Done:            4,
+		Pending:         1,
+		Total:           5,
+		CancelRequested: true,
+	}
+
+	assert.Equal(t, expectedDownload, actualDownload)
+
+	actualArchiveOutcome, err := downloader.getArchiveOutcome(downloadId, archiveId)
+	assert.NoError(t, err)
+
+	assert.Equal(t, downloads.ArchiveCancelled, actualArchiveOutcome.State)
+	assert.Equal(t, 0, actualArchiveOutcome.GamesAdded)
+
+	verifyDownloadedCall, err := wiremockClient.Verify(stubDownload.Request(), 0)
+	assert.NoError(t, err)
+	assert.True(t, verifyDownloadedCall)
+}
+
+func Test_when_archive_is_not_modified_CommitDownloader_should_only_mark_it_as_downloaded(t *testing.T) {
+	defer wiremockClient.Reset()
+
+	startOfTest := time.Now().UTC()
+
+	var err error
+	username := uuid.New().String()
+	userId := uuid.New().String()
+	archiveId := uuid.New().String()
+
+	lastDownloadedAt := db.Zuludatetime(time.Date(2022, 8, 20, 8, 45, 21, 0, time.UTC))
+
+	archiveRecord := archives.ArchiveRecord{
+		UserId:       userId,
+		ArchiveId:    archiveId,
+		Resource:     archiveId,
+		Year:         2022,
+		Month:        8,
+		DownloadedAt: &lastDownloadedAt,
+		Downloaded:   6,
+		ETag:         chessDotComArchiveETag,
+		LastModified: chessDotComArchiveLastModified,
+	}
+
+	err = downloader.persistArchive(archiveRecord)
+	assert.NoError(t, err)
+
+	downloadId := uuid.New().String()
+	downloadRecord := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Succeed:    3,
+		Failed:     0,
+		Done:       3,
+		Pending:    2,
+		Total:      5,
+	}
+
+	err = downloader.persistDownload(downloadRecord)
+	assert.NoError(t, err)
+
+	stubNotModified := wiremock.Get(wiremock.URLPathEqualTo(fmt.Sprintf("/pub/player/%s/games/2022/08", username))).
+		WithHeader("If-None-Match", wiremock.EqualTo(chessDotComArchiveETag)).
+		WithHeader("If-Modified-Since", wiremock.EqualTo(chessDotComArchiveLastModified)).
+		WillReturnResponse(
+			wiremock.NewResponse().
+				WithStatus(http.StatusNotModified),
+		)
+
+	err = wiremockClient.StubFor(stubNotModified)
+	assert.NoError(t, err)
+
+	command :=
+		events.SQSMessage{
+			Body: fmt.Sprintf(
+				`
+				{
+					"username": "%s",
+					"userId": "%s",
+					"platform": "CHESS_DOT_COM",
+					"archiveId": "%s",
+					"downloadId": "%s"
+				}
+			`,
+				username,
+				userId,
+				archiveId,
+				downloadId,
+			),
+			MessageId: "1",
+		}
+
+	actualCommandsProcessed, err := downloader.Download(events.SQSEvent{Records: []events.SQSMessage{command}})
+	assert.NoError(t, err)
+	expectedCommandsProcessed := events.SQSEventResponse{
+		BatchItemFailures: nil,
+	}
+	assert.Equal(t, expectedCommandsProcessed, actualCommandsProcessed)
+
+	actualArchive, err := downloader.getArchive(userId, archiveId)
+	assert.NoError(t, err)
+
+	startOfChecking := time.Now().UTC()
+
+	assert.Equal(t, 6, actualArchive.Downloaded)
+	assert.Equal(t, chessDotComArchiveETag, actualArchive.ETag)
+	assert.True(t, startOfChecking.After(actualArchive.DownloadedAt.ToTime()))
+	assert.True(t, startOfTest.Before(actualArchive.DownloadedAt.ToTime()))
+
+	actualGames, err := downloader.getAllGames(userId)
+	assert.NoError(t, err)
+	assert.Empty(t, actualGames)
+
+	actualDownload, err := downloader.getDownload(downloadId)
+	assert.NoError(t, err)
+
+	expectedDownload := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Succeed:    4,
+		Failed:     0,
+		Done:       4,
+		Pending:    1,
+		Total:      5,
+	}
+
+	assert.Equal(t, expectedDownload, actualDownload)
+
+	verifyNotModifiedCall, err := wiremockClient.Verify(stubNotModified.Request(), 1)
+	assert.NoError(t, err)
+	assert.True(t, verifyNotModifiedCall)
 }
 
-func Test_when_archive_is_fully_downloaded_CommitDownloader_should_skip_the_process(t *testing.T) {
+func Test_when_games_in_the_middle_of_archive_are_missing_CommitDownloader_should_download_exactly_them(t *testing.T) {
 	defer wiremockClient.Reset()
 
 	var err error
 	username := uuid.New().String()
 	userId := uuid.New().String()
+	archiveId := uuid.New().String()
 
-	archiveResource := fmt.Sprintf("http://0.0.0.0:18443/pub/player/%s/2022/08", username)
-	archiveId := archiveResource
+	allGames, err := downloader.chessDotComArchiveGames(userId, archiveId)
 	assert.NoError(t, err)
+	assert.Equal(t, 6, len(allGames))
 
-	lastDownloadedAt := db.Zuludatetime(time.Date(2023, 8, 2, 8, 45, 21, 0, time.UTC))
+	lastDownloadedAt := db.Zuludatetime(time.Date(2022, 8, 2, 9, 15, 0, 0, time.UTC))
 
+	// the latest game is already stored, so any end timestamp based watermark would skip the games in between
 	archiveRecord := archives.ArchiveRecord{
 		UserId:       userId,
 		ArchiveId:    archiveId,
-		Resource:     archiveResource,
+		Resource:     archiveId,
 		Year:         2022,
 		Month:        8,
 		DownloadedAt: &lastDownloadedAt,
-		Downloaded:   6,
+		Downloaded:   2,
 	}
 
 	err = downloader.persistArchive(archiveRecord)
 	assert.NoError(t, err)
 
+	err = downloader.persistGames([]games.GameRecord{allGames[0], allGames[5]})
+	assert.NoError(t, err)
+
 	downloadId := uuid.New().String()
 	downloadRecord := downloads.DownloadRecord{
 		DownloadId: downloadId,
-		Succeed:    3,
+		Succeed:    0,
 		Failed:     0,
-		Done:       3,
-		Pending:    2,
-		Total:      5,
+		Done:       0,
+		Pending:    1,
+		Total:      1,
 	}
 
 	err = downloader.persistDownload(downloadRecord)
@@ -434,8 +1075,7 @@ func Test_when_archive_is_fully_downloaded_CommitDownloader_should_skip_the_proc
 					"userId": "%s",
 					"platform": "CHESS_DOT_COM",
 					"archiveId": "%s",
-					"downloadId": "%s",
-					"hehe": "💣"
+					"downloadId": "%s"
 				}
 			`,
 				username,
@@ -455,27 +1095,25 @@ func Test_when_archive_is_fully_downloaded_CommitDownloader_should_skip_the_proc
 
 	actualArchive, err := downloader.getArchive(userId, archiveId)
 	assert.NoError(t, err)
-
 	assert.Equal(t, 6, actualArchive.Downloaded)
-	assert.Equal(t, lastDownloadedAt, *actualArchive.DownloadedAt)
+
+	actualGames, err := downloader.getAllGames(userId)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, allGames, actualGames)
 
 	actualDownload, err := downloader.getDownload(downloadId)
 	assert.NoError(t, err)
 
 	expectedDownload := downloads.DownloadRecord{
 		DownloadId: downloadId,
-		Succeed:    4,
+		Succeed:    1,
 		Failed:     0,
-		Done:       4,
-		Pending:    1,
-		Total:      5,
+		Done:       1,
+		Pending:    0,
+		Total:      1,
 	}
 
 	assert.Equal(t, expectedDownload, actualDownload)
-
-	verifyDownloadedCall, err := wiremockClient.Verify(stubDownload.Request(), 0)
-	assert.NoError(t, err)
-	assert.True(t, verifyDownloadedCall)
 }
 
 func Test_when_archive_does_not_exists_CommitDownloader_should_skip_the_process(t *testing.T) {
@@ -552,6 +1190,218 @@ func Test_when_archive_does_not_exists_CommitDownloader_should_skip_the_process(
 	assert.True(t, verifyDownloadedCall)
 }
 
+func Test_when_lichess_archive_is_not_downloaded_CommitDownloader_should_stream_all_games_of_the_month(t *testing.T) {
+	defer wiremockClient.Reset()
+
+	var err error
+	username := uuid.New().String()
+	userId := uuid.New().String()
+	archiveId := uuid.New().String()
+
+	archiveRecord := archives.ArchiveRecord{
+		UserId:       userId,
+		ArchiveId:    archiveId,
+		Resource:     archiveId,
+		Year:         2022,
+		Month:        8,
+		DownloadedAt: nil,
+		Downloaded:   0,
+	}
+
+	err = downloader.persistArchive(archiveRecord)
+	assert.NoError(t, err)
+
+	downloadId := uuid.New().String()
+	downloadRecord := downloads.NewDownloadRecord(downloadId, 1)
+
+	err = downloader.persistDownload(downloadRecord)
+	assert.NoError(t, err)
+
+	stubDownload, lichessGames, err := downloader.stubLichess(username, "1659312000000", "1661990399999")
+	assert.NoError(t, err)
+
+	err = wiremockClient.StubFor(stubDownload)
+	assert.NoError(t, err)
+
+	command :=
+		events.SQSMessage{
+			Body: fmt.Sprintf(
+				`
+				{
+					"username": "%s",
+					"userId": "%s",
+					"platform": "LICHESS",
+					"archiveId": "%s",
+					"downloadId": "%s"
+				}
+			`,
+				username,
+				userId,
+				archiveId,
+				downloadId,
+			),
+			MessageId: "1",
+		}
+
+	actualCommandsProcessed, err := downloader.Download(events.SQSEvent{Records: []events.SQSMessage{command}})
+	assert.NoError(t, err)
+	assert.Equal(t, events.SQSEventResponse{BatchItemFailures: nil}, actualCommandsProcessed)
+
+	actualArchive, err := downloader.getArchive(userId, archiveId)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, actualArchive.Downloaded)
+
+	// the structured fields of the games in testdata/lichess_2022-08_few_games.ndjson
+	expectedStructuredFields := map[string]games.GameRecord{
+		"3Uz4VYxq": {White: "Nezhmetdinov", Black: "tigran-c-137", WhiteRating: 1701, BlackRating: 1709, Result: "0-1", Eco: "C40"},
+		"q7ZvsdUF": {White: "tigran-c-137", Black: "Nezhmetdinov", WhiteRating: 1712, BlackRating: 1698, Result: "1-0", Eco: "C20"},
+	}
+
+	expectedGames := []games.GameRecord{}
+	for _, lichessGame := range lichessGames {
+		structuredFields := expectedStructuredFields[lichessGame.Id]
+		expectedGames = append(expectedGames, games.GameRecord{
+			UserId:       userId,
+			ArchiveId:    archiveId,
+			GameId:       "https://lichess.org/" + lichessGame.Id,
+			Resource:     "https://lichess.org/" + lichessGame.Id,
+			Pgn:          lichessGame.Pgn,
+			EndTimestamp: lichessGame.LastMoveAt / 1000,
+			White:        structuredFields.White,
+			Black:        structuredFields.Black,
+			WhiteRating:  structuredFields.WhiteRating,
+			BlackRating:  structuredFields.BlackRating,
+			Result:       structuredFields.Result,
+			Eco:          structuredFields.Eco,
+			TimeControl:  "300+0",
+			TimeClass:    games.Blitz,
+			Rules:        games.StandardRules,
+		})
+	}
+
+	actualGames, err := downloader.getAllGames(userId)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, expectedGames, actualGames)
+
+	actualDownload, err := downloader.getDownload(downloadId)
+	assert.NoError(t, err)
+
+	expectedDownload := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Succeed:    1,
+		Failed:     0,
+		Done:       1,
+		Pending:    0,
+		Total:      1,
+	}
+
+	assert.Equal(t, expectedDownload, actualDownload)
+
+	verifyDownloadedCall, err := wiremockClient.Verify(stubDownload.Request(), 1)
+	assert.NoError(t, err)
+	assert.True(t, verifyDownloadedCall)
+}
+
+func Test_when_lichess_is_unavailable_CommitDownloader_should_redeliver_the_command_and_record_the_archive_as_failed_after_the_last_receive(t *testing.T) {
+	defer wiremockClient.Reset()
+
+	var err error
+	username := uuid.New().String()
+	userId := uuid.New().String()
+	archiveId := uuid.New().String()
+
+	archiveRecord := archives.ArchiveRecord{
+		UserId:       userId,
+		ArchiveId:    archiveId,
+		Resource:     archiveId,
+		Year:         2022,
+		Month:        8,
+		DownloadedAt: nil,
+		Downloaded:   0,
+	}
+
+	err = downloader.persistArchive(archiveRecord)
+	assert.NoError(t, err)
+
+	downloadId := uuid.New().String()
+	downloadRecord := downloads.NewDownloadRecord(downloadId, 1)
+
+	err = downloader.persistDownload(downloadRecord)
+	assert.NoError(t, err)
+
+	stubDownload := wiremock.Get(wiremock.URLPathEqualTo(fmt.Sprintf("/api/games/user/%s", username))).
+		WillReturnResponse(
+			wiremock.NewResponse().
+				WithStatus(http.StatusServiceUnavailable),
+		)
+
+	err = wiremockClient.StubFor(stubDownload)
+	assert.NoError(t, err)
+
+	command :=
+		events.SQSMessage{
+			Body: fmt.Sprintf(
+				`
+				{
+					"username": "%s",
+					"userId": "%s",
+					"platform": "LICHESS",
+					"archiveId": "%s",
+					"downloadId": "%s"
+				}
+			`,
+				username,
+				userId,
+				archiveId,
+				downloadId,
+			),
+			MessageId: "1",
+			Attributes: map[string]string{
+				"ApproximateReceiveCount": "1",
+				"MessageGroupId":          userId,
+			},
+		}
+
+	expectedCommandsProcessed := events.SQSEventResponse{
+		BatchItemFailures: []events.SQSBatchItemFailure{{ItemIdentifier: "1"}},
+	}
+
+	actualCommandsProcessed, err := downloader.Download(events.SQSEvent{Records: []events.SQSMessage{command}})
+	assert.NoError(t, err)
+	assert.Equal(t, expectedCommandsProcessed, actualCommandsProcessed)
+
+	actualDownload, err := downloader.getDownload(downloadId)
+	assert.NoError(t, err)
+	assert.Equal(t, downloadRecord, actualDownload, "download must not be counted before the last receive")
+
+	command.Attributes["ApproximateReceiveCount"] = strconv.Itoa(queue.MaxReceiveCount)
+
+	actualCommandsProcessed, err = downloader.Download(events.SQSEvent{Records: []events.SQSMessage{command}})
+	assert.NoError(t, err)
+	assert.Equal(t, expectedCommandsProcessed, actualCommandsProcessed)
+
+	actualDownload, err = downloader.getDownload(downloadId)
+	assert.NoError(t, err)
+
+	expectedDownload := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Succeed:    0,
+		Failed:     1,
+		Done:       1,
+		Pending:    0,
+		Total:      1,
+	}
+
+	assert.Equal(t, expectedDownload, actualDownload)
+
+	actualArchiveOutcome, err := downloader.getArchiveOutcome(downloadId, archiveId)
+	assert.NoError(t, err)
+
+	assert.Equal(t, downloads.ArchiveFailed, actualArchiveOutcome.State)
+	assert.Equal(t, "SOURCE_UNAVAILABLE", actualArchiveOutcome.ErrorCode)
+	assert.Equal(t, 0, actualArchiveOutcome.GamesAdded)
+}
+
 func (downloader *GameDownloader) persistArchive(archive archives.ArchiveRecord) (err error) {
 	archiveMarshalledItems, err := dynamodbattribute.MarshalMap(archive)
 	if err != nil {
@@ -651,6 +1501,106 @@ func (downloader *GameDownloader) getDownload(downloadId string) (download downl
 	return
 }
 
+// receiveCallbacks drains the callbacks of the download from the queue shared with the other tests
+func (downloader *GameDownloader) receiveCallbacks(downloadId string) (callbacks []queue.DeliverCallbackCommand, err error) {
+	svc := sqs.New(awsSession)
+	for attempt := 0; attempt < 3; attempt++ {
+		var receiveMessageOutput *sqs.ReceiveMessageOutput
+		receiveMessageOutput, err = svc.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(downloader.deliverCallbacksQueueUrl),
+			MaxNumberOfMessages: aws.Int64(10),
+			WaitTimeSeconds:     aws.Int64(1),
+		})
+		if err != nil {
+			return
+		}
+		for _, message := range receiveMessageOutput.Messages {
+			command := queue.DeliverCallbackCommand{}
+			err = json.Unmarshal([]byte(*message.Body), &command)
+			if err != nil {
+				return
+			}
+			if !bytes.Contains(command.Payload, []byte(downloadId)) {
+				continue
+			}
+			callbacks = append(callbacks, command)
+			_, err = svc.DeleteMessage(&sqs.DeleteMessageInput{
+				QueueUrl:      aws.String(downloader.deliverCallbacksQueueUrl),
+				ReceiptHandle: message.ReceiptHandle,
+			})
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func (downloader *GameDownloader) getArchiveOutcome(downloadId string, archiveId string) (archiveOutcome downloads.ArchiveOutcomeRecord, err error) {
+	getItemInput := &dynamodb.GetItemInput{
+		TableName: aws.String(downloader.archiveOutcomesTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"download_id": {
+				S: aws.String(downloadId),
+			},
+			"archive_id": {
+				S: aws.String(archiveId),
+			},
+		},
+	}
+	var getItemOutput *dynamodb.GetItemOutput
+	getItemOutput, err = dynamodbClient.GetItem(getItemInput)
+	if err != nil {
+		return
+	}
+	err = dynamodbattribute.UnmarshalMap(getItemOutput.Item, &archiveOutcome)
+	return
+}
+
+func (downloader GameDownloader) chessDotComArchiveGames(userId string, archiveId string) (gameRecords []games.GameRecord, err error) {
+	data, err := os.ReadFile("testdata/2022-08_few_games.json")
+	if err != nil {
+		return
+	}
+
+	chessDotComArchive := struct {
+		Games []chessdotcom.Game `json:"games"`
+	}{}
+	err = json.Unmarshal(data, &chessDotComArchive)
+	if err != nil {
+		return
+	}
+
+	for _, chessDotComGame := range chessDotComArchive.Games {
+		var parsedPgn pgn.Game
+		parsedPgn, err = pgn.Parse(chessDotComGame.Pgn)
+		if err != nil {
+			return
+		}
+		gameRecords = append(gameRecords, games.GameRecord{
+			UserId:       userId,
+			ArchiveId:    archiveId,
+			GameId:       chessDotComGame.Url,
+			Resource:     chessDotComGame.Url,
+			Pgn:          chessDotComGame.Pgn,
+			EndTimestamp: chessDotComGame.EndTime,
+			White:        chessDotComGame.White.Username,
+			Black:        chessDotComGame.Black.Username,
+			WhiteRating:  chessDotComGame.White.Rating,
+			BlackRating:  chessDotComGame.Black.Rating,
+			Result:       parsedPgn.Tag("Result"),
+			Eco:          parsedPgn.Tag("ECO"),
+			TimeControl:  chessDotComGame.TimeControl,
+			TimeClass:    chessDotComGame.TimeClass,
+			Rules:        chessDotComGame.Rules,
+		})
+	}
+	return
+}
+
+const chessDotComArchiveETag = `"2022-08-few-games"`
+const chessDotComArchiveLastModified = "Wed, 31 Aug 2022 23:59:59 GMT"
+
 func (downloader GameDownloader) stubChessDotCom(username string, year string, month string) (rule *wiremock.StubRule, err error) {
 	file, err := os.Open("testdata/2022-08_few_games.json")
 	if err != nil {
@@ -670,7 +1620,39 @@ func (downloader GameDownloader) stubChessDotCom(username string, year string, m
 			wiremock.NewResponse().
 				WithStatus(http.StatusOK).
 				WithHeader("Content-Type", "application/json").
+				WithHeader("ETag", chessDotComArchiveETag).
+				WithHeader("Last-Modified", chessDotComArchiveLastModified).
 				WithBody(responseString),
 		)
 	return
 }
+
+func (downloader GameDownloader) stubLichess(username string, since string, until string) (rule *wiremock.StubRule, lichessGames []lichess.Game, err error) {
+	data, err := os.ReadFile("testdata/lichess_2022-08_few_games.ndjson")
+	if err != nil {
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		lichessGame := lichess.Game{}
+		err = decoder.Decode(&lichessGame)
+		if err != nil {
+			return
+		}
+		lichessGames = append(lichessGames, lichessGame)
+	}
+
+	rule = wiremock.Get(wiremock.URLPathEqualTo(fmt.Sprintf("/api/games/user/%s", username))).
+		WithQueryParam("since", wiremock.EqualTo(since)).
+		WithQueryParam("until", wiremock.EqualTo(until)).
+		WithQueryParam("pgnInJson", wiremock.EqualTo("true")).
+		WithHeader("Accept", wiremock.EqualTo("application/x-ndjson")).
+		WillReturnResponse(
+			wiremock.NewResponse().
+				WithStatus(http.StatusOK).
+				WithHeader("Content-Type", "application/x-ndjson").
+				WithBody(string(data)),
+		)
+	return
+}
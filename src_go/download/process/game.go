@@ -1,11 +1,62 @@
 package main
 
-type ChessDotComGames struct {
-	Games []ChessDotComGame `json:"games"`
+import (
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/lichess"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/pgn"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+)
+
+// lichess speeds named like the time classes of chess.com, which has no separate classes for the slowest and the fastest games
+var lichessTimeClasses = map[string]string{
+	"ultraBullet":    games.Bullet,
+	"bullet":         games.Bullet,
+	"blitz":          games.Blitz,
+	"rapid":          games.Rapid,
+	"classical":      games.Rapid,
+	"correspondence": games.Daily,
+}
+
+func chessDotComGameRecord(command queue.DownloadGamesCommand, chessDotComGame chessdotcom.Game) games.GameRecord {
+	gameRecord := games.GameRecord{
+		UserId:       command.UserId,
+		ArchiveId:    command.ArchiveId,
+		GameId:       chessDotComGame.Url,
+		Resource:     chessDotComGame.Url,
+		Pgn:          chessDotComGame.Pgn,
+		EndTimestamp: chessDotComGame.EndTime,
+		White:        chessDotComGame.White.Username,
+		Black:        chessDotComGame.Black.Username,
+		WhiteRating:  chessDotComGame.White.Rating,
+		BlackRating:  chessDotComGame.Black.Rating,
+		TimeControl:  chessDotComGame.TimeControl,
+		TimeClass:    chessDotComGame.TimeClass,
+		Rules:        chessDotComGame.Rules,
+	}
+	return withPgnTags(gameRecord)
+}
+
+func lichessGameRecord(command queue.DownloadGamesCommand, lichessGame lichess.Game) games.GameRecord {
+	gameUrl := lichess.SiteUrl + "/" + lichessGame.Id
+	gameRecord := games.GameRecord{
+		UserId:       command.UserId,
+		ArchiveId:    command.ArchiveId,
+		GameId:       gameUrl,
+		Resource:     gameUrl,
+		Pgn:          lichessGame.Pgn,
+		EndTimestamp: lichessGame.LastMoveAt / 1000,
+		TimeClass:    lichessTimeClasses[lichessGame.Speed],
+	}
+	if lichessGame.Variant != "" {
+		gameRecord.Rules = games.RulesOf(lichessGame.Variant)
+	}
+	return withPgnTags(gameRecord)
 }
 
-type ChessDotComGame struct {
-	Url     string `json:"url"`
-	Pgn     string `json:"pgn"`
-	EndTime int64  `json:"end_time"`
+// the result and the opening are known only from the pgn, a pgn that can not be parsed completely
+// still gives the tags that were read before the failure, so they are used anyway
+func withPgnTags(gameRecord games.GameRecord) games.GameRecord {
+	parsedPgn, _ := pgn.Parse(gameRecord.Pgn)
+	return gameRecord.WithPgnTags(parsedPgn.Tags)
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/lichess"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_chessDotComGameRecord_should_prefer_the_api_fields_over_the_pgn_tags(t *testing.T) {
+	command := queue.DownloadGamesCommand{UserId: "user", ArchiveId: "archive"}
+	chessDotComGame := chessdotcom.Game{
+		Url:         "https://www.chess.com/game/daily/1",
+		Pgn:         "[White \"tigran-c-137\"]\n[Black \"Garry\"]\n[Result \"1/2-1/2\"]\n[ECO \"C42\"]\n[WhiteElo \"1200\"]\n[TimeControl \"1/259200\"]\n\n1. e4 e5 1/2-1/2\n",
+		EndTime:     1659431445,
+		TimeControl: "1/259200",
+		TimeClass:   "daily",
+		Rules:       "chess960",
+		White:       chessdotcom.Player{Username: "Tigran-C-137", Rating: 1250},
+		Black:       chessdotcom.Player{Username: "Garry", Rating: 2851},
+	}
+
+	actualGame := chessDotComGameRecord(command, chessDotComGame)
+
+	expectedGame := games.GameRecord{
+		UserId:       "user",
+		ArchiveId:    "archive",
+		GameId:       "https://www.chess.com/game/daily/1",
+		Resource:     "https://www.chess.com/game/daily/1",
+		Pgn:          chessDotComGame.Pgn,
+		EndTimestamp: 1659431445,
+		White:        "Tigran-C-137",
+		Black:        "Garry",
+		WhiteRating:  1250,
+		BlackRating:  2851,
+		Result:       "1/2-1/2",
+		Eco:          "C42",
+		TimeControl:  "1/259200",
+		TimeClass:    games.Daily,
+		Rules:        "chess960",
+	}
+
+	assert.Equal(t, expectedGame, actualGame)
+}
+
+func Test_lichessGameRecord_should_name_speeds_and_variants_like_chess_dot_com(t *testing.T) {
+	command := queue.DownloadGamesCommand{UserId: "user", ArchiveId: "archive"}
+	lichessGame := lichess.Game{
+		Id:         "q7ZvsdUF",
+		Pgn:        "[White \"tigran-c-137\"]\n[Black \"Nezhmetdinov\"]\n[Result \"0-1\"]\n[Variant \"King of the Hill\"]\n[TimeControl \"1800+30\"]\n\n1. e4 e5 0-1\n",
+		LastMoveAt: 1659429821000,
+		Variant:    "kingOfTheHill",
+		Speed:      "classical",
+	}
+
+	actualGame := lichessGameRecord(command, lichessGame)
+
+	assert.Equal(t, "https://lichess.org/q7ZvsdUF", actualGame.GameId)
+	assert.Equal(t, int64(1659429821), actualGame.EndTimestamp)
+	assert.Equal(t, "tigran-c-137", actualGame.White)
+	assert.Equal(t, "Nezhmetdinov", actualGame.Black)
+	assert.Equal(t, "0-1", actualGame.Result)
+	assert.Equal(t, "1800+30", actualGame.TimeControl)
+	assert.Equal(t, games.Rapid, actualGame.TimeClass)
+	assert.Equal(t, "kingofthehill", actualGame.Rules)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
+	"go.uber.org/zap"
+)
+
+const maxGamesPerBatch = 25
+
+// gameWriter persists games in batches as soon as a batch is full,
+// so the memory consumption does not depend on the size of the archive
+type gameWriter struct {
+	dynamodbClient *dynamodb.DynamoDB
+	gamesTableName string
+	logger         *zap.Logger
+	batch          []*dynamodb.WriteRequest
+	batches        int
+	written        int
+}
+
+func newGameWriter(dynamodbClient *dynamodb.DynamoDB, gamesTableName string, logger *zap.Logger) *gameWriter {
+	return &gameWriter{
+		dynamodbClient: dynamodbClient,
+		gamesTableName: gamesTableName,
+		logger:         logger,
+		batch:          make([]*dynamodb.WriteRequest, 0, maxGamesPerBatch),
+	}
+}
+
+func (writer *gameWriter) add(gameRecord games.GameRecord) (err error) {
+	gameRecordItems, err := dynamodbattribute.MarshalMap(gameRecord)
+	if err != nil {
+		writer.logger.Error("impossible to marshal the missing game record", zap.Error(err))
+		return
+	}
+
+	writer.batch = append(writer.batch, &dynamodb.WriteRequest{
+		PutRequest: &dynamodb.PutRequest{
+			Item: gameRecordItems,
+		},
+	})
+
+	if len(writer.batch) >= maxGamesPerBatch {
+		err = writer.flush()
+	}
+	return
+}
+
+func (writer *gameWriter) flush() (err error) {
+	if len(writer.batch) == 0 {
+		return
+	}
+
+	writer.batches++
+	logger := writer.logger.With(zap.Int("batchNumber", writer.batches), zap.Int("batchSize", len(writer.batch)))
+	logger.Info("trying to persist a batch of missing games")
+
+	unprocessedWriteRequests := map[string][]*dynamodb.WriteRequest{
+		writer.gamesTableName: writer.batch,
+	}
+
+	for len(unprocessedWriteRequests) > 0 {
+		logger.Info("trying to persist missing games in one iteration")
+		var writeOutput *dynamodb.BatchWriteItemOutput
+		writeOutput, err = writer.dynamodbClient.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+			RequestItems: unprocessedWriteRequests,
+		})
+
+		if err != nil {
+			logger.Error("impossible to persist the missing game records", zap.Error(err))
+			return
+		}
+
+		unprocessedWriteRequests = writeOutput.UnprocessedItems
+		if len(unprocessedWriteRequests) > 0 {
+			time.Sleep(time.Millisecond * 100)
+		}
+	}
+
+	writer.written += len(writer.batch)
+	writer.batch = writer.batch[:0]
+	return
+}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"time"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/lichess"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
 )
 
 func main() {
@@ -16,11 +20,41 @@ func main() {
 		panic(errors.New("CHESS_DOT_COM_URL is missing"))
 	}
 
+	chessDotComConfig := chessdotcom.DefaultConfig(chessDotComUrl)
+	chessDotComTimeout, chessDotComTimeoutExists := os.LookupEnv("CHESS_DOT_COM_TIMEOUT")
+	if chessDotComTimeoutExists {
+		timeout, err := time.ParseDuration(chessDotComTimeout)
+		if err != nil {
+			panic(fmt.Errorf("CHESS_DOT_COM_TIMEOUT is invalid: %w", err))
+		}
+		chessDotComConfig.Timeout = timeout
+	}
+
+	lichessUrl, lichessUrlExists := os.LookupEnv("LICHESS_URL")
+	if !lichessUrlExists {
+		panic(errors.New("LICHESS_URL is missing"))
+	}
+
+	lichessConfig := lichess.DefaultConfig(lichessUrl)
+	lichessTimeout, lichessTimeoutExists := os.LookupEnv("LICHESS_TIMEOUT")
+	if lichessTimeoutExists {
+		timeout, err := time.ParseDuration(lichessTimeout)
+		if err != nil {
+			panic(fmt.Errorf("LICHESS_TIMEOUT is invalid: %w", err))
+		}
+		lichessConfig.Timeout = timeout
+	}
+
 	downloadsTableName, downloadsTableNameExists := os.LookupEnv("DOWNLOADS_TABLE_NAME")
 	if !downloadsTableNameExists {
 		panic(errors.New("DOWNLOADS_TABLE_NAME is missing"))
 	}
 
+	archiveOutcomesTableName, archiveOutcomesTableNameExists := os.LookupEnv("ARCHIVE_OUTCOMES_TABLE_NAME")
+	if !archiveOutcomesTableNameExists {
+		panic(errors.New("ARCHIVE_OUTCOMES_TABLE_NAME is missing"))
+	}
+
 	archivesTableName, archivesTableNameExists := os.LookupEnv("ARCHIVES_TABLE_NAME")
 	if !archivesTableNameExists {
 		panic(errors.New("ARCHIVES_TABLE_NAME is missing"))
@@ -31,9 +65,14 @@ func main() {
 		panic(errors.New("GAMES_TABLE_NAME is missing"))
 	}
 
-	gamesByEndTimestampIndexName, gamesByEndTimestampIndexNameExists := os.LookupEnv("GAMES_BY_END_TIMESTAMP_INDEX_NAME")
-	if !gamesByEndTimestampIndexNameExists {
-		panic(errors.New("GAMES_BY_END_TIMESTAMP_INDEX_NAME is missing"))
+	gamesByArchiveIndexName, gamesByArchiveIndexNameExists := os.LookupEnv("GAMES_BY_ARCHIVE_INDEX_NAME")
+	if !gamesByArchiveIndexNameExists {
+		panic(errors.New("GAMES_BY_ARCHIVE_INDEX_NAME is missing"))
+	}
+
+	deliverCallbacksQueueUrl, deliverCallbacksQueueUrlExists := os.LookupEnv("DELIVER_CALLBACKS_QUEUE_URL")
+	if !deliverCallbacksQueueUrlExists {
+		panic(errors.New("DELIVER_CALLBACKS_QUEUE_URL is missing"))
 	}
 
 	awsRegion, awsRegionExists := os.LookupEnv("AWS_REGION")
@@ -42,23 +81,19 @@ func main() {
 	}
 
 	downloader := GameDownloader{
-		chessDotComUrl:               chessDotComUrl,
-		downloadsTableName:           downloadsTableName,
-		archivesTableName:            archivesTableName,
-		gamesTableName:               gamesTableName,
-		gamesByEndTimestampIndexName: gamesByEndTimestampIndexName,
+		chessDotComConfig:        chessDotComConfig,
+		lichessConfig:            lichessConfig,
+		downloadsTableName:       downloadsTableName,
+		archiveOutcomesTableName: archiveOutcomesTableName,
+		archivesTableName:        archivesTableName,
+		gamesTableName:           gamesTableName,
+		gamesByArchiveIndexName:  gamesByArchiveIndexName,
+		deliverCallbacksQueueUrl: deliverCallbacksQueueUrl,
 		awsConfig: &aws.Config{
 			Region: &awsRegion,
 		},
 	}
 
-	lambda.Start(sealErrors(downloader.Download))
+	lambda.Start(queue.SealErrors(downloader.Download))
 
 }
-
-func sealErrors(unsafeHandling func(events.SQSEvent) (events.SQSEventResponse, error)) func(events.SQSEvent) (events.SQSEventResponse, error) {
-	return func(commands events.SQSEvent) (commandsProcessed events.SQSEventResponse, err error) {
-		_, _ = unsafeHandling(commands)
-		return
-	}
-}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/callback"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/downloads"
+	"go.uber.org/zap"
+)
+
+// notifyIfCompleted hands the final status of a completed download over to the deliverer of the callbacks,
+// only one of the workers counting the last archives sends it
+func (downloader *GameDownloader) notifyIfCompleted(
+	dynamodbClient *dynamodb.DynamoDB,
+	svc *sqs.SQS,
+	logger *zap.Logger,
+	downloadId string,
+) (err error) {
+	claim := callback.Claim{
+		TableName: downloader.downloadsTableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"download_id": {
+				S: aws.String(downloadId),
+			},
+		},
+		Condition: "done = total",
+	}
+
+	err = callback.NotifyOnce(dynamodbClient, svc, logger, downloader.deliverCallbacksQueueUrl, claim, func(record map[string]*dynamodb.AttributeValue) (callbackUrl string, callbackSecret string, payload any, err error) {
+		downloadRecord := downloads.DownloadRecord{}
+		err = dynamodbattribute.UnmarshalMap(record, &downloadRecord)
+		if err != nil {
+			return
+		}
+
+		callbackUrl = downloadRecord.CallbackUrl
+		callbackSecret = downloadRecord.CallbackSecret
+		payload = callback.DownloadCompleted{
+			Event:      callback.DownloadCompletedEvent,
+			DownloadId: downloadRecord.DownloadId,
+			Failed:     downloadRecord.Failed,
+			Succeed:    downloadRecord.Succeed,
+			Cancelled:  downloadRecord.Cancelled,
+			Done:       downloadRecord.Done,
+			Pending:    downloadRecord.Pending,
+			Total:      downloadRecord.Total,
+		}
+		return
+	})
+	return
+}
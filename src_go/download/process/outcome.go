@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/lichess"
+)
+
+// errorCode is what the UI shows for a failed archive, the details of the error stay in the logs
+func errorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var awsErr awserr.Error
+	var urlErr *url.Error
+	switch {
+	case errors.Is(err, chessdotcom.ErrRateLimited),
+		errors.Is(err, lichess.ErrRateLimited):
+		return "RATE_LIMITED"
+	case errors.Is(err, chessdotcom.ErrServerFailure),
+		errors.Is(err, chessdotcom.ErrUnexpectedStatus),
+		errors.Is(err, chessdotcom.ErrStalled),
+		errors.Is(err, lichess.ErrServerFailure),
+		errors.Is(err, lichess.ErrUnexpectedStatus),
+		errors.Is(err, lichess.ErrStalled),
+		errors.As(err, &urlErr):
+		return "SOURCE_UNAVAILABLE"
+	case errors.As(err, &awsErr):
+		return "STORAGE_FAILURE"
+	default:
+		return "INTERNAL_ERROR"
+	}
+}
+
+// isRetryable tells whether the same command can succeed later, otherwise it fails for good on the first attempt
+func isRetryable(err error) bool {
+	var awsErr awserr.Error
+	var urlErr *url.Error
+	switch {
+	case errors.Is(err, chessdotcom.ErrRateLimited),
+		errors.Is(err, chessdotcom.ErrServerFailure),
+		errors.Is(err, chessdotcom.ErrStalled),
+		errors.Is(err, lichess.ErrRateLimited),
+		errors.Is(err, lichess.ErrServerFailure),
+		errors.Is(err, lichess.ErrStalled),
+		errors.As(err, &urlErr):
+		return true
+	case errors.As(err, &awsErr):
+		return awsErr.Code() != "ValidationException"
+	default:
+		return false
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/lichess"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_errorCode_should_tell_where_the_archive_failed(t *testing.T) {
+	assert.Equal(t, "", errorCode(nil))
+	assert.Equal(t, "RATE_LIMITED", errorCode(chessdotcom.ErrRateLimited))
+	assert.Equal(t, "SOURCE_UNAVAILABLE", errorCode(fmt.Errorf("%w: 503", chessdotcom.ErrServerFailure)))
+	assert.Equal(t, "SOURCE_UNAVAILABLE", errorCode(fmt.Errorf("%w: context canceled", chessdotcom.ErrStalled)))
+	assert.Equal(t, "RATE_LIMITED", errorCode(lichess.ErrRateLimited))
+	assert.Equal(t, "SOURCE_UNAVAILABLE", errorCode(fmt.Errorf("%w: 400", lichess.ErrUnexpectedStatus)))
+	assert.Equal(t, "SOURCE_UNAVAILABLE", errorCode(fmt.Errorf("%w: context canceled", lichess.ErrStalled)))
+	assert.Equal(t, "SOURCE_UNAVAILABLE", errorCode(&url.Error{Op: "Get", URL: "https://lichess.org", Err: errors.New("connection reset")}))
+	assert.Equal(t, "STORAGE_FAILURE", errorCode(awserr.New("ProvisionedThroughputExceededException", "slow down", nil)))
+	assert.Equal(t, "INTERNAL_ERROR", errorCode(errors.New("boom")))
+}
+
+func Test_isRetryable_should_retry_only_failures_that_can_pass(t *testing.T) {
+	assert.True(t, isRetryable(chessdotcom.ErrRateLimited))
+	assert.True(t, isRetryable(fmt.Errorf("%w: 502", chessdotcom.ErrServerFailure)))
+	assert.True(t, isRetryable(fmt.Errorf("%w: context canceled", chessdotcom.ErrStalled)))
+	assert.True(t, isRetryable(lichess.ErrRateLimited))
+	assert.True(t, isRetryable(fmt.Errorf("%w: 503", lichess.ErrServerFailure)))
+	assert.True(t, isRetryable(fmt.Errorf("%w: context canceled", lichess.ErrStalled)))
+	assert.True(t, isRetryable(&url.Error{Op: "Get", URL: "https://lichess.org", Err: errors.New("connection reset")}))
+	assert.True(t, isRetryable(awserr.New("ProvisionedThroughputExceededException", "slow down", nil)))
+
+	assert.False(t, isRetryable(awserr.New("ValidationException", "item is too large", nil)))
+	assert.False(t, isRetryable(fmt.Errorf("%w: 400", chessdotcom.ErrUnexpectedStatus)))
+	assert.False(t, isRetryable(fmt.Errorf("%w: 400", lichess.ErrUnexpectedStatus)))
+	assert.False(t, isRetryable(errors.New("invalid character in the archive")))
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+)
+
+func main() {
+	downloadsTableName, downloadsTableNameExists := os.LookupEnv("DOWNLOADS_TABLE_NAME")
+	if !downloadsTableNameExists {
+		panic(errors.New("DOWNLOADS_TABLE_NAME is missing"))
+	}
+
+	archiveOutcomesTableName, archiveOutcomesTableNameExists := os.LookupEnv("ARCHIVE_OUTCOMES_TABLE_NAME")
+	if !archiveOutcomesTableNameExists {
+		panic(errors.New("ARCHIVE_OUTCOMES_TABLE_NAME is missing"))
+	}
+
+	downloadGamesQueueUrl, downloadGamesQueueUrlExists := os.LookupEnv("DOWNLOAD_GAMES_QUEUE_URL")
+	if !downloadGamesQueueUrlExists {
+		panic(errors.New("DOWNLOAD_GAMES_QUEUE_URL is missing"))
+	}
+
+	awsRegion, awsRegionExists := os.LookupEnv("AWS_REGION")
+	if !awsRegionExists {
+		panic(errors.New("AWS_REGION is missing"))
+	}
+
+	retrier := DownloadRetrier{
+		downloadsTableName:       downloadsTableName,
+		archiveOutcomesTableName: archiveOutcomesTableName,
+		downloadGamesQueueUrl:    downloadGamesQueueUrl,
+		awsConfig: &aws.Config{
+			Region: &awsRegion,
+		},
+	}
+
+	lambda.Start(api.WithRecover(retrier.Retry))
+}
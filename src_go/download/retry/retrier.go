@@ -0,0 +1,356 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/downloads"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type DownloadRetrier struct {
+	awsConfig                *aws.Config
+	downloadsTableName       string
+	archiveOutcomesTableName string
+	downloadGamesQueueUrl    string
+}
+
+// Retry queues again only the archives that failed under the download, they are counted as pending again
+func (retrier *DownloadRetrier) Retry(event *events.APIGatewayV2HTTPRequest) (responseEvent events.APIGatewayV2HTTPResponse, err error) {
+
+	config := zap.NewProductionConfig()
+	config.OutputPaths = []string{"stdout"}
+	timeEncoder := func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.UTC().Format("2006-01-02T15:04:05.000Z"))
+	} // Log to stdout
+	config.EncoderConfig.EncodeTime = timeEncoder
+
+	// Create the logger from the configuration
+	logger, err := config.Build()
+	if err != nil {
+		panic(err)
+	}
+	logger = logger.With(zap.String("requestId", event.RequestContext.RequestID))
+	defer logger.Sync()
+
+	awsSession, err := session.NewSession(retrier.awsConfig)
+	if err != nil {
+		logger.Panic("impossible to create an AWS session!", zap.Error(err))
+	}
+
+	dynamodbClient := dynamodb.New(awsSession)
+	svc := sqs.New(awsSession)
+
+	if event.RouteKey != "POST /api/faster/game/{downloadId}/retry" {
+		logger.Panic("download retrier is attached to a wrong route!")
+	}
+
+	downloadId, downloadIdExists := event.PathParameters["downloadId"]
+	if !downloadIdExists || downloadId == "" {
+		err = api.ValidationError{
+			Msg: "path parameter downloadId is missing",
+		}
+		return
+	}
+
+	logger = logger.With(zap.String("downloadId", downloadId))
+
+	downloadItems, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"download_id": {
+				S: aws.String(downloadId),
+			},
+		},
+		TableName: aws.String(retrier.downloadsTableName),
+	})
+	if err != nil {
+		logger.Error("faild to get download record", zap.Error(err))
+		err = api.ServiceOverloaded
+		return
+	}
+
+	if downloadItems.Item == nil || len(downloadItems.Item) == 0 {
+		logger.Error("no dowload request found!")
+		err = api.DownloadNotFound(downloadId)
+		return
+	}
+
+	downloadRecord := downloads.DownloadRecord{}
+	err = dynamodbattribute.UnmarshalMap(downloadItems.Item, &downloadRecord)
+	if err != nil {
+		logger.Error("faild to unmarshal download record", zap.Error(err))
+		return
+	}
+
+	if downloadRecord.CancelRequested {
+		logger.Info("download is cancelled, its archives are not retried")
+		err = DownloadIsCancelled(downloadId)
+		return
+	}
+
+	failedArchives, err := retrier.getFailedArchives(dynamodbClient, logger, downloadId)
+	if err != nil {
+		err = api.ServiceOverloaded
+		return
+	}
+
+	retried := 0
+	for _, failedArchive := range failedArchives {
+		logger := logger.With(zap.String("archiveId", failedArchive.ArchiveId))
+		var isRetried bool
+		isRetried, err = retrier.retryArchive(dynamodbClient, svc, logger, failedArchive)
+		if err != nil {
+			err = api.ServiceOverloaded
+			return
+		}
+		if isRetried {
+			retried++
+		}
+	}
+
+	logger.Info("failed archives are queued again", zap.Int("retried", retried))
+
+	responseBody, err := json.Marshal(RetryResponse{DownloadId: downloadId, Retried: retried})
+	if err != nil {
+		logger.Error("faild to marshal retry response", zap.Error(err))
+		return
+	}
+	responseEvent = events.APIGatewayV2HTTPResponse{
+		Body:       string(responseBody),
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}
+	return
+}
+
+func (retrier *DownloadRetrier) getFailedArchives(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	downloadId string,
+) (failedArchives []downloads.ArchiveOutcomeRecord, err error) {
+	var lastKey map[string]*dynamodb.AttributeValue
+	for {
+		var response *dynamodb.QueryOutput
+		response, err = dynamodbClient.Query(&dynamodb.QueryInput{
+			TableName:              aws.String(retrier.archiveOutcomesTableName),
+			KeyConditionExpression: aws.String("download_id = :download_id"),
+			FilterExpression:       aws.String("#state = :failed"),
+			ExpressionAttributeNames: map[string]*string{
+				"#state": aws.String("state"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":download_id": {
+					S: aws.String(downloadId),
+				},
+				":failed": {
+					S: aws.String(string(downloads.ArchiveFailed)),
+				},
+			},
+			ExclusiveStartKey: lastKey,
+		})
+		if err != nil {
+			logger.Error("faild to query failed archives", zap.Error(err))
+			return
+		}
+
+		pageOfFailedArchives := []downloads.ArchiveOutcomeRecord{}
+		err = dynamodbattribute.UnmarshalListOfMaps(response.Items, &pageOfFailedArchives)
+		if err != nil {
+			logger.Error("faild to unmarshal failed archives", zap.Error(err))
+			return
+		}
+		failedArchives = append(failedArchives, pageOfFailedArchives...)
+
+		lastKey = response.LastEvaluatedKey
+		if len(lastKey) == 0 {
+			break
+		}
+	}
+	return
+}
+
+// retryArchive moves one failed archive back to pending, the archive is claimed first,
+// so concurrent retries of the same download never queue the archive or count it twice
+func (retrier *DownloadRetrier) retryArchive(
+	dynamodbClient *dynamodb.DynamoDB,
+	svc *sqs.SQS,
+	logger *zap.Logger,
+	failedArchive downloads.ArchiveOutcomeRecord,
+) (isRetried bool, err error) {
+	isClaimed, err := retrier.changeArchiveState(dynamodbClient, logger, failedArchive, downloads.ArchiveFailed, downloads.ArchiveRetrying)
+	if err != nil || !isClaimed {
+		return
+	}
+
+	wasNotified, isMoved, err := retrier.moveFromFailedToPending(dynamodbClient, logger, failedArchive.DownloadId)
+	if err != nil || !isMoved {
+		_, _ = retrier.changeArchiveState(dynamodbClient, logger, failedArchive, downloads.ArchiveRetrying, downloads.ArchiveFailed)
+		return
+	}
+
+	err = queue.SendDownloadGamesCommand(svc, retrier.downloadGamesQueueUrl, queue.DownloadGamesCommand{
+		Username:   failedArchive.Username,
+		Platform:   queue.Platform(failedArchive.Platform),
+		ArchiveId:  failedArchive.ArchiveId,
+		UserId:     failedArchive.UserId,
+		DownloadId: failedArchive.DownloadId,
+	})
+	if err != nil {
+		logger.Error("impossible to publish the download game command!", zap.Error(err))
+		_ = retrier.moveFromPendingToFailed(dynamodbClient, logger, failedArchive.DownloadId, wasNotified)
+		_, _ = retrier.changeArchiveState(dynamodbClient, logger, failedArchive, downloads.ArchiveRetrying, downloads.ArchiveFailed)
+		return
+	}
+
+	isRetried = true
+	return
+}
+
+func (retrier *DownloadRetrier) changeArchiveState(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	archive downloads.ArchiveOutcomeRecord,
+	from downloads.ArchiveState,
+	to downloads.ArchiveState,
+) (isChanged bool, err error) {
+	_, err = dynamodbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(retrier.archiveOutcomesTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"download_id": {
+				S: aws.String(archive.DownloadId),
+			},
+			"archive_id": {
+				S: aws.String(archive.ArchiveId),
+			},
+		},
+		UpdateExpression:    aws.String("SET #state = :to"),
+		ConditionExpression: aws.String("#state = :from"),
+		ExpressionAttributeNames: map[string]*string{
+			"#state": aws.String("state"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":from": {
+				S: aws.String(string(from)),
+			},
+			":to": {
+				S: aws.String(string(to)),
+			},
+		},
+	})
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		logger.Info("archive is not in the expected state anymore", zap.String("expectedState", string(from)))
+		err = nil
+		return
+	}
+
+	if err != nil {
+		logger.Error("impossible to change the state of the archive", zap.Error(err))
+		return
+	}
+
+	isChanged = true
+	return
+}
+
+// moveFromFailedToPending counts one failed archive as pending again, the download is notified again once it is counted.
+// A download cancelled meanwhile is left as it is, wasNotified tells how to restore the download if the archive can not be queued
+func (retrier *DownloadRetrier) moveFromFailedToPending(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	downloadId string,
+) (wasNotified bool, isMoved bool, err error) {
+	updateOutput, err := dynamodbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(retrier.downloadsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"download_id": {
+				S: aws.String(downloadId),
+			},
+		},
+		UpdateExpression:    aws.String("ADD failed :decrement, done :decrement, pending :increment REMOVE notified"),
+		ConditionExpression: aws.String("attribute_not_exists(cancel_requested) OR cancel_requested = :false"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":decrement": {
+				N: aws.String("-1"),
+			},
+			":increment": {
+				N: aws.String("1"),
+			},
+			":false": {
+				BOOL: aws.Bool(false),
+			},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueUpdatedOld),
+	})
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		logger.Info("download is cancelled meanwhile, the archive is not retried")
+		err = nil
+		return
+	}
+
+	if err != nil {
+		logger.Error("impossible to update the counters of the download", zap.Error(err))
+		return
+	}
+
+	notified, exists := updateOutput.Attributes["notified"]
+	wasNotified = exists && aws.BoolValue(notified.BOOL)
+	isMoved = true
+	return
+}
+
+// moveFromPendingToFailed undoes moveFromFailedToPending, including the notified flag it removed
+func (retrier *DownloadRetrier) moveFromPendingToFailed(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	downloadId string,
+	wasNotified bool,
+) (err error) {
+	updateExpression := "ADD failed :increment, done :increment, pending :decrement"
+	expressionAttributeValues := map[string]*dynamodb.AttributeValue{
+		":decrement": {
+			N: aws.String("-1"),
+		},
+		":increment": {
+			N: aws.String("1"),
+		},
+	}
+	if wasNotified {
+		updateExpression += " SET notified = :notified"
+		expressionAttributeValues[":notified"] = &dynamodb.AttributeValue{
+			BOOL: aws.Bool(true),
+		}
+	}
+
+	_, err = dynamodbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(retrier.downloadsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"download_id": {
+				S: aws.String(downloadId),
+			},
+		},
+		UpdateExpression:          aws.String(updateExpression),
+		ExpressionAttributeValues: expressionAttributeValues,
+	})
+	if err != nil {
+		logger.Error("impossible to restore the counters of the download", zap.Error(err))
+		return
+	}
+	return
+}
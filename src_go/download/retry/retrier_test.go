@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/downloads"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/users"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var awsConfig = aws.Config{
+	Region:     aws.String("us-east-1"),
+	Endpoint:   aws.String("http://localhost:4566"), // this is the LocalStack endpoint for all services
+	DisableSSL: aws.Bool(true),
+}
+
+var retrier = DownloadRetrier{
+	awsConfig:                &awsConfig,
+	downloadsTableName:       "chessfinder_dynamodb-downloads",
+	archiveOutcomesTableName: "chessfinder_dynamodb-archiveOutcomes",
+	downloadGamesQueueUrl:    "http://localhost:4566/000000000000/chessfinder_sqs-DownloadGames.fifo",
+}
+
+var awsSession = session.Must(session.NewSession(&awsConfig))
+var dynamodbClient = dynamodb.New(awsSession)
+var svc = sqs.New(awsSession)
+
+func Test_only_failed_archives_are_queued_again_under_the_same_download(t *testing.T) {
+	var err error
+	downloadId := uuid.New().String()
+	userId := uuid.New().String()
+	username := uuid.New().String()
+
+	event := retryEvent(downloadId)
+
+	downloadRecord := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Succeed:    1,
+		Failed:     2,
+		Done:       3,
+		Pending:    0,
+		Total:      3,
+		Notified:   true,
+	}
+	err = retrier.persistDownload(downloadRecord)
+	assert.NoError(t, err)
+
+	archiveOutcome := func(month string, state downloads.ArchiveState) downloads.ArchiveOutcomeRecord {
+		return downloads.ArchiveOutcomeRecord{
+			DownloadId: downloadId,
+			ArchiveId:  fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2022/%v", username, month),
+			UserId:     userId,
+			Username:   username,
+			Platform:   users.ChessDotCom,
+			State:      state,
+		}
+	}
+	succeededArchive := archiveOutcome("06", downloads.ArchiveSucceeded)
+	failedArchive1 := archiveOutcome("07", downloads.ArchiveFailed)
+	failedArchive2 := archiveOutcome("08", downloads.ArchiveFailed)
+	for _, archive := range []downloads.ArchiveOutcomeRecord{succeededArchive, failedArchive1, failedArchive2} {
+		err = retrier.persistArchiveOutcome(archive)
+		assert.NoError(t, err)
+	}
+
+	actualResponse, err := retrier.Retry(&event)
+	assert.NoError(t, err)
+
+	expectedResponseBody := fmt.Sprintf(`{"downloadId":"%v","retried":2}`, downloadId)
+	assert.JSONEq(t, expectedResponseBody, actualResponse.Body, "Expected retry response is not met!")
+	assert.Equal(t, 200, actualResponse.StatusCode, "Expected status code is not met!")
+
+	actualDownloadRecord, err := retrier.getDownload(downloadId)
+	assert.NoError(t, err)
+
+	expectedDownloadRecord := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Succeed:    1,
+		Failed:     0,
+		Done:       1,
+		Pending:    2,
+		Total:      3,
+	}
+	assert.Equal(t, expectedDownloadRecord, actualDownloadRecord)
+
+	for _, archive := range []downloads.ArchiveOutcomeRecord{failedArchive1, failedArchive2} {
+		actualArchiveOutcome, err := retrier.getArchiveOutcome(downloadId, archive.ArchiveId)
+		assert.NoError(t, err)
+		assert.Equal(t, downloads.ArchiveRetrying, actualArchiveOutcome.State)
+	}
+
+	actualCommands := []queue.DownloadGamesCommand{}
+	for i := 0; i < 5; i++ {
+		commands, err := retrier.getCommands()
+		assert.NoError(t, err)
+		for _, command := range commands {
+			if command.DownloadId == downloadId {
+				actualCommands = append(actualCommands, command)
+			}
+		}
+	}
+
+	expectedCommands := []queue.DownloadGamesCommand{
+		{
+			Username:   username,
+			Platform:   queue.ChessDotCom,
+			ArchiveId:  failedArchive1.ArchiveId,
+			UserId:     userId,
+			DownloadId: downloadId,
+		},
+		{
+			Username:   username,
+			Platform:   queue.ChessDotCom,
+			ArchiveId:  failedArchive2.ArchiveId,
+			UserId:     userId,
+			DownloadId: downloadId,
+		},
+	}
+	assert.ElementsMatch(t, expectedCommands, actualCommands)
+
+	actualResponse, err = retrier.Retry(&event)
+	assert.NoError(t, err)
+
+	expectedResponseBody = fmt.Sprintf(`{"downloadId":"%v","retried":0}`, downloadId)
+	assert.JSONEq(t, expectedResponseBody, actualResponse.Body, "Archives must not be retried twice!")
+}
+
+func Test_download_request_not_found_is_responded_if_there_is_no_download_to_retry(t *testing.T) {
+
+	downloadId := uuid.New().String()
+
+	event := retryEvent(downloadId)
+
+	actualResponse, err := api.WithRecover(retrier.Retry)(&event)
+	if err != nil {
+		assert.FailNow(t, fmt.Sprintf("%v", err.Error()))
+	}
+
+	expectedResponseBody := fmt.Sprintf(`{"code":"DOWNLOAD_REQUEST_NOT_FOUND","msg":"Download request %v not found"}`, downloadId)
+
+	assert.JSONEq(t, expectedResponseBody, actualResponse.Body, "Expected error is not met!")
+	assert.Equal(t, 422, actualResponse.StatusCode, "Expected status code is not met!")
+}
+
+func Test_cancelled_download_is_not_retried(t *testing.T) {
+	var err error
+	downloadId := uuid.New().String()
+	username := uuid.New().String()
+
+	event := retryEvent(downloadId)
+
+	downloadRecord := downloads.DownloadRecord{
+		DownloadId:      downloadId,
+		Failed:          1,
+		Cancelled:       1,
+		Done:            2,
+		Pending:         0,
+		Total:           2,
+		CancelRequested: true,
+	}
+	err = retrier.persistDownload(downloadRecord)
+	assert.NoError(t, err)
+
+	failedArchive := downloads.ArchiveOutcomeRecord{
+		DownloadId: downloadId,
+		ArchiveId:  fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2022/07", username),
+		UserId:     uuid.New().String(),
+		Username:   username,
+		Platform:   users.ChessDotCom,
+		State:      downloads.ArchiveFailed,
+	}
+	err = retrier.persistArchiveOutcome(failedArchive)
+	assert.NoError(t, err)
+
+	actualResponse, err := api.WithRecover(retrier.Retry)(&event)
+	assert.NoError(t, err)
+
+	expectedResponseBody := fmt.Sprintf(`{"code":"DOWNLOAD_REQUEST_CANCELLED","msg":"Download request %v is cancelled and can not be retried"}`, downloadId)
+	assert.JSONEq(t, expectedResponseBody, actualResponse.Body, "Expected error is not met!")
+	assert.Equal(t, 422, actualResponse.StatusCode, "Expected status code is not met!")
+
+	actualDownloadRecord, err := retrier.getDownload(downloadId)
+	assert.NoError(t, err)
+	assert.Equal(t, downloadRecord, actualDownloadRecord)
+
+	actualArchiveOutcome, err := retrier.getArchiveOutcome(downloadId, failedArchive.ArchiveId)
+	assert.NoError(t, err)
+	assert.Equal(t, downloads.ArchiveFailed, actualArchiveOutcome.State)
+}
+
+func retryEvent(downloadId string) events.APIGatewayV2HTTPRequest {
+	return events.APIGatewayV2HTTPRequest{
+		RouteKey: "POST /api/faster/game/{downloadId}/retry",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "POST",
+				Path:   fmt.Sprintf("/api/faster/game/%v/retry", downloadId),
+			},
+		},
+		PathParameters: map[string]string{
+			"downloadId": downloadId,
+		},
+	}
+}
+
+func (retrier *DownloadRetrier) persistDownload(downloadRecord downloads.DownloadRecord) (err error) {
+	item, err := dynamodbattribute.MarshalMap(downloadRecord)
+	if err != nil {
+		return
+	}
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(retrier.downloadsTableName),
+	})
+	return
+}
+
+func (retrier *DownloadRetrier) persistArchiveOutcome(archiveOutcome downloads.ArchiveOutcomeRecord) (err error) {
+	item, err := dynamodbattribute.MarshalMap(archiveOutcome)
+	if err != nil {
+		return
+	}
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(retrier.archiveOutcomesTableName),
+	})
+	return
+}
+
+func (retrier *DownloadRetrier) getDownload(downloadId string) (download downloads.DownloadRecord, err error) {
+	getItemOutput, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(retrier.downloadsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"download_id": {
+				S: aws.String(downloadId),
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+	err = dynamodbattribute.UnmarshalMap(getItemOutput.Item, &download)
+	return
+}
+
+func (retrier *DownloadRetrier) getArchiveOutcome(downloadId string, archiveId string) (archiveOutcome downloads.ArchiveOutcomeRecord, err error) {
+	getItemOutput, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(retrier.archiveOutcomesTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"download_id": {
+				S: aws.String(downloadId),
+			},
+			"archive_id": {
+				S: aws.String(archiveId),
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+	err = dynamodbattribute.UnmarshalMap(getItemOutput.Item, &archiveOutcome)
+	return
+}
+
+func (retrier *DownloadRetrier) getCommands() (commands []queue.DownloadGamesCommand, err error) {
+	resp, err := svc.ReceiveMessage(&sqs.ReceiveMessageInput{
+		QueueUrl:            &retrier.downloadGamesQueueUrl,
+		MaxNumberOfMessages: aws.Int64(10),
+		VisibilityTimeout:   aws.Int64(30),
+		WaitTimeSeconds:     aws.Int64(0),
+	})
+	if err != nil {
+		return
+	}
+	for _, message := range resp.Messages {
+		_, err = svc.DeleteMessage(&sqs.DeleteMessageInput{
+			QueueUrl:      &retrier.downloadGamesQueueUrl,
+			ReceiptHandle: message.ReceiptHandle,
+		})
+		if err != nil {
+			return
+		}
+		command := queue.DownloadGamesCommand{}
+		err = json.Unmarshal([]byte(*message.Body), &command)
+		if err != nil {
+			return
+		}
+		commands = append(commands, command)
+	}
+	return
+}
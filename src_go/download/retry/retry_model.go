@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+)
+
+type RetryResponse struct {
+	DownloadId string `json:"downloadId"`
+	Retried    int    `json:"retried"`
+}
+
+func DownloadIsCancelled(downloadId string) api.BusinessError {
+	return api.BusinessError{
+		Msg:  fmt.Sprintf("Download request %v is cancelled and can not be retried", downloadId),
+		Code: "DOWNLOAD_REQUEST_CANCELLED",
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom"
+)
+
+func main() {
+
+	downloadsTableName, downloadsTableNameExists := os.LookupEnv("DOWNLOADS_TABLE_NAME")
+	if !downloadsTableNameExists {
+		panic(errors.New("DOWNLOADS_TABLE_NAME is missing"))
+	}
+
+	archivesTableName, archivesTableNameExists := os.LookupEnv("ARCHIVES_TABLE_NAME")
+	if !archivesTableNameExists {
+		panic(errors.New("ARCHIVES_TABLE_NAME is missing"))
+	}
+
+	usersTableName, usersTableNameExists := os.LookupEnv("USERS_TABLE_NAME")
+	if !usersTableNameExists {
+		panic(errors.New("USERS_TABLE_NAME is missing"))
+	}
+
+	chessDotComUrl, chessDotComUrlExists := os.LookupEnv("CHESS_DOT_COM_URL")
+	if !chessDotComUrlExists {
+		panic(errors.New("CHESS_DOT_COM_URL is missing"))
+	}
+
+	chessDotComConfig := chessdotcom.DefaultConfig(chessDotComUrl)
+	chessDotComTimeout, chessDotComTimeoutExists := os.LookupEnv("CHESS_DOT_COM_TIMEOUT")
+	if chessDotComTimeoutExists {
+		timeout, err := time.ParseDuration(chessDotComTimeout)
+		if err != nil {
+			panic(fmt.Errorf("CHESS_DOT_COM_TIMEOUT is invalid: %w", err))
+		}
+		chessDotComConfig.Timeout = timeout
+	}
+
+	lichessUrl, lichessUrlExists := os.LookupEnv("LICHESS_URL")
+	if !lichessUrlExists {
+		panic(errors.New("LICHESS_URL is missing"))
+	}
+
+	downloadGamesQueueUrl, downloadGamesQueueUrlExists := os.LookupEnv("DOWNLOAD_GAMES_QUEUE_URL")
+	if !downloadGamesQueueUrlExists {
+		panic(errors.New("DOWNLOAD_GAMES_QUEUE_URL is missing"))
+	}
+
+	syncCommandsBudget, syncCommandsBudgetExists := os.LookupEnv("SYNC_COMMANDS_BUDGET")
+	if !syncCommandsBudgetExists {
+		panic(errors.New("SYNC_COMMANDS_BUDGET is missing"))
+	}
+	commandsBudget, err := strconv.Atoi(syncCommandsBudget)
+	if err != nil {
+		panic(fmt.Errorf("SYNC_COMMANDS_BUDGET is invalid: %w", err))
+	}
+
+	awsRegion, awsRegionExists := os.LookupEnv("AWS_REGION")
+	if !awsRegionExists {
+		panic(errors.New("AWS_REGION is missing"))
+	}
+
+	synchronizer := Synchronizer{
+		downloadsTableName:    downloadsTableName,
+		usersTableName:        usersTableName,
+		archivesTableName:     archivesTableName,
+		downloadGamesQueueUrl: downloadGamesQueueUrl,
+		chessDotComConfig:     chessDotComConfig,
+		lichessUrl:            lichessUrl,
+		commandsBudget:        commandsBudget,
+		awsConfig: &aws.Config{
+			Region: &awsRegion,
+		},
+	}
+
+	// outside of AWS there is no scheduler, so a local timer triggers the runs instead
+	syncInterval, syncIntervalExists := os.LookupEnv("SYNC_INTERVAL")
+	if syncIntervalExists {
+		interval, err := time.ParseDuration(syncInterval)
+		if err != nil {
+			panic(fmt.Errorf("SYNC_INTERVAL is invalid: %w", err))
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for tick := range ticker.C {
+			err := synchronizer.Sync(context.Background(), events.CloudWatchEvent{ID: tick.UTC().Format(time.RFC3339)})
+			if err != nil {
+				log.Printf("sync of %v failed: %v", tick.UTC().Format(time.RFC3339), err)
+			}
+		}
+		return
+	}
+
+	lambda.Start(synchronizer.Sync)
+}
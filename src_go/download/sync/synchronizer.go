@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/archives"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/downloads"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/users"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// the run stops picking up new players when the lambda is about to time out
+const deadlineMargin = 30 * time.Second
+
+type Synchronizer struct {
+	chessDotComConfig     chessdotcom.Config
+	lichessUrl            string
+	awsConfig             *aws.Config
+	usersTableName        string
+	archivesTableName     string
+	downloadsTableName    string
+	downloadGamesQueueUrl string
+	// commandsBudget is the total number of download commands one run may enqueue over all players,
+	// it bounds the load a run puts on the download workers and chess.com rather than how many of them run at once
+	commandsBudget int
+}
+
+// player is a user with all the usernames they have been cached under
+type player struct {
+	userId   string
+	platform users.Platform
+	aliases  []users.UserRecord
+}
+
+func (synchronizer *Synchronizer) Sync(ctx context.Context, event events.CloudWatchEvent) (err error) {
+	config := zap.NewProductionConfig()
+	config.OutputPaths = []string{"stdout"}
+	timeEncoder := func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.UTC().Format("2006-01-02T15:04:05.000Z"))
+	}
+	config.EncoderConfig.EncodeTime = timeEncoder
+
+	logger, err := config.Build()
+	if err != nil {
+		panic(err)
+	}
+	logger = logger.With(zap.String("syncId", event.ID))
+	defer logger.Sync()
+
+	awsSession, err := session.NewSession(synchronizer.awsConfig)
+	if err != nil {
+		logger.Error("impossible to create an AWS session!", zap.Error(err))
+		return
+	}
+	dynamodbClient := dynamodb.New(awsSession)
+	svc := sqs.New(awsSession)
+	chessDotComClient, err := chessdotcom.NewClient(synchronizer.chessDotComConfig)
+	if err != nil {
+		logger.Error("impossible to create a chess.com client!", zap.Error(err))
+		return
+	}
+
+	players, err := synchronizer.getPlayers(dynamodbClient, logger)
+	if err != nil {
+		return
+	}
+
+	// the budget does not let the first players of the table starve the others from run to run
+	rand.Shuffle(len(players), func(i, j int) { players[i], players[j] = players[j], players[i] })
+
+	budget := synchronizer.commandsBudget
+	synced := 0
+	for _, player := range players {
+		if budget <= 0 {
+			logger.Info("budget of the run is exhausted")
+			break
+		}
+		if deadline, hasDeadline := ctx.Deadline(); hasDeadline && time.Until(deadline) < deadlineMargin {
+			logger.Info("run is about to time out")
+			break
+		}
+
+		logger := logger.With(zap.String("userId", player.userId), zap.String("platform", string(player.platform)))
+		enqueued, errOfPlayer := synchronizer.syncPlayer(dynamodbClient, svc, chessDotComClient, logger, player, budget, time.Now())
+		if errOfPlayer != nil {
+			logger.Error("impossible to sync the player", zap.Error(errOfPlayer))
+			continue
+		}
+		budget -= enqueued
+		synced++
+	}
+
+	logger.Info("sync is finished", zap.Int("players", len(players)), zap.Int("syncedPlayers", synced), zap.Int("enqueued", synchronizer.commandsBudget-budget))
+	return
+}
+
+func (synchronizer *Synchronizer) getPlayers(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+) (players []player, err error) {
+	logger.Info("scanning users")
+	playersByUserId := map[string]*player{}
+	var lastKey map[string]*dynamodb.AttributeValue
+
+	for {
+		var usersItems *dynamodb.ScanOutput
+		usersItems, err = dynamodbClient.Scan(&dynamodb.ScanInput{
+			TableName:         aws.String(synchronizer.usersTableName),
+			ExclusiveStartKey: lastKey,
+		})
+
+		if err != nil {
+			logger.Error("impossible to scan the users", zap.Error(err))
+			return
+		}
+
+		userRecords := []users.UserRecord{}
+		err = dynamodbattribute.UnmarshalListOfMaps(usersItems.Items, &userRecords)
+		if err != nil {
+			logger.Error("impossible to unmarshal the users", zap.Error(err))
+			return
+		}
+
+		for _, userRecord := range userRecords {
+			key := string(userRecord.Platform) + "|" + userRecord.UserId
+			existingPlayer, exists := playersByUserId[key]
+			if !exists {
+				existingPlayer = &player{userId: userRecord.UserId, platform: userRecord.Platform}
+				playersByUserId[key] = existingPlayer
+			}
+			existingPlayer.aliases = append(existingPlayer.aliases, userRecord)
+		}
+
+		lastKey = usersItems.LastEvaluatedKey
+		if len(lastKey) == 0 {
+			break
+		}
+	}
+
+	players = make([]player, 0, len(playersByUserId))
+	for _, player := range playersByUserId {
+		players = append(players, *player)
+	}
+	logger.Info("users are scanned", zap.Int("players", len(players)))
+	return
+}
+
+// syncPlayer enqueues downloads of the archives of the player that are missing or could have got new games,
+// the same way a download requested by the player would do, but never more than the budget allows.
+func (synchronizer *Synchronizer) syncPlayer(
+	dynamodbClient *dynamodb.DynamoDB,
+	svc *sqs.SQS,
+	chessDotComClient *chessdotcom.Client,
+	logger *zap.Logger,
+	player player,
+	budget int,
+	now time.Time,
+) (enqueued int, err error) {
+	archivesFromDb, err := archives.GetArchiveRecords(dynamodbClient, synchronizer.archivesTableName, player.userId)
+	if err != nil {
+		logger.Error("impossible to query dynamodb for archives!", zap.Error(err))
+		return
+	}
+
+	var user users.UserRecord
+	var archiveUrls []string
+	switch player.platform {
+	case users.ChessDotCom:
+		user, archiveUrls, err = synchronizer.getArchivesFromChessDotCom(chessDotComClient, logger, player)
+	case users.Lichess:
+		user = player.aliases[0]
+		archiveUrls = archives.LichessArchives(synchronizer.lichessUrl, user.Username, firstMonth(archivesFromDb, now), now)
+	default:
+		logger.Info("platform is not supported")
+		return
+	}
+	if err != nil {
+		return
+	}
+	if user.Username == "" {
+		logger.Info("player does not use any of their usernames anymore")
+		return
+	}
+
+	missingArchiveUrls := archives.ResolveMissing(archiveUrls, archivesFromDb)
+	archivesToDownload := archives.ResolveToDownload(archivesFromDb)
+	missingArchiveUrls, archivesToDownload = withinBudget(missingArchiveUrls, archivesToDownload, budget)
+
+	logger = logger.With(zap.Int("missingArchives", len(missingArchiveUrls)), zap.Int("archivesToDownload", len(archivesToDownload)))
+	if len(missingArchiveUrls)+len(archivesToDownload) == 0 {
+		logger.Info("player is up to date")
+		return
+	}
+
+	missingArchives, err := archives.PersistMissing(dynamodbClient, synchronizer.archivesTableName, player.userId, missingArchiveUrls)
+	if err != nil {
+		logger.Error("impossible to persist the missing archives!", zap.Error(err))
+		return
+	}
+
+	// nobody waits for the download of a sync, its record keeps the progress and the outcomes the workers count into it,
+	// so the download id in the logs lets the status of the sync be checked and its failed archives be retried
+	downloadRecord := downloads.NewDownloadRecord(uuid.New().String(), len(missingArchives)+len(archivesToDownload))
+	logger = logger.With(zap.String("downloadId", downloadRecord.DownloadId))
+
+	downloadRecordItems, err := dynamodbattribute.MarshalMap(downloadRecord)
+	if err != nil {
+		logger.Error("impossible to marshal the download record!", zap.Error(err))
+		return
+	}
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(synchronizer.downloadsTableName),
+		Item:      downloadRecordItems,
+	})
+	if err != nil {
+		logger.Error("impossible to persist the download record!", zap.Error(err))
+		return
+	}
+
+	err = synchronizer.publishDownloadGameCommands(logger, svc, user, downloadRecord, append(archivesToDownload, missingArchives...))
+	if err != nil {
+		return
+	}
+
+	enqueued = downloadRecord.Total
+	return
+}
+
+// getArchivesFromChessDotCom tries all usernames of the player, the ones they do not use anymore are not found
+func (synchronizer *Synchronizer) getArchivesFromChessDotCom(
+	chessDotComClient *chessdotcom.Client,
+	logger *zap.Logger,
+	player player,
+) (user users.UserRecord, archiveUrls []string, err error) {
+	usernames := make([]string, 0, len(player.aliases))
+	for _, alias := range player.aliases {
+		usernames = append(usernames, alias.Username)
+	}
+
+	username, archivesFromChessDotCom, err := chessDotComClient.ArchivesOfFirstUsedUsername(logger, usernames)
+	if err != nil {
+		logger.Error("impossible to get the archives from chess.com!", zap.Error(err))
+		return
+	}
+	for _, alias := range player.aliases {
+		if alias.Username == username {
+			user = alias
+			archiveUrls = archivesFromChessDotCom.Archives
+		}
+	}
+	return
+}
+
+func (synchronizer *Synchronizer) publishDownloadGameCommands(
+	logger *zap.Logger,
+	svc *sqs.SQS,
+	user users.UserRecord,
+	downloadRecord downloads.DownloadRecord,
+	archivesToDownload []archives.ArchiveRecord,
+) (err error) {
+	for _, archive := range archivesToDownload {
+		err = queue.SendDownloadGamesCommand(svc, synchronizer.downloadGamesQueueUrl, queue.DownloadGamesCommand{
+			Username:   user.Username,
+			Platform:   queue.Platform(user.Platform),
+			ArchiveId:  archive.ArchiveId,
+			UserId:     archive.UserId,
+			DownloadId: downloadRecord.DownloadId,
+		})
+		if err != nil {
+			logger.Error("impossible to publish the download game command!", zap.String("archiveId", archive.ArchiveId), zap.Error(err))
+			return
+		}
+	}
+
+	logger.Info("download game commands published")
+	return
+}
+
+// withinBudget keeps the most recent archives when the player needs more downloads than the run can afford
+func withinBudget(
+	missingArchiveUrls []string,
+	archivesToDownload []archives.ArchiveRecord,
+	budget int,
+) ([]string, []archives.ArchiveRecord) {
+	if len(missingArchiveUrls)+len(archivesToDownload) <= budget {
+		return missingArchiveUrls, archivesToDownload
+	}
+	if budget <= 0 {
+		return []string{}, []archives.ArchiveRecord{}
+	}
+
+	type candidate struct {
+		year, month    int
+		missingUrl     string
+		archiveToFetch *archives.ArchiveRecord
+	}
+	candidates := make([]candidate, 0, len(missingArchiveUrls)+len(archivesToDownload))
+	for _, missingArchiveUrl := range missingArchiveUrls {
+		year, month, _ := archives.Month(missingArchiveUrl)
+		candidates = append(candidates, candidate{year: year, month: month, missingUrl: missingArchiveUrl})
+	}
+	for i := range archivesToDownload {
+		archive := archivesToDownload[i]
+		candidates = append(candidates, candidate{year: archive.Year, month: archive.Month, archiveToFetch: &archive})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].year != candidates[j].year {
+			return candidates[i].year > candidates[j].year
+		}
+		return candidates[i].month > candidates[j].month
+	})
+
+	affordableMissingArchiveUrls := []string{}
+	affordableArchivesToDownload := []archives.ArchiveRecord{}
+	for _, candidate := range candidates[:budget] {
+		if candidate.archiveToFetch != nil {
+			affordableArchivesToDownload = append(affordableArchivesToDownload, *candidate.archiveToFetch)
+		} else {
+			affordableMissingArchiveUrls = append(affordableMissingArchiveUrls, candidate.missingUrl)
+		}
+	}
+	return affordableMissingArchiveUrls, affordableArchivesToDownload
+}
+
+// firstMonth is the month lichess pseudo-archives of a cached player start from
+func firstMonth(archivesFromDb []archives.ArchiveRecord, now time.Time) time.Time {
+	first := now
+	for _, archive := range archivesFromDb {
+		if archive.Uploaded {
+			continue
+		}
+		month := time.Date(archive.Year, time.Month(archive.Month), 1, 0, 0, 0, 0, time.UTC)
+		if month.Before(first) {
+			first = month
+		}
+	}
+	return first
+}
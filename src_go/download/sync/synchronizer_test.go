@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chessdotcom"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/archives"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/downloads"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/users"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/wiremock/go-wiremock"
+	"go.uber.org/zap"
+)
+
+var awsConfig = aws.Config{
+	Region:     aws.String("us-east-1"),
+	Endpoint:   aws.String("http://localhost:4566"), // this is the LocalStack endpoint for all services
+	DisableSSL: aws.Bool(true),
+}
+
+var synchronizer = Synchronizer{
+	downloadGamesQueueUrl: "http://localhost:4566/000000000000/chessfinder_sqs-DownloadGames.fifo",
+	chessDotComConfig:     chessdotcom.DefaultConfig("http://0.0.0.0:18443"),
+	lichessUrl:            "http://0.0.0.0:18443",
+	usersTableName:        "chessfinder_dynamodb-users",
+	archivesTableName:     "chessfinder_dynamodb-archives",
+	downloadsTableName:    "chessfinder_dynamodb-downloads",
+	commandsBudget:        100,
+	awsConfig:             &awsConfig,
+}
+
+var awsSession = session.Must(session.NewSession(&awsConfig))
+
+var dynamodbClient = dynamodb.New(awsSession)
+var svc = sqs.New(awsSession)
+
+var wiremockClient = wiremock.NewClient("http://0.0.0.0:18443")
+
+func Test_Synchronizer_should_enqueue_new_and_stale_archives_of_a_player_under_the_username_still_in_use(t *testing.T) {
+	var err error
+	defer wiremockClient.Reset()
+
+	oldUsername := uuid.New().String()
+	username := uuid.New().String()
+	userId := fmt.Sprintf("https://api.chess.com/pub/player/%v", oldUsername)
+
+	player := player{
+		userId:   userId,
+		platform: users.ChessDotCom,
+		aliases: []users.UserRecord{
+			{Username: oldUsername, Platform: users.ChessDotCom, UserId: userId},
+			{Username: username, Platform: users.ChessDotCom, UserId: userId},
+		},
+	}
+
+	getOldArchivesStub := wiremock.Get(wiremock.URLPathEqualTo(fmt.Sprintf("/pub/player/%v/games/archives", oldUsername))).
+		WillReturnResponse(
+			wiremock.NewResponse().
+				WithStatus(http.StatusNotFound),
+		)
+	err = wiremockClient.StubFor(getOldArchivesStub)
+	assert.NoError(t, err)
+
+	archivesResponseBody := fmt.Sprintf(
+		`{
+			"archives": [
+				"https://api.chess.com/pub/player/%v/games/2021/10",
+				"https://api.chess.com/pub/player/%v/games/2021/11",
+				"https://api.chess.com/pub/player/%v/games/2021/12"
+			]
+		}`, username, username, username,
+	)
+
+	getArchivesStub := wiremock.Get(wiremock.URLPathEqualTo(fmt.Sprintf("/pub/player/%v/games/archives", username))).
+		WillReturnResponse(
+			wiremock.NewResponse().
+				WithBody(archivesResponseBody).
+				WithHeader("Content-Type", "application/json").
+				WithStatus(http.StatusOK),
+		)
+	err = wiremockClient.StubFor(getArchivesStub)
+	assert.NoError(t, err)
+
+	downloadedAfterTheMonthEnd := db.Zuludatetime(time.Date(2021, 11, 2, 0, 0, 0, 0, time.UTC))
+	downloadedInTheMiddleOfTheMonth := db.Zuludatetime(time.Date(2021, 11, 15, 0, 0, 0, 0, time.UTC))
+
+	completeArchive := archives.ArchiveRecord{
+		UserId:       userId,
+		ArchiveId:    fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2021/10", oldUsername),
+		Resource:     fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2021/10", oldUsername),
+		Year:         2021,
+		Month:        10,
+		Downloaded:   10,
+		DownloadedAt: &downloadedAfterTheMonthEnd,
+	}
+	staleArchive := archives.ArchiveRecord{
+		UserId:       userId,
+		ArchiveId:    fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2021/11", oldUsername),
+		Resource:     fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2021/11", oldUsername),
+		Year:         2021,
+		Month:        11,
+		Downloaded:   5,
+		DownloadedAt: &downloadedInTheMiddleOfTheMonth,
+	}
+	for _, archive := range []archives.ArchiveRecord{completeArchive, staleArchive} {
+		err = synchronizer.persistArchiveRecord(archive)
+		assert.NoError(t, err)
+	}
+
+	chessDotComClient, err := chessdotcom.NewClient(synchronizer.chessDotComConfig)
+	assert.NoError(t, err)
+
+	enqueued, err := synchronizer.syncPlayer(dynamodbClient, svc, chessDotComClient, zap.NewNop(), player, synchronizer.commandsBudget, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, enqueued)
+
+	newArchiveId := fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2021/12", username)
+	actualNewArchive, err := synchronizer.getArchiveRecord(userId, newArchiveId)
+	assert.NoError(t, err)
+	expectedNewArchive := archives.ArchiveRecord{
+		UserId:    userId,
+		ArchiveId: newArchiveId,
+		Resource:  newArchiveId,
+		Year:      2021,
+		Month:     12,
+	}
+	assert.Equal(t, expectedNewArchive, actualNewArchive)
+
+	actualCommands := []queue.DownloadGamesCommand{}
+	for i := 0; i < 5; i++ {
+		var commands []queue.DownloadGamesCommand
+		commands, err = synchronizer.getCommands()
+		assert.NoError(t, err)
+		for _, command := range commands {
+			if command.UserId == userId {
+				actualCommands = append(actualCommands, command)
+			}
+		}
+	}
+	assert.Len(t, actualCommands, 2)
+
+	downloadId := actualCommands[0].DownloadId
+	expectedCommands := []queue.DownloadGamesCommand{
+		{
+			Username:   username,
+			Platform:   queue.ChessDotCom,
+			ArchiveId:  staleArchive.ArchiveId,
+			UserId:     userId,
+			DownloadId: downloadId,
+		},
+		{
+			Username:   username,
+			Platform:   queue.ChessDotCom,
+			ArchiveId:  newArchiveId,
+			UserId:     userId,
+			DownloadId: downloadId,
+		},
+	}
+	assert.ElementsMatch(t, expectedCommands, actualCommands)
+
+	actualDownloadRecord, err := synchronizer.getDownloadRecord(downloadId)
+	assert.NoError(t, err)
+	assert.Equal(t, downloads.NewDownloadRecord(downloadId, 2), actualDownloadRecord)
+}
+
+func Test_withinBudget_should_keep_the_most_recent_archives(t *testing.T) {
+	missingArchiveUrls := []string{
+		"https://api.chess.com/pub/player/tigran-c-137/games/2021/12",
+		"https://api.chess.com/pub/player/tigran-c-137/games/2022/01",
+	}
+	archivesToDownload := []archives.ArchiveRecord{
+		{ArchiveId: "https://api.chess.com/pub/player/tigran-c-137/games/2021/10", Year: 2021, Month: 10},
+		{ArchiveId: "https://api.chess.com/pub/player/tigran-c-137/games/2021/11", Year: 2021, Month: 11},
+	}
+
+	actualMissingArchiveUrls, actualArchivesToDownload := withinBudget(missingArchiveUrls, archivesToDownload, 3)
+	assert.ElementsMatch(t, missingArchiveUrls, actualMissingArchiveUrls)
+	assert.ElementsMatch(t, archivesToDownload[1:], actualArchivesToDownload)
+
+	actualMissingArchiveUrls, actualArchivesToDownload = withinBudget(missingArchiveUrls, archivesToDownload, 10)
+	assert.Equal(t, missingArchiveUrls, actualMissingArchiveUrls)
+	assert.Equal(t, archivesToDownload, actualArchivesToDownload)
+
+	actualMissingArchiveUrls, actualArchivesToDownload = withinBudget(missingArchiveUrls, archivesToDownload, 0)
+	assert.Empty(t, actualMissingArchiveUrls)
+	assert.Empty(t, actualArchivesToDownload)
+}
+
+func (synchronizer Synchronizer) getArchiveRecord(userId string, archiveId string) (archive archives.ArchiveRecord, err error) {
+
+	archiveRecordQuery := &dynamodb.GetItemInput{
+		TableName: aws.String(synchronizer.archivesTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"user_id": {
+				S: aws.String(userId),
+			},
+			"archive_id": {
+				S: aws.String(archiveId),
+			},
+		},
+	}
+
+	archiveItem, err := dynamodbClient.GetItem(archiveRecordQuery)
+	if err != nil {
+		return
+	}
+
+	err = dynamodbattribute.UnmarshalMap(archiveItem.Item, &archive)
+	return
+}
+
+func (synchronizer Synchronizer) getDownloadRecord(downloadId string) (downloadRecord downloads.DownloadRecord, err error) {
+
+	downloadRecordQuery := &dynamodb.GetItemInput{
+		TableName: aws.String(synchronizer.downloadsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"download_id": {
+				S: aws.String(downloadId),
+			},
+		},
+	}
+
+	downloadRecordItem, err := dynamodbClient.GetItem(downloadRecordQuery)
+	if err != nil {
+		return
+	}
+
+	err = dynamodbattribute.UnmarshalMap(downloadRecordItem.Item, &downloadRecord)
+	return
+}
+
+func (synchronizer Synchronizer) persistArchiveRecord(archive archives.ArchiveRecord) (err error) {
+
+	archiveItem, err := dynamodbattribute.MarshalMap(archive)
+	if err != nil {
+		return
+	}
+
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(synchronizer.archivesTableName),
+		Item:      archiveItem,
+	})
+	return
+}
+
+func (synchronizer Synchronizer) getCommands() (commands []queue.DownloadGamesCommand, err error) {
+	resp, err := svc.ReceiveMessage(&sqs.ReceiveMessageInput{
+		QueueUrl:            &synchronizer.downloadGamesQueueUrl,
+		MaxNumberOfMessages: aws.Int64(10),
+		VisibilityTimeout:   aws.Int64(30),
+		WaitTimeSeconds:     aws.Int64(0),
+	})
+	if err != nil {
+		return
+	}
+	for _, message := range resp.Messages {
+		_, err = svc.DeleteMessage(&sqs.DeleteMessageInput{
+			QueueUrl:      &synchronizer.downloadGamesQueueUrl,
+			ReceiptHandle: message.ReceiptHandle,
+		})
+		if err != nil {
+			return
+		}
+		command := queue.DownloadGamesCommand{}
+		err = json.Unmarshal([]byte(*message.Body), &command)
+		if err != nil {
+			return
+		}
+		commands = append(commands, command)
+	}
+	return
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+)
+
+func main() {
+
+	usersTableName, usersTableNameExists := os.LookupEnv("USERS_TABLE_NAME")
+	if !usersTableNameExists {
+		panic(errors.New("USERS_TABLE_NAME is missing"))
+	}
+
+	archivesTableName, archivesTableNameExists := os.LookupEnv("ARCHIVES_TABLE_NAME")
+	if !archivesTableNameExists {
+		panic(errors.New("ARCHIVES_TABLE_NAME is missing"))
+	}
+
+	downloadsTableName, downloadsTableNameExists := os.LookupEnv("DOWNLOADS_TABLE_NAME")
+	if !downloadsTableNameExists {
+		panic(errors.New("DOWNLOADS_TABLE_NAME is missing"))
+	}
+
+	gamesTableName, gamesTableNameExists := os.LookupEnv("GAMES_TABLE_NAME")
+	if !gamesTableNameExists {
+		panic(errors.New("GAMES_TABLE_NAME is missing"))
+	}
+
+	awsRegion, awsRegionExists := os.LookupEnv("AWS_REGION")
+	if !awsRegionExists {
+		panic(errors.New("AWS_REGION is missing"))
+	}
+
+	uploader := PgnUploader{
+		usersTableName:     usersTableName,
+		archivesTableName:  archivesTableName,
+		downloadsTableName: downloadsTableName,
+		gamesTableName:     gamesTableName,
+		awsConfig: &aws.Config{
+			Region: &awsRegion,
+		},
+	}
+
+	lambda.Start(api.WithRecover(uploader.Upload))
+}
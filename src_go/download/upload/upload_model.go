@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+)
+
+type UploadRequest struct {
+	Username string `json:"username"`
+	Platform string `json:"platform"`
+	Pgn      string `json:"pgn"`
+}
+
+type UploadResponse struct {
+	DownloadId string `json:"downloadId"`
+}
+
+var NoGameInPgn = api.BusinessError{
+	Code: "NO_GAME_IN_PGN",
+	Msg:  "Uploaded pgn does not contain any game!",
+}
+
+// the games of an upload are stored before the response, within the timeout of the api gateway
+const maxUploadedGames = 1000
+
+func TooManyGamesInPgn(uploadedGames int) api.BusinessError {
+	return api.BusinessError{
+		Code: "TOO_MANY_GAMES_IN_PGN",
+		Msg:  fmt.Sprintf("Uploaded pgn contains %d games, at most %d games can be uploaded at once!", uploadedGames, maxUploadedGames),
+	}
+}
+
+func ProfileIsNotCached(username string, platform string) api.BusinessError {
+	return api.BusinessError{
+		Code: "PROFILE_IS_NOT_CACHED",
+		Msg:  fmt.Sprintf("Profile %s from %s is not cached!", username, platform),
+	}
+}
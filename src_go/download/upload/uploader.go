@@ -0,0 +1,426 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/batcher"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chess"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/archives"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/downloads"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/users"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/pgn"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type PgnUploader struct {
+	awsConfig          *aws.Config
+	usersTableName     string
+	archivesTableName  string
+	downloadsTableName string
+	gamesTableName     string
+}
+
+func (uploader *PgnUploader) Upload(event *events.APIGatewayV2HTTPRequest) (responseEvent events.APIGatewayV2HTTPResponse, err error) {
+	config := zap.NewProductionConfig()
+	config.OutputPaths = []string{"stdout"}
+	timeEncoder := func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.UTC().Format("2006-01-02T15:04:05.000Z"))
+	} // Log to stdout
+	config.EncoderConfig.EncodeTime = timeEncoder
+
+	// Create the logger from the configuration
+	logger, err := config.Build()
+	if err != nil {
+		panic(err)
+	}
+	logger = logger.With(zap.String("requestId", event.RequestContext.RequestID))
+	defer logger.Sync()
+
+	awsSession, err := session.NewSession(uploader.awsConfig)
+	if err != nil {
+		logger.Panic("impossible to create an AWS session!")
+		return
+	}
+	dynamodbClient := dynamodb.New(awsSession)
+
+	method := event.RequestContext.HTTP.Method
+	path := event.RequestContext.HTTP.Path
+
+	if path != "/api/faster/game/upload" || method != "POST" {
+		logger.Panic("pgn uploader is attached to a wrong route!")
+	}
+
+	body := event.Body
+	if event.IsBase64Encoded {
+		var decodedBody []byte
+		decodedBody, err = base64.StdEncoding.DecodeString(event.Body)
+		if err != nil {
+			logger.Error("impossible to decode the request body!", zap.Error(err))
+			err = api.InvalidBody
+			return
+		}
+		body = string(decodedBody)
+	}
+
+	uploadRequest := UploadRequest{}
+	err = json.Unmarshal([]byte(body), &uploadRequest)
+	if err != nil {
+		logger.Error("impossible to unmarshal the request body!", zap.Error(err))
+		err = api.InvalidBody
+		return
+	}
+
+	logger = logger.With(zap.String("username", uploadRequest.Username), zap.String("platform", uploadRequest.Platform))
+
+	user, err := uploader.getUserRecord(dynamodbClient, logger, uploadRequest)
+	if err != nil {
+		return
+	}
+
+	logger = logger.With(zap.String("userId", user.UserId))
+
+	uploadedPgns := pgn.Split(uploadRequest.Pgn)
+	if len(uploadedPgns) == 0 {
+		logger.Info("no game in the uploaded pgn")
+		err = NoGameInPgn
+		return
+	}
+	if len(uploadedPgns) > maxUploadedGames {
+		logger.Info("too many games in the uploaded pgn", zap.Int("uploadedGames", len(uploadedPgns)))
+		err = TooManyGamesInPgn(len(uploadedPgns))
+		return
+	}
+
+	downloadId := uuid.New().String()
+	archiveId := "upload/" + downloadId
+	logger = logger.With(zap.String("downloadId", downloadId), zap.String("archiveId", archiveId), zap.Int("uploadedGames", len(uploadedPgns)))
+
+	gameRecords := make([]games.GameRecord, 0, len(uploadedPgns))
+	uploadedGameIds := make(map[string]struct{}, len(uploadedPgns))
+	invalidGames := 0
+	for i, uploadedPgn := range uploadedPgns {
+		game, errOfValidation := validate(uploadedPgn)
+		if errOfValidation != nil {
+			logger.Info("invalid game in the uploaded pgn", zap.Int("gameNumber", i+1), zap.Error(errOfValidation))
+			invalidGames++
+			continue
+		}
+		gameRecord := uploadedGameRecord(user, archiveId, uploadedPgn, game)
+		if _, isUploaded := uploadedGameIds[gameRecord.GameId]; isUploaded {
+			continue
+		}
+		uploadedGameIds[gameRecord.GameId] = struct{}{}
+		gameRecords = append(gameRecords, gameRecord)
+	}
+
+	// the archive is stored before its games, so the games of a failed upload are never left without it
+	year, month := monthOf(gameRecords, time.Now())
+	archiveRecord := archives.ArchiveRecord{
+		UserId:    user.UserId,
+		ArchiveId: archiveId,
+		Resource:  archiveId,
+		Year:      year,
+		Month:     month,
+		Uploaded:  true,
+	}
+	err = uploader.persistArchiveRecord(dynamodbClient, logger, archiveRecord)
+	if err != nil {
+		return
+	}
+
+	// the games uploaded twice are stored once, they are counted as succeeded right away
+	duplicates := len(uploadedPgns) - invalidGames - len(gameRecords)
+	downloadRecord := downloads.NewDownloadRecord(downloadId, len(uploadedPgns))
+	downloadRecord.Pending -= invalidGames + duplicates
+	downloadRecord.Failed += invalidGames
+	downloadRecord.Succeed += duplicates
+	downloadRecord.Done += invalidGames + duplicates
+	err = uploader.persistDownloadRecord(dynamodbClient, logger, downloadRecord)
+	if err != nil {
+		return
+	}
+
+	logger.Info("persisting uploaded games", zap.Int("validGames", len(gameRecords)), zap.Int("invalidGames", invalidGames))
+	persisted := 0
+	for batchNumber, batch := range batcher.Batcher(gameRecords, 25) {
+		logger := logger.With(zap.Int("batchNumber", batchNumber+1), zap.Int("batchSize", len(batch)))
+		err = uploader.persistGameRecords(dynamodbClient, logger, batch)
+		if err != nil {
+			// the games that are left are failed, so the download does not stay pending forever
+			errOfCount := uploader.countGames(dynamodbClient, logger, downloadId, "failed", len(gameRecords)-persisted)
+			if errOfCount != nil {
+				logger.Error("impossible to count the games that are left as failed!", zap.Error(errOfCount))
+			}
+			return
+		}
+
+		err = uploader.countGames(dynamodbClient, logger, downloadId, "succeed", len(batch))
+		if err != nil {
+			return
+		}
+		persisted += len(batch)
+	}
+
+	nowInZulu := db.Zuludatetime(time.Now())
+	archiveRecord.Downloaded = len(gameRecords)
+	archiveRecord.DownloadedAt = &nowInZulu
+	err = uploader.persistArchiveRecord(dynamodbClient, logger, archiveRecord)
+	if err != nil {
+		return
+	}
+
+	logger.Info("upload finished", zap.Int("succeed", persisted+duplicates), zap.Int("failed", invalidGames))
+
+	uploadResponse := UploadResponse{
+		DownloadId: downloadId,
+	}
+
+	jsonBody, err := json.Marshal(uploadResponse)
+	if err != nil {
+		logger.Error("impossible to marshal the upload response!", zap.Error(err))
+		return
+	}
+
+	responseEvent = events.APIGatewayV2HTTPResponse{
+		StatusCode: 200,
+		Body:       string(jsonBody),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}
+
+	return
+}
+
+// validate parses the tags and replays the moves, a game that can not be replayed is never found by a search
+func validate(uploadedPgn string) (game pgn.Game, err error) {
+	game, err = pgn.Parse(uploadedPgn)
+	if err != nil {
+		return
+	}
+	replay, err := chess.Read(uploadedPgn)
+	if err != nil {
+		return
+	}
+	_, err = replay.Positions()
+	return
+}
+
+// the id of an uploaded game is derived from its pgn, so uploading the same game again overwrites it instead of duplicating it
+func uploadedGameId(uploadedPgn string) string {
+	hash := sha256.Sum256([]byte(strings.TrimSpace(uploadedPgn)))
+	return "upload/" + hex.EncodeToString(hash[:])
+}
+
+func uploadedGameRecord(user users.UserRecord, archiveId string, uploadedPgn string, game pgn.Game) games.GameRecord {
+	gameId := uploadedGameId(uploadedPgn)
+	resource := gameId
+	if site := game.Tag("Site"); strings.HasPrefix(site, "http://") || strings.HasPrefix(site, "https://") {
+		resource = site
+	}
+
+	gameRecord := games.GameRecord{
+		UserId:       user.UserId,
+		ArchiveId:    archiveId,
+		GameId:       gameId,
+		Resource:     resource,
+		Pgn:          uploadedPgn,
+		EndTimestamp: playedAt(game),
+	}
+	return gameRecord.WithPgnTags(game.Tags)
+}
+
+// the best guess about when the game was played, the uploaded games often have only the date or even only the year
+func playedAt(game pgn.Game) int64 {
+	date := game.Tag("UTCDate")
+	clock := game.Tag("UTCTime")
+	if date == "" {
+		date = game.Tag("Date")
+		clock = ""
+	}
+
+	if playedAt, err := time.Parse("2006.01.02 15:04:05", date+" "+clock); err == nil {
+		return playedAt.Unix()
+	}
+
+	if playedAt, err := time.Parse("2006.01.02", date); err == nil {
+		return playedAt.Unix()
+	}
+
+	return 0
+}
+
+// monthOf is the month of the latest uploaded game, or the given fallback when no game tells when it was played
+func monthOf(gameRecords []games.GameRecord, fallback time.Time) (year int, month int) {
+	latest := int64(0)
+	for _, gameRecord := range gameRecords {
+		if gameRecord.EndTimestamp > latest {
+			latest = gameRecord.EndTimestamp
+		}
+	}
+	playedAt := fallback
+	if latest > 0 {
+		playedAt = time.Unix(latest, 0)
+	}
+	return playedAt.UTC().Year(), int(playedAt.UTC().Month())
+}
+
+func (uploader *PgnUploader) getUserRecord(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	uploadRequest UploadRequest,
+) (user users.UserRecord, err error) {
+	user, isFound, err := users.GetUserRecord(dynamodbClient, uploader.usersTableName, uploadRequest.Username, users.Platform(uploadRequest.Platform))
+	if err != nil {
+		logger.Error("error while getting user from db", zap.Error(err))
+		return
+	}
+	if !isFound {
+		err = ProfileIsNotCached(uploadRequest.Username, uploadRequest.Platform)
+		logger.Info("profile is not cached")
+		return
+	}
+	return
+}
+
+func (uploader *PgnUploader) persistDownloadRecord(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	downloadRecord downloads.DownloadRecord,
+) (err error) {
+	downloadRecordItems, err := dynamodbattribute.MarshalMap(downloadRecord)
+	if err != nil {
+		logger.Error("impossible to marshal the download record!", zap.Error(err))
+		return
+	}
+
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(uploader.downloadsTableName),
+		Item:      downloadRecordItems,
+	})
+	if err != nil {
+		logger.Error("impossible to persist the download record!", zap.Error(err))
+		return
+	}
+	return
+}
+
+func (uploader *PgnUploader) persistArchiveRecord(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	archiveRecord archives.ArchiveRecord,
+) (err error) {
+	archiveRecordItems, err := dynamodbattribute.MarshalMap(archiveRecord)
+	if err != nil {
+		logger.Error("impossible to marshal the archive record!", zap.Error(err))
+		return
+	}
+
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(uploader.archivesTableName),
+		Item:      archiveRecordItems,
+	})
+	if err != nil {
+		logger.Error("impossible to persist the archive record!", zap.Error(err))
+		return
+	}
+	return
+}
+
+// countGames moves the given number of pending games to the given counter of the download
+func (uploader *PgnUploader) countGames(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	downloadId string,
+	counter string,
+	count int,
+) (err error) {
+	if count == 0 {
+		return
+	}
+	_, err = dynamodbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(uploader.downloadsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"download_id": {
+				S: aws.String(downloadId),
+			},
+		},
+		UpdateExpression: aws.String("ADD pending :decrement, done :increment, #counter :increment"),
+		ExpressionAttributeNames: map[string]*string{
+			"#counter": aws.String(counter),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":increment": {
+				N: aws.String(strconv.Itoa(count)),
+			},
+			":decrement": {
+				N: aws.String(strconv.Itoa(-count)),
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("impossible to count the uploaded games in the download record!", zap.String("counter", counter), zap.Error(err))
+		return
+	}
+	return
+}
+
+func (uploader *PgnUploader) persistGameRecords(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	gameRecords []games.GameRecord,
+) (err error) {
+	writeRequests := make([]*dynamodb.WriteRequest, 0, len(gameRecords))
+	for _, gameRecord := range gameRecords {
+		var gameRecordItems map[string]*dynamodb.AttributeValue
+		gameRecordItems, err = dynamodbattribute.MarshalMap(gameRecord)
+		if err != nil {
+			logger.Error("impossible to marshal the game record", zap.Error(err))
+			return
+		}
+		writeRequests = append(writeRequests, &dynamodb.WriteRequest{
+			PutRequest: &dynamodb.PutRequest{
+				Item: gameRecordItems,
+			},
+		})
+	}
+
+	unprocessedWriteRequests := map[string][]*dynamodb.WriteRequest{
+		uploader.gamesTableName: writeRequests,
+	}
+
+	for len(unprocessedWriteRequests) > 0 {
+		logger.Info("trying to persist uploaded games in one iteration")
+		var writeOutput *dynamodb.BatchWriteItemOutput
+		writeOutput, err = dynamodbClient.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+			RequestItems: unprocessedWriteRequests,
+		})
+
+		if err != nil {
+			logger.Error("impossible to persist the uploaded game records", zap.Error(err))
+			return
+		}
+
+		unprocessedWriteRequests = writeOutput.UnprocessedItems
+		if len(unprocessedWriteRequests) > 0 {
+			time.Sleep(time.Millisecond * 100)
+		}
+	}
+	return
+}
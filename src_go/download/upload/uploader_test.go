@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/archives"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/downloads"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/users"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var awsConfig = aws.Config{
+	Region:     aws.String("us-east-1"),
+	Endpoint:   aws.String("http://localhost:4566"), // this is the LocalStack endpoint for all services
+	DisableSSL: aws.Bool(true),
+}
+
+var uploader = PgnUploader{
+	usersTableName:     "chessfinder_dynamodb-users",
+	archivesTableName:  "chessfinder_dynamodb-archives",
+	downloadsTableName: "chessfinder_dynamodb-downloads",
+	gamesTableName:     "chessfinder_dynamodb-games",
+	awsConfig:          &awsConfig,
+}
+
+var awsSession = session.Must(session.NewSession(&awsConfig))
+var dynamodbClient = dynamodb.New(awsSession)
+
+const otbGames = `[Event "Yerevan Open"]
+[Site "Yerevan"]
+[Date "2022.08.02"]
+[Round "1"]
+[White "tigran-c-137"]
+[Black "Nezhmetdinov"]
+[Result "1-0"]
+
+1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0
+
+[Event "Yerevan Open"]
+[Site "Yerevan"]
+[Date "2022.08.03"]
+[Round "2"]
+[White "Petrosian"]
+[Black "tigran-c-137"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3
+
+[Event "Yerevan Open"]
+[Site "https://lichess.org/3Uz4VYxq"]
+[Date "2022.08.04"]
+[Round "3"]
+[White "Nezhmetdinov"]
+[Black "tigran-c-137"]
+[Result "0-1"]
+
+1. f3 e5 2. g4 Qh4# 0-1
+
+[Event "Yerevan Open"]
+[Site "Yerevan"]
+[Date "2022.08.05"]
+[Round "4"]
+[White "tigran-c-137"]
+[Black "Petrosian"]
+[Result "1-0"]
+
+1. e4 e5 2. Ke3 Nc6 1-0
+`
+
+// the first and the third games are valid, the second has no result and the fourth has an illegal move
+const firstGamePgn = "[Event \"Yerevan Open\"]\n[Site \"Yerevan\"]\n[Date \"2022.08.02\"]\n[Round \"1\"]\n[White \"tigran-c-137\"]\n[Black \"Nezhmetdinov\"]\n[Result \"1-0\"]\n\n1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0\n"
+const thirdGamePgn = "[Event \"Yerevan Open\"]\n[Site \"https://lichess.org/3Uz4VYxq\"]\n[Date \"2022.08.04\"]\n[Round \"3\"]\n[White \"Nezhmetdinov\"]\n[Black \"tigran-c-137\"]\n[Result \"0-1\"]\n\n1. f3 e5 2. g4 Qh4# 0-1\n"
+
+func Test_PgnUploader_should_store_valid_games_under_a_synthetic_archive_and_count_invalid_ones_as_failed(t *testing.T) {
+	var err error
+	username := uuid.New().String()
+	userId := fmt.Sprintf("https://api.chess.com/pub/player/%v", username)
+
+	err = uploader.persistUserRecord(users.UserRecord{
+		Username: username,
+		Platform: users.ChessDotCom,
+		UserId:   userId,
+	})
+	assert.NoError(t, err)
+
+	downloadId := upload(t, username, otbGames)
+	archiveId := "upload/" + downloadId
+
+	actualDownloadRecord, err := uploader.getDownloadRecord(downloadId)
+	assert.NoError(t, err)
+
+	expectedDownloadRecord := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Succeed:    2,
+		Failed:     2,
+		Done:       4,
+		Pending:    0,
+		Total:      4,
+	}
+	assert.Equal(t, expectedDownloadRecord, actualDownloadRecord)
+
+	actualArchive, err := uploader.getArchiveRecord(userId, archiveId)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, actualArchive.Downloaded)
+	assert.True(t, actualArchive.Uploaded)
+	assert.NotNil(t, actualArchive.DownloadedAt)
+	assert.Equal(t, 2022, actualArchive.Year)
+	assert.Equal(t, 8, actualArchive.Month)
+
+	actualGames, err := uploader.getAllGames(userId)
+	assert.NoError(t, err)
+
+	expectedGames := []games.GameRecord{
+		{
+			UserId:       userId,
+			ArchiveId:    archiveId,
+			GameId:       uploadedGameId(firstGamePgn),
+			Resource:     uploadedGameId(firstGamePgn),
+			Pgn:          firstGamePgn,
+			EndTimestamp: 1659398400,
+			White:        "tigran-c-137",
+			Black:        "Nezhmetdinov",
+			Result:       "1-0",
+			Rules:        games.StandardRules,
+		},
+		{
+			UserId:       userId,
+			ArchiveId:    archiveId,
+			GameId:       uploadedGameId(thirdGamePgn),
+			Resource:     "https://lichess.org/3Uz4VYxq",
+			Pgn:          thirdGamePgn,
+			EndTimestamp: 1659571200,
+			White:        "Nezhmetdinov",
+			Black:        "tigran-c-137",
+			Result:       "0-1",
+			Rules:        games.StandardRules,
+		},
+	}
+	assert.ElementsMatch(t, expectedGames, actualGames)
+}
+
+func Test_PgnUploader_should_not_duplicate_games_uploaded_again(t *testing.T) {
+	var err error
+	username := uuid.New().String()
+	userId := fmt.Sprintf("https://api.chess.com/pub/player/%v", username)
+
+	err = uploader.persistUserRecord(users.UserRecord{
+		Username: username,
+		Platform: users.ChessDotCom,
+		UserId:   userId,
+	})
+	assert.NoError(t, err)
+
+	upload(t, username, otbGames)
+	downloadId := upload(t, username, firstGamePgn+"\n"+firstGamePgn)
+
+	actualDownloadRecord, err := uploader.getDownloadRecord(downloadId)
+	assert.NoError(t, err)
+
+	expectedDownloadRecord := downloads.DownloadRecord{
+		DownloadId: downloadId,
+		Succeed:    2,
+		Failed:     0,
+		Done:       2,
+		Pending:    0,
+		Total:      2,
+	}
+	assert.Equal(t, expectedDownloadRecord, actualDownloadRecord)
+
+	actualGames, err := uploader.getAllGames(userId)
+	assert.NoError(t, err)
+
+	actualGameIds := []string{}
+	for _, actualGame := range actualGames {
+		actualGameIds = append(actualGameIds, actualGame.GameId)
+	}
+	assert.ElementsMatch(t, []string{uploadedGameId(firstGamePgn), uploadedGameId(thirdGamePgn)}, actualGameIds)
+}
+
+func Test_PgnUploader_should_reject_too_many_games(t *testing.T) {
+	var err error
+	username := uuid.New().String()
+	userId := fmt.Sprintf("https://api.chess.com/pub/player/%v", username)
+
+	err = uploader.persistUserRecord(users.UserRecord{
+		Username: username,
+		Platform: users.ChessDotCom,
+		UserId:   userId,
+	})
+	assert.NoError(t, err)
+
+	tooManyGames := strings.Repeat(firstGamePgn+"\n", maxUploadedGames+1)
+
+	_, err = uploader.Upload(uploadEvent(t, username, tooManyGames))
+	assert.Equal(t, TooManyGamesInPgn(maxUploadedGames+1), err)
+}
+
+func Test_PgnUploader_should_reject_upload_for_a_non_cached_profile(t *testing.T) {
+	username := uuid.New().String()
+
+	requestBody, err := json.Marshal(UploadRequest{
+		Username: username,
+		Platform: "CHESS_DOT_COM",
+		Pgn:      otbGames,
+	})
+	assert.NoError(t, err)
+
+	event := events.APIGatewayV2HTTPRequest{
+		Body: string(requestBody),
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "POST",
+				Path:   "/api/faster/game/upload",
+			},
+		},
+	}
+
+	_, err = uploader.Upload(&event)
+	assert.Equal(t, ProfileIsNotCached(username, "CHESS_DOT_COM"), err)
+}
+
+func uploadEvent(t *testing.T, username string, pgn string) *events.APIGatewayV2HTTPRequest {
+	requestBody, err := json.Marshal(UploadRequest{
+		Username: username,
+		Platform: "CHESS_DOT_COM",
+		Pgn:      pgn,
+	})
+	assert.NoError(t, err)
+
+	return &events.APIGatewayV2HTTPRequest{
+		Body: string(requestBody),
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "POST",
+				Path:   "/api/faster/game/upload",
+			},
+		},
+	}
+}
+
+func upload(t *testing.T, username string, pgn string) (downloadId string) {
+	actualResponse, err := uploader.Upload(uploadEvent(t, username, pgn))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, actualResponse.StatusCode)
+
+	actualUploadResponse := UploadResponse{}
+	err = json.Unmarshal([]byte(actualResponse.Body), &actualUploadResponse)
+	assert.NoError(t, err)
+	return actualUploadResponse.DownloadId
+}
+
+func (uploader PgnUploader) persistUserRecord(user users.UserRecord) (err error) {
+	userItems, err := dynamodbattribute.MarshalMap(user)
+	if err != nil {
+		return
+	}
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(uploader.usersTableName),
+		Item:      userItems,
+	})
+	return
+}
+
+func (uploader PgnUploader) getDownloadRecord(downloadId string) (downloadRecord downloads.DownloadRecord, err error) {
+	downloadRecordItem, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(uploader.downloadsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"download_id": {
+				S: aws.String(downloadId),
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+	err = dynamodbattribute.UnmarshalMap(downloadRecordItem.Item, &downloadRecord)
+	return
+}
+
+func (uploader PgnUploader) getArchiveRecord(userId string, archiveId string) (archive archives.ArchiveRecord, err error) {
+	archiveItem, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(uploader.archivesTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"user_id": {
+				S: aws.String(userId),
+			},
+			"archive_id": {
+				S: aws.String(archiveId),
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+	err = dynamodbattribute.UnmarshalMap(archiveItem.Item, &archive)
+	return
+}
+
+func (uploader PgnUploader) getAllGames(userId string) (gameRecords []games.GameRecord, err error) {
+	queryOutput, err := dynamodbClient.Query(&dynamodb.QueryInput{
+		TableName:              aws.String(uploader.gamesTableName),
+		KeyConditionExpression: aws.String("user_id = :user_id"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":user_id": {
+				S: aws.String(userId),
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+	err = dynamodbattribute.UnmarshalListOfMaps(queryOutput.Items, &gameRecords)
+	return
+}
@@ -16,8 +16,11 @@ import (
 )
 
 type SearchResultChecker struct {
-	awsConfig         *aws.Config
-	searchesTableName string
+	awsConfig              *aws.Config
+	searchesTableName      string
+	searchMatchesTableName string
+	pollInterval           time.Duration
+	maxWait                time.Duration
 }
 
 func (checker *SearchResultChecker) Check(event *events.APIGatewayV2HTTPRequest) (responseEvent events.APIGatewayV2HTTPResponse, err error) {
@@ -61,40 +64,43 @@ func (checker *SearchResultChecker) Check(event *events.APIGatewayV2HTTPRequest)
 
 	logger = logger.With(zap.String("searchId", searchId))
 
-	searchItems, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
-		Key: map[string]*dynamodb.AttributeValue{
-			"search_id": {
-				S: aws.String(searchId),
-			},
-		},
-		TableName: aws.String(checker.searchesTableName),
-	})
-
+	pageSize, err := pageSizeOf(event.QueryStringParameters)
 	if err != nil {
-		logger.Error("faild to get search!")
 		return
 	}
+	cursor := event.QueryStringParameters["cursor"]
 
-	if len(searchItems.Item) == 0 {
-		logger.Error("no search search found!")
-		err = SearchNotFound(searchId)
+	searchRecord, err := checker.getSearchRecord(dynamodbClient, logger, searchId)
+	if err != nil {
 		return
 	}
 
-	searchRecord := searches.SearchRecord{}
-	err = dynamodbattribute.UnmarshalMap(searchItems.Item, &searchRecord)
+	// the client that knows the current version waits for the next one instead of polling
+	waitForChangeSince, waitForChangeSinceExists := event.QueryStringParameters["waitForChangeSince"]
+	if waitForChangeSinceExists {
+		searchRecord, err = checker.waitForChange(dynamodbClient, logger, searchRecord, waitForChangeSince)
+		if err != nil {
+			return
+		}
+	}
+
+	page, err := checker.getMatchesPage(dynamodbClient, logger, searchRecord, pageSize, cursor)
 	if err != nil {
-		logger.Error("faild to unmarshal search!", zap.Error(err))
 		return
 	}
+
 	searchResultResponse := SearchResultResponse{
 		SearchId:       searchRecord.SearchId,
 		Total:          searchRecord.Total,
 		StartAt:        searchRecord.StartAt.ToTime(),
 		LastExaminedAt: searchRecord.LastExaminedAt.ToTime(),
 		Examined:       searchRecord.Examined,
-		Matched:        searchRecord.Matched,
+		Matched:        page.Matched,
+		MatchedCount:   matchedCountOf(searchRecord),
+		NextCursor:     page.NextCursor,
+		Matches:        page.Matches,
 		Status:         SearchStatus(string(searchRecord.Status)),
+		Version:        searchVersion(searchRecord),
 	}
 	responseBody, err := json.Marshal(searchResultResponse)
 	if err != nil {
@@ -110,3 +116,62 @@ func (checker *SearchResultChecker) Check(event *events.APIGatewayV2HTTPRequest)
 	}
 	return
 }
+
+func (checker *SearchResultChecker) getSearchRecord(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	searchId string,
+) (searchRecord searches.SearchRecord, err error) {
+	searchItems, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"search_id": {
+				S: aws.String(searchId),
+			},
+		},
+		TableName: aws.String(checker.searchesTableName),
+	})
+
+	if err != nil {
+		logger.Error("faild to get search!")
+		return
+	}
+
+	if len(searchItems.Item) == 0 {
+		logger.Error("no search search found!")
+		err = SearchNotFound(searchId)
+		return
+	}
+
+	err = dynamodbattribute.UnmarshalMap(searchItems.Item, &searchRecord)
+	if err != nil {
+		logger.Error("faild to unmarshal search!", zap.Error(err))
+		return
+	}
+	return
+}
+
+// waitForChange rereads the search until its version differs from the one the client knows,
+// the search is over or the wait runs out, then the latest state is returned
+func (checker *SearchResultChecker) waitForChange(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	searchRecord searches.SearchRecord,
+	knownVersion string,
+) (latestSearchRecord searches.SearchRecord, err error) {
+	latestSearchRecord = searchRecord
+	waitUntil := time.Now().Add(checker.maxWait)
+	polls := 0
+	for searchVersion(latestSearchRecord) == knownVersion && latestSearchRecord.Status == searches.InProgress {
+		if !time.Now().Add(checker.pollInterval).Before(waitUntil) {
+			break
+		}
+		time.Sleep(checker.pollInterval)
+		polls++
+		latestSearchRecord, err = checker.getSearchRecord(dynamodbClient, logger, searchRecord.SearchId)
+		if err != nil {
+			return
+		}
+	}
+	logger.Info("waited for the change of the search", zap.Int("polls", polls), zap.Bool("changed", searchVersion(latestSearchRecord) != knownVersion))
+	return
+}
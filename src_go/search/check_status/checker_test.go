@@ -1,8 +1,10 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go/aws"
@@ -23,8 +25,11 @@ var awsConfig = aws.Config{
 }
 
 var statusChecker = SearchResultChecker{
-	awsConfig:         &awsConfig,
-	searchesTableName: "chessfinder_dynamodb-searches",
+	awsConfig:              &awsConfig,
+	searchesTableName:      "chessfinder_dynamodb-searches",
+	searchMatchesTableName: "chessfinder_dynamodb-searchMatches",
+	pollInterval:           100 * time.Millisecond,
+	maxWait:                2 * time.Second,
 }
 
 var awsSession = session.Must(session.NewSession(statusChecker.awsConfig))
@@ -75,12 +80,179 @@ func Test_search_result_is_delivered_if_there_is_a_search_for_given_id(t *testin
 	actualResponse, err := statusChecker.Check(&event)
 	assert.NoError(t, err)
 
-	expectedResponseBody := fmt.Sprintf(`{"searchId":"%v","startAt":"2021-01-01T00:00:00Z","lastExaminedAt":"2021-02-01T00:11:24Z","examined":15,"total":100,"matched":["https://www.chess.com/game/live/88624306385","https://www.chess.com/game/live/88704743803"],"status":"SEARCHED_ALL"}`, searchId)
+	expectedResponseBody := fmt.Sprintf(`{"searchId":"%v","startAt":"2021-01-01T00:00:00Z","lastExaminedAt":"2021-02-01T00:11:24Z","examined":15,"total":100,"matched":["https://www.chess.com/game/live/88624306385","https://www.chess.com/game/live/88704743803"],"matchedCount":2,"status":"SEARCHED_ALL","version":"15-2-SEARCHED_ALL","matches":[{"resource":"https://www.chess.com/game/live/88624306385","ply":0},{"resource":"https://www.chess.com/game/live/88704743803","ply":0}]}`, searchId)
 
 	assert.JSONEq(t, expectedResponseBody, actualResponse.Body, "Expected download status is not met!")
 	assert.Equal(t, 200, actualResponse.StatusCode, "Expected status code is not met!")
 }
 
+func Test_search_result_is_delivered_once_it_changes_if_the_client_waits_for_a_change(t *testing.T) {
+	var err error
+	searchId := uuid.New().String()
+
+	event := events.APIGatewayV2HTTPRequest{
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "GET",
+				Path:   "/api/faster/board",
+			},
+		},
+		QueryStringParameters: map[string]string{
+			"searchId":           searchId,
+			"waitForChangeSince": "0-0-IN_PROGRESS",
+		},
+	}
+
+	startAt, err := db.ZuluDateTimeFromString("2021-01-01T00:00:00.000Z")
+	assert.NoError(t, err)
+
+	searchRecord := searches.NewSearchRecord(searchId, startAt.ToTime(), 100)
+
+	item, err := dynamodbattribute.MarshalMap(searchRecord)
+	assert.NoError(t, err)
+
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(statusChecker.searchesTableName),
+	})
+	assert.NoError(t, err)
+
+	matchRecord := searches.NewMatchRecord(searchId, "88704743803", "https://www.chess.com/game/live/88704743803", 1610000000)
+	matchRecord.Ply = 5
+	matchRecord.Fen = "r1bqkbnr/pppp1ppp/2n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 3"
+	matchItem, err := dynamodbattribute.MarshalMap(matchRecord)
+	assert.NoError(t, err)
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		_, _ = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+			Item:      matchItem,
+			TableName: aws.String(statusChecker.searchMatchesTableName),
+		})
+		_, _ = dynamodbClient.UpdateItem(&dynamodb.UpdateItemInput{
+			TableName: aws.String(statusChecker.searchesTableName),
+			Key: map[string]*dynamodb.AttributeValue{
+				"search_id": {
+					S: aws.String(searchId),
+				},
+			},
+			UpdateExpression: aws.String("SET examined = :examined, matched_count = :matchedCount"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":examined": {
+					N: aws.String("50"),
+				},
+				":matchedCount": {
+					N: aws.String("1"),
+				},
+			},
+		})
+	}()
+
+	actualResponse, err := statusChecker.Check(&event)
+	assert.NoError(t, err)
+
+	expectedResponseBody := fmt.Sprintf(`{"searchId":"%v","startAt":"2021-01-01T00:00:00Z","lastExaminedAt":"2021-01-01T00:00:00Z","examined":50,"total":100,"matched":["https://www.chess.com/game/live/88704743803"],"matchedCount":1,"status":"IN_PROGRESS","version":"50-1-IN_PROGRESS","matches":[{"resource":"https://www.chess.com/game/live/88704743803","ply":5,"fen":"r1bqkbnr/pppp1ppp/2n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 3"}]}`, searchId)
+
+	assert.JSONEq(t, expectedResponseBody, actualResponse.Body, "Expected search result is not met!")
+	assert.Equal(t, 200, actualResponse.StatusCode, "Expected status code is not met!")
+}
+
+func Test_search_result_is_delivered_page_by_page_in_the_order_of_the_game_dates(t *testing.T) {
+	var err error
+	searchId := uuid.New().String()
+
+	startAt, err := db.ZuluDateTimeFromString("2021-01-01T00:00:00.000Z")
+	assert.NoError(t, err)
+
+	searchRecord := searches.NewSearchRecord(searchId, startAt.ToTime(), 100)
+	searchRecord.Examined = 100
+	searchRecord.MatchedCount = 3
+	searchRecord.Status = searches.SearchedAll
+
+	item, err := dynamodbattribute.MarshalMap(searchRecord)
+	assert.NoError(t, err)
+
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(statusChecker.searchesTableName),
+	})
+	assert.NoError(t, err)
+
+	matchRecords := []searches.MatchRecord{
+		searches.NewMatchRecord(searchId, "88704743803", "https://www.chess.com/game/live/88704743803", 1610000300),
+		searches.NewMatchRecord(searchId, "88624306385", "https://www.chess.com/game/live/88624306385", 1610000100),
+		searches.NewMatchRecord(searchId, "88624306999", "https://www.chess.com/game/live/88624306999", 1610000200),
+	}
+	for _, matchRecord := range matchRecords {
+		matchItem, err := dynamodbattribute.MarshalMap(matchRecord)
+		assert.NoError(t, err)
+
+		_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+			Item:      matchItem,
+			TableName: aws.String(statusChecker.searchMatchesTableName),
+		})
+		assert.NoError(t, err)
+	}
+
+	checkPage := func(cursor string) (searchResultResponse SearchResultResponse) {
+		queryStringParameters := map[string]string{
+			"searchId": searchId,
+			"pageSize": "2",
+		}
+		if cursor != "" {
+			queryStringParameters["cursor"] = cursor
+		}
+		event := events.APIGatewayV2HTTPRequest{
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+					Method: "GET",
+					Path:   "/api/faster/board",
+				},
+			},
+			QueryStringParameters: queryStringParameters,
+		}
+
+		actualResponse, err := statusChecker.Check(&event)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, actualResponse.StatusCode, "Expected status code is not met!")
+
+		err = json.Unmarshal([]byte(actualResponse.Body), &searchResultResponse)
+		assert.NoError(t, err)
+		return
+	}
+
+	firstPage := checkPage("")
+	assert.Equal(t, []string{"https://www.chess.com/game/live/88624306385", "https://www.chess.com/game/live/88624306999"}, firstPage.Matched)
+	assert.Equal(t, 3, firstPage.MatchedCount)
+	assert.NotEmpty(t, firstPage.NextCursor)
+
+	secondPage := checkPage(firstPage.NextCursor)
+	assert.Equal(t, []string{"https://www.chess.com/game/live/88704743803"}, secondPage.Matched)
+	assert.Equal(t, 3, secondPage.MatchedCount)
+	assert.Empty(t, secondPage.NextCursor)
+}
+
+func Test_search_result_is_not_delivered_if_the_page_size_is_above_the_maximum(t *testing.T) {
+	event := events.APIGatewayV2HTTPRequest{
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "GET",
+				Path:   "/api/faster/board",
+			},
+		},
+		QueryStringParameters: map[string]string{
+			"searchId": uuid.New().String(),
+			"pageSize": "101",
+		},
+	}
+
+	actualResponse, err := api.WithRecover(statusChecker.Check)(&event)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "query parameter pageSize 101 has to be a number between 1 and 100", actualResponse.Body, "Expected error is not met!")
+	assert.Equal(t, 400, actualResponse.StatusCode, "Expected status code is not met!")
+}
+
 func Test_search_result_not_found_is_responded_if_there_is_no_search_for_given_id(t *testing.T) {
 
 	searchId := uuid.New().String()
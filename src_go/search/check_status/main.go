@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"os"
+	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
@@ -15,13 +16,21 @@ func main() {
 		panic(errors.New("SEARCHES_TABLE_NAME is missing"))
 	}
 
+	searchMatchesTableName, searchMatchesTableNameExists := os.LookupEnv("SEARCH_MATCHES_TABLE_NAME")
+	if !searchMatchesTableNameExists {
+		panic(errors.New("SEARCH_MATCHES_TABLE_NAME is missing"))
+	}
+
 	awsRegion, awsRegionExists := os.LookupEnv("AWS_REGION")
 	if !awsRegionExists {
 		panic(errors.New("AWS_REGION is missing"))
 	}
 
 	checker := SearchResultChecker{
-		searchesTableName: searchesTableName,
+		searchesTableName:      searchesTableName,
+		searchMatchesTableName: searchMatchesTableName,
+		pollInterval:           time.Second,
+		maxWait:                20 * time.Second,
 		awsConfig: &aws.Config{
 			Region: &awsRegion,
 		},
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/searches"
+	"go.uber.org/zap"
+)
+
+const DefaultPageSize = 50
+const MaxPageSize = 100
+
+func InvalidPageSize(pageSize string) api.ValidationError {
+	return api.ValidationError{
+		Msg: fmt.Sprintf("query parameter pageSize %v has to be a number between 1 and %v", pageSize, MaxPageSize),
+	}
+}
+
+func InvalidCursor(cursor string) api.ValidationError {
+	return api.ValidationError{
+		Msg: fmt.Sprintf("query parameter cursor %v is not a cursor given by the previous page", cursor),
+	}
+}
+
+// matchesPage is the part of the matches the client asks for, the cursor is empty after the last page
+type matchesPage struct {
+	Matched    []string
+	Matches    []MatchResponse
+	NextCursor string
+}
+
+func pageSizeOf(queryStringParameters map[string]string) (pageSize int, err error) {
+	rawPageSize, rawPageSizeExists := queryStringParameters["pageSize"]
+	if !rawPageSizeExists {
+		pageSize = DefaultPageSize
+		return
+	}
+	pageSize, err = strconv.Atoi(rawPageSize)
+	if err != nil || pageSize < 1 || pageSize > MaxPageSize {
+		err = InvalidPageSize(rawPageSize)
+		return
+	}
+	return
+}
+
+// matchIdOfCursor reads the cursor that is the id of the last match of the previous page
+func matchIdOfCursor(cursor string) (matchId string, err error) {
+	decodedCursor, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(decodedCursor) == 0 {
+		err = InvalidCursor(cursor)
+		return
+	}
+	matchId = string(decodedCursor)
+	return
+}
+
+func cursorOfMatchId(matchId string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(matchId))
+}
+
+// matchedCountOf counts the matches of the searches made before the matches were stored apart as well
+func matchedCountOf(searchRecord searches.SearchRecord) int {
+	if searchRecord.MatchedCount == 0 {
+		return len(searchRecord.Matched)
+	}
+	return searchRecord.MatchedCount
+}
+
+func (checker *SearchResultChecker) getMatchesPage(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	searchRecord searches.SearchRecord,
+	pageSize int,
+	cursor string,
+) (page matchesPage, err error) {
+	page.Matched = []string{}
+	page.Matches = []MatchResponse{}
+
+	if len(searchRecord.Matched) > 0 && searchRecord.MatchedCount == 0 {
+		// the searches made before the matches were stored apart have at most 10 of them, they fit one page
+		page.Matched = searchRecord.Matched
+		for _, resource := range searchRecord.Matched {
+			page.Matches = append(page.Matches, MatchResponse{Resource: resource})
+		}
+		return
+	}
+
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(checker.searchMatchesTableName),
+		KeyConditionExpression: aws.String("search_id = :searchId"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":searchId": {
+				S: aws.String(searchRecord.SearchId),
+			},
+		},
+		Limit: aws.Int64(int64(pageSize)),
+	}
+
+	if cursor != "" {
+		var matchId string
+		matchId, err = matchIdOfCursor(cursor)
+		if err != nil {
+			return
+		}
+		queryInput.ExclusiveStartKey = map[string]*dynamodb.AttributeValue{
+			"search_id": {
+				S: aws.String(searchRecord.SearchId),
+			},
+			"match_id": {
+				S: aws.String(matchId),
+			},
+		}
+	}
+
+	queryOutput, err := dynamodbClient.Query(queryInput)
+	if err != nil {
+		logger.Error("faild to query matches!", zap.Error(err))
+		return
+	}
+
+	matchRecords := []searches.MatchRecord{}
+	err = dynamodbattribute.UnmarshalListOfMaps(queryOutput.Items, &matchRecords)
+	if err != nil {
+		logger.Error("faild to unmarshal matches!", zap.Error(err))
+		return
+	}
+
+	for _, matchRecord := range matchRecords {
+		page.Matched = append(page.Matched, matchRecord.Resource)
+		page.Matches = append(page.Matches, MatchResponse{
+			Resource: matchRecord.Resource,
+			Ply:      matchRecord.Ply,
+			Fen:      matchRecord.Fen,
+		})
+	}
+
+	if lastMatchId, lastMatchIdExists := queryOutput.LastEvaluatedKey["match_id"]; lastMatchIdExists && lastMatchId.S != nil {
+		page.NextCursor = cursorOfMatchId(*lastMatchId.S)
+	}
+	return
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/searches"
 )
 
 type SearchStatus string
@@ -22,7 +23,27 @@ type SearchResultResponse struct {
 	Examined       int          `json:"examined"`
 	Total          int          `json:"total"`
 	Matched        []string     `json:"matched"`
+	MatchedCount   int          `json:"matchedCount"`
 	Status         SearchStatus `json:"status"`
+	// Version changes whenever the progress does, it is given back as waitForChangeSince to wait for the progress
+	Version string `json:"version"`
+	// Matched is one page of the matches ordered by the date of their games,
+	// NextCursor is given back as cursor to read the next page, there is none after the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+	// Matches tell where the board occurs first in the games of Matched
+	Matches []MatchResponse `json:"matches"`
+}
+
+// MatchResponse points to the first position of the game the board is found in, the ply 0 is the position
+// before the first move, the fen is missing if the position is unknown, as for the searches made before it was stored
+type MatchResponse struct {
+	Resource string `json:"resource"`
+	Ply      int    `json:"ply"`
+	Fen      string `json:"fen,omitempty"`
+}
+
+func searchVersion(searchRecord searches.SearchRecord) string {
+	return fmt.Sprintf("%v-%v-%v", searchRecord.Examined, matchedCountOf(searchRecord), searchRecord.Status)
 }
 
 func SearchNotFound(searchId string) api.BusinessError {
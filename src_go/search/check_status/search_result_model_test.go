@@ -17,7 +17,13 @@ func Test_Search_Result_Is_Marshalled_Correctlly(t *testing.T) {
 			"examined": 15,
 			"total": 100,
 			"matched": ["https://www.chess.com/game/live/88704743803", "https://www.chess.com/game/live/88624306385"],
-			"status": "SEARCHED_ALL"
+			"matchedCount": 2,
+			"status": "SEARCHED_ALL",
+			"version": "15-2-SEARCHED_ALL",
+			"matches": [
+				{"resource": "https://www.chess.com/game/live/88704743803", "ply": 5, "fen": "r1bqkbnr/pppp1ppp/2n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 3"},
+				{"resource": "https://www.chess.com/game/live/88624306385", "ply": 0}
+			]
 		}
 		`
 	startAt, err := time.Parse("2006-01-02T15:04:05.000Z", "2021-01-01T00:00:00.123Z")
@@ -32,7 +38,19 @@ func Test_Search_Result_Is_Marshalled_Correctlly(t *testing.T) {
 		Examined:       15,
 		Total:          100,
 		Matched:        []string{"https://www.chess.com/game/live/88704743803", "https://www.chess.com/game/live/88624306385"},
+		MatchedCount:   2,
 		Status:         SearchedAll,
+		Version:        "15-2-SEARCHED_ALL",
+		Matches: []MatchResponse{
+			{
+				Resource: "https://www.chess.com/game/live/88704743803",
+				Ply:      5,
+				Fen:      "r1bqkbnr/pppp1ppp/2n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 3",
+			},
+			{
+				Resource: "https://www.chess.com/game/live/88624306385",
+			},
+		},
 	}
 
 	actualResultStatusJson, err := json.Marshal(searchResultResponse)
@@ -53,7 +71,13 @@ func Test_Search_Result_Is_Unmarshalled_Correctlly(t *testing.T) {
 			"examined": 15,
 			"total": 100,
 			"matched": ["https://www.chess.com/game/live/88704743803", "https://www.chess.com/game/live/88624306385"],
-			"status": "SEARCHED_ALL"
+			"matchedCount": 2,
+			"status": "SEARCHED_ALL",
+			"version": "15-2-SEARCHED_ALL",
+			"matches": [
+				{"resource": "https://www.chess.com/game/live/88704743803", "ply": 5, "fen": "r1bqkbnr/pppp1ppp/2n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 3"},
+				{"resource": "https://www.chess.com/game/live/88624306385", "ply": 0}
+			]
 		}
 		`
 	startAt, err := time.Parse("2006-01-02T15:04:05.000Z", "2021-01-01T00:00:00.000Z")
@@ -67,7 +91,19 @@ func Test_Search_Result_Is_Unmarshalled_Correctlly(t *testing.T) {
 		Examined:       15,
 		Total:          100,
 		Matched:        []string{"https://www.chess.com/game/live/88704743803", "https://www.chess.com/game/live/88624306385"},
+		MatchedCount:   2,
 		Status:         SearchedAll,
+		Version:        "15-2-SEARCHED_ALL",
+		Matches: []MatchResponse{
+			{
+				Resource: "https://www.chess.com/game/live/88704743803",
+				Ply:      5,
+				Fen:      "r1bqkbnr/pppp1ppp/2n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 3",
+			},
+			{
+				Resource: "https://www.chess.com/game/live/88624306385",
+			},
+		},
 	}
 
 	actualSearchResultResponse := new(SearchResultResponse)
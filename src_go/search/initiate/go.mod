@@ -6,7 +6,10 @@ require (
 	github.com/aws/aws-lambda-go v1.41.0
 	github.com/aws/aws-sdk-go v1.45.24
 	github.com/chessfinder/chessfinder-faster-backend/src_go/details/api v0.0.0-20230921201148-2f6c15cfb0c9
+	github.com/chessfinder/chessfinder-faster-backend/src_go/details/callback v0.0.0-00010101000000-000000000000
+	github.com/chessfinder/chessfinder-faster-backend/src_go/details/chess v0.0.0-00010101000000-000000000000
 	github.com/chessfinder/chessfinder-faster-backend/src_go/details/db v0.0.0-00010101000000-000000000000
+	github.com/chessfinder/chessfinder-faster-backend/src_go/details/isolate v0.0.0-00010101000000-000000000000
 	github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue v0.0.0-00010101000000-000000000000
 	github.com/google/uuid v1.3.1
 	github.com/stretchr/testify v1.8.4
@@ -14,6 +17,8 @@ require (
 )
 
 require (
+	github.com/chessfinder/chessfinder-faster-backend/src_go/details/batcher v0.0.0-00010101000000-000000000000 // indirect
+	github.com/chessfinder/chessfinder-faster-backend/src_go/details/pgn v0.0.0-00010101000000-000000000000 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
@@ -28,3 +33,11 @@ replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue =
 replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/batcher => ../../details/batcher
 
 replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/db => ../../details/db
+
+replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/callback => ../../details/callback
+
+replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/chess => ../../details/chess
+
+replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/pgn => ../../details/pgn
+
+replace github.com/chessfinder/chessfinder-faster-backend/src_go/details/isolate => ../../details/isolate
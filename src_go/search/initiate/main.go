@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/search/initiate/validation"
 )
 
 func main() {
@@ -16,11 +17,21 @@ func main() {
 		panic(errors.New("USERS_TABLE_NAME is missing"))
 	}
 
+	usersByPlayerIdIndexName, usersByPlayerIdIndexNameExists := os.LookupEnv("USERS_BY_PLAYER_ID_INDEX_NAME")
+	if !usersByPlayerIdIndexNameExists {
+		panic(errors.New("USERS_BY_PLAYER_ID_INDEX_NAME is missing"))
+	}
+
 	archivesTableName, archivesTableNameExists := os.LookupEnv("ARCHIVES_TABLE_NAME")
 	if !archivesTableNameExists {
 		panic(errors.New("ARCHIVES_TABLE_NAME is missing"))
 	}
 
+	gamesTableName, gamesTableNameExists := os.LookupEnv("GAMES_TABLE_NAME")
+	if !gamesTableNameExists {
+		panic(errors.New("GAMES_TABLE_NAME is missing"))
+	}
+
 	searchesTableName, searchesTableNameExists := os.LookupEnv("SEARCHES_TABLE_NAME")
 	if !searchesTableNameExists {
 		panic(errors.New("SEARCHES_TABLE_NAME is missing"))
@@ -31,19 +42,31 @@ func main() {
 		panic(errors.New("SEARCH_BOARD_QUEUE_URL is missing"))
 	}
 
+	boardMatcher, boardMatcherExists := os.LookupEnv("BOARD_MATCHER")
+	if !boardMatcherExists {
+		panic(errors.New("BOARD_MATCHER is missing"))
+	}
+	validator, err := validation.ValidatorOf(boardMatcher)
+	if err != nil {
+		panic(err)
+	}
+
 	awsRegion, awsRegionExists := os.LookupEnv("AWS_REGION")
 	if !awsRegionExists {
 		panic(errors.New("AWS_REGION is missing"))
 	}
 
 	registrar := SearchRegistrar{
-		userTableName:       userTableName,
-		archivesTableName:   archivesTableName,
-		searchesTableName:   searchesTableName,
-		searchBoardQueueUrl: searchBoardQueueUrl,
+		userTableName:            userTableName,
+		usersByPlayerIdIndexName: usersByPlayerIdIndexName,
+		archivesTableName:        archivesTableName,
+		gamesTableName:           gamesTableName,
+		searchesTableName:        searchesTableName,
+		searchBoardQueueUrl:      searchBoardQueueUrl,
 		awsConfig: &aws.Config{
 			Region: &awsRegion,
 		},
+		validator: validator,
 	}
 
 	lambda.Start(api.WithRecover(registrar.RegisterSearchRequest))
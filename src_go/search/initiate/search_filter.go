@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+)
+
+const filterDateLayout = "2006-01-02"
+
+func InvalidFilter(msg string) api.ValidationError {
+	return api.ValidationError{
+		Msg: fmt.Sprintf("Invalid filter: %v", msg),
+	}
+}
+
+// filterOf builds the filter of the request for the games of the user, there is no filter if the request has none
+func filterOf(searchRequest SearchRequest, username string) (filter *queue.SearchFilter, err error) {
+	if searchRequest.From == "" && searchRequest.To == "" && searchRequest.Color == "" &&
+		searchRequest.Opponent == "" && searchRequest.Result == "" && searchRequest.TimeClass == "" {
+		return
+	}
+
+	searchFilter := queue.SearchFilter{
+		Username:  username,
+		Color:     searchRequest.Color,
+		Opponent:  searchRequest.Opponent,
+		Result:    searchRequest.Result,
+		TimeClass: searchRequest.TimeClass,
+	}
+
+	if searchRequest.From != "" {
+		from, errOfParsing := time.Parse(filterDateLayout, searchRequest.From)
+		if errOfParsing != nil {
+			err = InvalidFilter(fmt.Sprintf("from %v is not a date like 2023-10-01", searchRequest.From))
+			return
+		}
+		searchFilter.From = from.Unix()
+	}
+
+	if searchRequest.To != "" {
+		to, errOfParsing := time.Parse(filterDateLayout, searchRequest.To)
+		if errOfParsing != nil {
+			err = InvalidFilter(fmt.Sprintf("to %v is not a date like 2023-10-01", searchRequest.To))
+			return
+		}
+		// the games of the last day are included
+		searchFilter.To = to.AddDate(0, 0, 1).Unix()
+	}
+
+	if searchFilter.From != 0 && searchFilter.To != 0 && searchFilter.From >= searchFilter.To {
+		err = InvalidFilter(fmt.Sprintf("from %v is after to %v", searchRequest.From, searchRequest.To))
+		return
+	}
+
+	switch searchFilter.Color {
+	case "", games.White, games.Black:
+	default:
+		err = InvalidFilter(fmt.Sprintf("color %v is neither white nor black", searchFilter.Color))
+		return
+	}
+
+	switch searchFilter.Result {
+	case "", games.Win, games.Draw, games.Loss:
+	default:
+		err = InvalidFilter(fmt.Sprintf("result %v is none of win, draw and loss", searchFilter.Result))
+		return
+	}
+
+	switch searchFilter.TimeClass {
+	case "", games.Bullet, games.Blitz, games.Rapid, games.Daily:
+	default:
+		err = InvalidFilter(fmt.Sprintf("time class %v is none of bullet, blitz, rapid and daily", searchFilter.TimeClass))
+		return
+	}
+
+	filter = &searchFilter
+	return
+}
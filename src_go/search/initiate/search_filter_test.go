@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_filterOf_should_be_nil_if_the_request_has_no_filter(t *testing.T) {
+	filter, err := filterOf(SearchRequest{Username: "tigran-c-137", Variant: "chess960"}, "tigran-c-137")
+	assert.NoError(t, err)
+	assert.Nil(t, filter)
+}
+
+func Test_filterOf_should_include_the_whole_last_day(t *testing.T) {
+	searchRequest := SearchRequest{
+		From:      "2022-08-01",
+		To:        "2022-08-31",
+		Color:     "black",
+		Opponent:  "Garry",
+		Result:    "win",
+		TimeClass: "blitz",
+	}
+
+	filter, err := filterOf(searchRequest, "tigran-c-137")
+	assert.NoError(t, err)
+
+	expectedFilter := &queue.SearchFilter{
+		Username:  "tigran-c-137",
+		From:      1659312000,
+		To:        1661990400,
+		Color:     "black",
+		Opponent:  "Garry",
+		Result:    "win",
+		TimeClass: "blitz",
+	}
+	assert.Equal(t, expectedFilter, filter)
+}
+
+func Test_filterOf_should_reject_unknown_values(t *testing.T) {
+	_, err := filterOf(SearchRequest{From: "2022-08"}, "tigran-c-137")
+	assert.Equal(t, api.ValidationError{Msg: "Invalid filter: from 2022-08 is not a date like 2023-10-01"}, err)
+
+	_, err = filterOf(SearchRequest{From: "2022-09-01", To: "2022-08-01"}, "tigran-c-137")
+	assert.Equal(t, api.ValidationError{Msg: "Invalid filter: from 2022-09-01 is after to 2022-08-01"}, err)
+
+	_, err = filterOf(SearchRequest{Color: "red"}, "tigran-c-137")
+	assert.Equal(t, api.ValidationError{Msg: "Invalid filter: color red is neither white nor black"}, err)
+
+	_, err = filterOf(SearchRequest{Result: "1-0"}, "tigran-c-137")
+	assert.Equal(t, api.ValidationError{Msg: "Invalid filter: result 1-0 is none of win, draw and loss"}, err)
+
+	_, err = filterOf(SearchRequest{TimeClass: "classical"}, "tigran-c-137")
+	assert.Equal(t, api.ValidationError{Msg: "Invalid filter: time class classical is none of bullet, blitz, rapid and daily"}, err)
+}
@@ -10,17 +10,54 @@ type SearchRequest struct {
 	Username string `json:"username"`
 	Platform string `json:"platform"`
 	Board    string `json:"board"`
+	// Variant is optional, only the standard games are searched without it
+	Variant string `json:"variant,omitempty"`
+	// the filters are optional, From and To are the dates the games end within, both are included
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	Color     string `json:"color,omitempty"`
+	Opponent  string `json:"opponent,omitempty"`
+	Result    string `json:"result,omitempty"`
+	TimeClass string `json:"timeClass,omitempty"`
+	// Limit is optional, the search stops once that many games are matched, 10 without it
+	Limit int `json:"limit,omitempty"`
+	// CallbackUrl is optional, the final status of the search is posted there once the search is over
+	CallbackUrl string `json:"callbackUrl,omitempty"`
 }
 
 type SearchResponse struct {
 	SearchId string `json:"searchId"`
+	// CallbackSecret verifies the signatures of the callbacks, it is given only together with a callback url
+	CallbackSecret string `json:"callbackSecret,omitempty"`
 }
 
+// MaxMatchLimit keeps a single search from examining the whole history of a profile only to collect its matches
+const MaxMatchLimit = 1000
+
 var InvalidSearchBoard = api.BusinessError{
 	Code: "INVALID_SEARCH_BOARD",
 	Msg:  "Invalid board!",
 }
 
+func InvalidCallbackUrl(searchRequest SearchRequest) api.ValidationError {
+	return api.ValidationError{
+		Msg: fmt.Sprintf("Callback url %v has to be an absolute https url of a public host!", searchRequest.CallbackUrl),
+	}
+}
+
+func InvalidMatchLimit(searchRequest SearchRequest) api.ValidationError {
+	return api.ValidationError{
+		Msg: fmt.Sprintf("Limit %v has to be between 1 and %v, or omitted for the default one!", searchRequest.Limit, MaxMatchLimit),
+	}
+}
+
+func NoGameMatchesFilter(username string) api.BusinessError {
+	return api.BusinessError{
+		Msg:  fmt.Sprintf("Profile %v does not have any game that passes the filter!", username),
+		Code: "NO_GAME_MATCHES_FILTER",
+	}
+}
+
 func ProfileIsNotCached(username string, platform string) api.BusinessError {
 	return api.BusinessError{
 		Code: "PROFILE_IS_NOT_CACHED",
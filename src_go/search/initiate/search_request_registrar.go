@@ -2,6 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -11,7 +15,9 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/callback"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/archives"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/searches"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/users"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
@@ -23,11 +29,14 @@ import (
 )
 
 type SearchRegistrar struct {
-	userTableName       string
-	archivesTableName   string
-	searchesTableName   string
-	searchBoardQueueUrl string
-	awsConfig           *aws.Config
+	userTableName            string
+	usersByPlayerIdIndexName string
+	archivesTableName        string
+	gamesTableName           string
+	searchesTableName        string
+	searchBoardQueueUrl      string
+	awsConfig                *aws.Config
+	validator                validation.Validator
 }
 
 func (registrar *SearchRegistrar) RegisterSearchRequest(event *events.APIGatewayV2HTTPRequest) (responseEvent events.APIGatewayV2HTTPResponse, err error) {
@@ -74,8 +83,11 @@ func (registrar *SearchRegistrar) RegisterSearchRequest(event *events.APIGateway
 	logger = logger.With(zap.String("username", searchRequest.Username), zap.String("platform", searchRequest.Platform))
 	logger = logger.With(zap.String("board", searchRequest.Board))
 
+	variant := games.RulesOf(searchRequest.Variant)
+	logger = logger.With(zap.String("variant", variant))
+
 	logger.Info("validating board")
-	if isValid, strangeError := validation.ValidateBoard(searchRequest.Board); !isValid || strangeError != nil {
+	if isValid, strangeError := registrar.validator.ValidateBoard(searchRequest.Board); !isValid || strangeError != nil {
 		logger.Info("invalid board")
 		if strangeError != nil {
 			logger.Error("error while validating board", zap.Error(strangeError))
@@ -84,6 +96,24 @@ func (registrar *SearchRegistrar) RegisterSearchRequest(event *events.APIGateway
 		return
 	}
 
+	filter, err := filterOf(searchRequest, searchRequest.Username)
+	if err != nil {
+		logger.Info("invalid filter", zap.Error(err))
+		return
+	}
+
+	if searchRequest.Limit < 0 || searchRequest.Limit > MaxMatchLimit {
+		logger.Info("invalid limit", zap.Int("limit", searchRequest.Limit))
+		err = InvalidMatchLimit(searchRequest)
+		return
+	}
+
+	if searchRequest.CallbackUrl != "" && callback.ValidateUrl(searchRequest.CallbackUrl) != nil {
+		logger.Info("invalid callback url", zap.String("callbackUrl", searchRequest.CallbackUrl))
+		err = InvalidCallbackUrl(searchRequest)
+		return
+	}
+
 	logger.Info("fetching user from db", zap.String("user", searchRequest.Username))
 	user, err := registrar.getUserRecord(searchRequest, logger, dynamodbClient)
 	if err != nil {
@@ -92,6 +122,13 @@ func (registrar *SearchRegistrar) RegisterSearchRequest(event *events.APIGateway
 
 	logger = logger.With(zap.String("userId", user.UserId))
 
+	if filter != nil {
+		filter.Aliases, err = registrar.getAliases(user, logger, dynamodbClient)
+		if err != nil {
+			return
+		}
+	}
+
 	logger.Info("fetching archives from db")
 	archives, err := registrar.getArchiveRecords(user, logger, dynamodbClient)
 	if err != nil {
@@ -99,8 +136,13 @@ func (registrar *SearchRegistrar) RegisterSearchRequest(event *events.APIGateway
 	}
 
 	downloadedGames := 0
+	// every archive with games is a shard of the search, the shards are searched in parallel
+	shardArchiveIds := []string{}
 	for _, archive := range archives {
 		downloadedGames += archive.Downloaded
+		if archive.Downloaded > 0 {
+			shardArchiveIds = append(shardArchiveIds, archive.ArchiveId)
+		}
 	}
 
 	if downloadedGames == 0 {
@@ -109,11 +151,34 @@ func (registrar *SearchRegistrar) RegisterSearchRequest(event *events.APIGateway
 		return
 	}
 
+	total := downloadedGames
+	if filter != nil {
+		logger.Info("counting the games that pass the filter")
+		total, err = registrar.countFilteredGames(user, shardArchiveIds, *filter, logger, dynamodbClient)
+		if err != nil {
+			return
+		}
+		if total == 0 {
+			logger.Info("no game passes the filter")
+			err = NoGameMatchesFilter(user.Username)
+			return
+		}
+	}
+
 	searchId := uuid.New().String()
 	logger = logger.With(zap.String("searchResultId", searchId))
 	now := time.Now()
 
-	searchResult := searches.NewSearchRecord(searchId, now, downloadedGames)
+	searchResult := searches.NewSearchRecord(searchId, now, total)
+	searchResult.Shards = len(shardArchiveIds)
+	if searchRequest.CallbackUrl != "" {
+		searchResult.CallbackUrl = searchRequest.CallbackUrl
+		searchResult.CallbackSecret, err = callback.NewSecret()
+		if err != nil {
+			logger.Error("impossible to generate the callback secret!", zap.Error(err))
+			return
+		}
+	}
 
 	logger.Info("putting search result")
 	err = registrar.persistSearchRecord(dynamodbClient, logger, searchResult)
@@ -121,36 +186,47 @@ func (registrar *SearchRegistrar) RegisterSearchRequest(event *events.APIGateway
 		return
 	}
 
-	logger.Info("sending search board command")
-
-	searchBoardCommand := queue.SearchBoardCommand{
-		UserId:   user.UserId,
-		SearchId: searchId,
-		Board:    searchRequest.Board,
-	}
-
-	searchBoardCommandJson, err := json.Marshal(searchBoardCommand)
-	if err != nil {
-		logger.Error("error while marshalling search board command")
-	}
+	logger.Info("sending search board commands", zap.Int("shards", len(shardArchiveIds)))
+
+	for shard, archiveId := range shardArchiveIds {
+		searchBoardCommand := queue.SearchBoardCommand{
+			UserId:     user.UserId,
+			SearchId:   searchId,
+			Board:      searchRequest.Board,
+			Variant:    variant,
+			MatchLimit: searchRequest.Limit,
+			Filter:     filter,
+			ArchiveId:  archiveId,
+		}
 
-	sendMessageInput := &sqs.SendMessageInput{
-		MessageBody: aws.String(string(searchBoardCommandJson)),
-		QueueUrl:    aws.String(registrar.searchBoardQueueUrl),
-		//fixme this should be the boeard, but that makes the test flaky. in test we need to wait for the message to be processed and forgotten by SQS. To overcome this we should generate valid boear each time. That will break the restriction of deduplication.
-		MessageDeduplicationId: aws.String(searchBoardCommand.SearchId),
-		MessageGroupId:         aws.String(user.UserId),
-	}
+		var searchBoardCommandJson []byte
+		searchBoardCommandJson, err = json.Marshal(searchBoardCommand)
+		if err != nil {
+			logger.Error("error while marshalling search board command", zap.Error(err))
+			return
+		}
 
-	_, err = svc.SendMessage(sendMessageInput)
-	if err != nil {
-		logger.Error("error while sending search board command")
+		// every shard is a message group of its own, so the shards do not wait for each other
+		shardId := fmt.Sprintf("%v#%d", searchId, shard)
+		_, err = svc.SendMessage(&sqs.SendMessageInput{
+			MessageBody:            aws.String(string(searchBoardCommandJson)),
+			QueueUrl:               aws.String(registrar.searchBoardQueueUrl),
+			MessageDeduplicationId: aws.String(shardId),
+			MessageGroupId:         aws.String(shardId),
+		})
+		if err != nil {
+			logger.Error("error while sending search board command", zap.String("archiveId", archiveId), zap.Error(err))
+			// the search is not left in progress waiting for the shards that were never sent
+			registrar.stopUnsentShards(dynamodbClient, logger, searchId, shard, len(shardArchiveIds)-shard)
+			return
+		}
 	}
 
-	logger.Info("search board command sent")
+	logger.Info("search board commands sent")
 
 	searchResponse := SearchResponse{
-		SearchId: searchId,
+		SearchId:       searchId,
+		CallbackSecret: searchResult.CallbackSecret,
 	}
 
 	searchResponseJson, err := json.Marshal(searchResponse)
@@ -174,34 +250,40 @@ func (registrar *SearchRegistrar) getUserRecord(
 	logger *zap.Logger,
 	dynamodbClient *dynamodb.DynamoDB,
 ) (user users.UserRecord, err error) {
-	getItemOutput, err := dynamodbClient.GetItem(
-		&dynamodb.GetItemInput{
-			TableName: aws.String(registrar.userTableName),
-			Key: map[string]*dynamodb.AttributeValue{
-				"username": {
-					S: aws.String(searchRequest.Username),
-				},
-				"platform": {
-					S: aws.String(string(searchRequest.Platform)),
-				},
-			},
-		},
-	)
-
+	user, isFound, err := users.GetUserRecord(dynamodbClient, registrar.userTableName, searchRequest.Username, users.Platform(searchRequest.Platform))
 	if err != nil {
 		logger.Error("error while getting user from db", zap.Error(err))
 		return
 	}
-	if len(getItemOutput.Item) == 0 {
+	if !isFound {
 		err = ProfileIsNotCached(searchRequest.Username, searchRequest.Platform)
 		logger.Info("profile is not cached")
 		return
 	}
-	err = dynamodbattribute.UnmarshalMap(getItemOutput.Item, &user)
+	return
+}
+
+// getAliases finds the other usernames of a renamed player, the filter tells the games played under them for the player too
+func (registrar *SearchRegistrar) getAliases(
+	user users.UserRecord,
+	logger *zap.Logger,
+	dynamodbClient *dynamodb.DynamoDB,
+) (aliases []string, err error) {
+	if user.PlayerId == 0 {
+		return
+	}
+
+	aliasRecords, err := users.GetAliases(dynamodbClient, registrar.userTableName, registrar.usersByPlayerIdIndexName, user.PlayerId)
 	if err != nil {
-		logger.Error("error while unmarshalling user from db", zap.Error(err))
+		logger.Error("error while getting aliases of the user from db", zap.Error(err))
 		return
 	}
+
+	for _, alias := range aliasRecords {
+		if alias.UserId == user.UserId && !strings.EqualFold(alias.Username, user.Username) {
+			aliases = append(aliases, alias.Username)
+		}
+	}
 	return
 }
 
@@ -235,6 +317,69 @@ func (registrar *SearchRegistrar) getArchiveRecords(
 	return
 }
 
+// countFilteredGames reads only the metadata of the games of the user, the total of a filtered search
+// counts only the games the finder examines, so only the games of the archives that are shards of the search.
+// The games of other variants are counted as in the total of a search without a filter
+func (registrar *SearchRegistrar) countFilteredGames(
+	user users.UserRecord,
+	shardArchiveIds []string,
+	filter queue.SearchFilter,
+	logger *zap.Logger,
+	dynamodbClient *dynamodb.DynamoDB,
+) (total int, err error) {
+	isShard := make(map[string]bool, len(shardArchiveIds))
+	for _, archiveId := range shardArchiveIds {
+		isShard[archiveId] = true
+	}
+
+	var lastKey map[string]*dynamodb.AttributeValue
+	for {
+		var queryOutput *dynamodb.QueryOutput
+		queryOutput, err = dynamodbClient.Query(&dynamodb.QueryInput{
+			TableName:              aws.String(registrar.gamesTableName),
+			KeyConditionExpression: aws.String("user_id = :user_id"),
+			ProjectionExpression:   aws.String("#archive_id, #end_timestamp, #white, #black, #result, #time_class"),
+			ExpressionAttributeNames: map[string]*string{
+				"#archive_id":    aws.String("archive_id"),
+				"#end_timestamp": aws.String("end_timestamp"),
+				"#white":         aws.String("white"),
+				"#black":         aws.String("black"),
+				"#result":        aws.String("result"),
+				"#time_class":    aws.String("time_class"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":user_id": {
+					S: aws.String(user.UserId),
+				},
+			},
+			ExclusiveStartKey: lastKey,
+		})
+		if err != nil {
+			logger.Error("error while getting games from db", zap.Error(err))
+			return
+		}
+
+		gameRecords := []games.GameRecord{}
+		err = dynamodbattribute.UnmarshalListOfMaps(queryOutput.Items, &gameRecords)
+		if err != nil {
+			logger.Error("error while unmarshalling games from db", zap.Error(err))
+			return
+		}
+
+		for _, gameRecord := range gameRecords {
+			if isShard[gameRecord.ArchiveId] && games.Filter(filter).Passes(gameRecord) {
+				total++
+			}
+		}
+
+		lastKey = queryOutput.LastEvaluatedKey
+		if len(lastKey) == 0 {
+			break
+		}
+	}
+	return
+}
+
 func (registrar *SearchRegistrar) persistSearchRecord(
 	dynamodbClient *dynamodb.DynamoDB,
 	logger *zap.Logger,
@@ -259,3 +404,82 @@ func (registrar *SearchRegistrar) persistSearchRecord(
 
 	return
 }
+
+// stopUnsentShards shrinks the search to the shards that were sent and counts the others as stopped, so the search
+// is searched partially once the sent shards are over. The search is finished here if they are already over
+func (registrar *SearchRegistrar) stopUnsentShards(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	searchId string,
+	sent int,
+	unsent int,
+) {
+	searchRecordItems, err := dynamodbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(registrar.searchesTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"search_id": {
+				S: aws.String(searchId),
+			},
+		},
+		UpdateExpression: aws.String("SET shards = :sent ADD stopped_shards :unsent"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":sent": {
+				N: aws.String(strconv.Itoa(sent)),
+			},
+			":unsent": {
+				N: aws.String(strconv.Itoa(unsent)),
+			},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		logger.Error("error while stopping the unsent shards", zap.Error(err))
+		return
+	}
+
+	searchRecord := searches.SearchRecord{}
+	err = dynamodbattribute.UnmarshalMap(searchRecordItems.Attributes, &searchRecord)
+	if err != nil {
+		logger.Error("error while unmarshalling search record", zap.Error(err))
+		return
+	}
+
+	if searchRecord.ShardsDone < searchRecord.Shards {
+		logger.Info("search is finished by its last sent shard", zap.Int("sent", sent), zap.Int("unsent", unsent))
+		return
+	}
+
+	_, err = dynamodbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(registrar.searchesTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"search_id": {
+				S: aws.String(searchId),
+			},
+		},
+		UpdateExpression:    aws.String("SET #status = :status"),
+		ConditionExpression: aws.String("shards_done = shards AND #status = :inProgress"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":status": {
+				S: aws.String(string(searches.SearchedPartially)),
+			},
+			":inProgress": {
+				S: aws.String(string(searches.InProgress)),
+			},
+		},
+	})
+
+	var conditionalCheckFailed *dynamodb.ConditionalCheckFailedException
+	if errors.As(err, &conditionalCheckFailed) {
+		logger.Info("search is already finished by its shards")
+		return
+	}
+	if err != nil {
+		logger.Error("error while finishing the search", zap.Error(err))
+		return
+	}
+
+	logger.Info("search is finished without the unsent shards", zap.Int("unsent", unsent))
+}
@@ -18,11 +18,14 @@ import (
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/api"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/archives"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/searches"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/users"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/search/initiate/validation"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
 var awsConfig = aws.Config{
@@ -32,11 +35,14 @@ var awsConfig = aws.Config{
 }
 
 var registrar = SearchRegistrar{
-	userTableName:       "chessfinder_dynamodb-users",
-	archivesTableName:   "chessfinder_dynamodb-archives",
-	searchesTableName:   "chessfinder_dynamodb-searches",
-	searchBoardQueueUrl: "http://localhost:4566/000000000000/chessfinder_sqs-SearchBoard.fifo",
-	awsConfig:           &awsConfig,
+	userTableName:            "chessfinder_dynamodb-users",
+	usersByPlayerIdIndexName: "chessfinder_dynamodb-usersByPlayerId",
+	archivesTableName:        "chessfinder_dynamodb-archives",
+	gamesTableName:           "chessfinder_dynamodb-games",
+	searchesTableName:        "chessfinder_dynamodb-searches",
+	searchBoardQueueUrl:      "http://localhost:4566/000000000000/chessfinder_sqs-SearchBoard.fifo",
+	awsConfig:                &awsConfig,
+	validator:                validation.CoreValidator{},
 }
 var awsSession = session.Must(session.NewSession(&awsConfig))
 var dynamodbClient = dynamodb.New(awsSession)
@@ -116,6 +122,136 @@ func Test_SearchRegistrar_should_emit_SearchBoardCommand_for_an_existing_user_an
 	assert.Equal(t, int(0), actualSearchRecord.Examined, "Examined is not equal!")
 	assert.Equal(t, int(40), actualSearchRecord.Total, "Total is not equal!")
 	assert.Nil(t, actualSearchRecord.Matched, "Matched is not equal!")
+	assert.Equal(t, int(2), actualSearchRecord.Shards, "Shards are not equal!")
+
+	actualCommands, err := getCommands(svc, registrar)
+	assert.NoError(t, err)
+
+	expectedCommands := []queue.SearchBoardCommand{
+		{
+			UserId:    userId,
+			SearchId:  actualSearchResultResponse.SearchId,
+			Board:     "????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????",
+			Variant:   games.StandardRules,
+			ArchiveId: archive1Resource,
+		},
+		{
+			UserId:    userId,
+			SearchId:  actualSearchResultResponse.SearchId,
+			Board:     "????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????",
+			Variant:   games.StandardRules,
+			ArchiveId: archive2Resource,
+		},
+	}
+
+	assert.ElementsMatch(t, expectedCommands, actualCommands, "Commands are not equal!")
+
+}
+
+func Test_SearchRegistrar_should_count_only_the_games_that_pass_the_filter(t *testing.T) {
+	var err error
+	if !testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// the player has been renamed, the games played before are stored under the previous username
+	previousUsername := uuid.New().String()
+	username := uuid.New().String()
+	userId := fmt.Sprintf("https://api.chess.com/pub/player/%v", previousUsername)
+	playerId := time.Now().UnixNano()
+	for _, alias := range []string{previousUsername, username} {
+		err = persistUserRecord(dynamodbClient, registrar, users.UserRecord{
+			UserId:   userId,
+			Username: alias,
+			Platform: users.ChessDotCom,
+			PlayerId: playerId,
+		})
+		assert.NoError(t, err)
+	}
+
+	archiveResource := fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2022/08", previousUsername)
+	archiveDownloadedAt := db.Zuludatetime(time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC))
+	archive := archives.ArchiveRecord{
+		UserId:       userId,
+		ArchiveId:    archiveResource,
+		Resource:     archiveResource,
+		Year:         2022,
+		Month:        8,
+		DownloadedAt: &archiveDownloadedAt,
+		Downloaded:   5,
+	}
+
+	err = persistArchiveRecords(dynamodbClient, registrar, archive)
+	assert.NoError(t, err)
+
+	// the archive that is still downloaded is not a shard of the search, its games are not counted in the total
+	downloadingArchiveResource := fmt.Sprintf("https://api.chess.com/pub/player/%v/games/2022/09", previousUsername)
+	downloadingArchive := archives.ArchiveRecord{
+		UserId:     userId,
+		ArchiveId:  downloadingArchiveResource,
+		Resource:   downloadingArchiveResource,
+		Year:       2022,
+		Month:      9,
+		Downloaded: 0,
+	}
+
+	err = persistArchiveRecords(dynamodbClient, registrar, downloadingArchive)
+	assert.NoError(t, err)
+
+	err = persistGameRecord(dynamodbClient, registrar, games.GameRecord{
+		UserId:       userId,
+		ArchiveId:    downloadingArchiveResource,
+		GameId:       "https://www.chess.com/game/live/5",
+		Resource:     "https://www.chess.com/game/live/5",
+		White:        username,
+		Black:        "Garry",
+		Result:       "1-0",
+		TimeClass:    games.Blitz,
+		EndTimestamp: 1662109845,
+		Pgn:          "1. e4 e5 1-0",
+	})
+	assert.NoError(t, err)
+
+	gameRecords := []games.GameRecord{
+		{GameId: "https://www.chess.com/game/live/1", White: username, Black: "Garry", Result: "1-0", TimeClass: games.Blitz, EndTimestamp: 1659431445},
+		{GameId: "https://www.chess.com/game/live/2", White: "Garry", Black: username, Result: "1-0", TimeClass: games.Blitz, EndTimestamp: 1659431445},
+		{GameId: "https://www.chess.com/game/live/3", White: username, Black: "Garry", Result: "1-0", TimeClass: games.Rapid, EndTimestamp: 1659431445},
+		{GameId: "https://www.chess.com/game/live/4", White: previousUsername, Black: "Garry", Result: "1-0", TimeClass: games.Blitz, EndTimestamp: 1659431445},
+		// the games of other variants are counted as in the total of a search without a filter
+		{GameId: "https://www.chess.com/game/live/6", White: username, Black: "Garry", Result: "1-0", TimeClass: games.Blitz, Rules: "chess960", EndTimestamp: 1659431445},
+	}
+	for _, gameRecord := range gameRecords {
+		gameRecord.UserId = userId
+		gameRecord.ArchiveId = archiveResource
+		gameRecord.Resource = gameRecord.GameId
+		gameRecord.Pgn = "1. e4 e5 1-0"
+		err = persistGameRecord(dynamodbClient, registrar, gameRecord)
+		assert.NoError(t, err)
+	}
+
+	event := events.APIGatewayV2HTTPRequest{
+		Body: fmt.Sprintf(`{"username":"%v", "platform": "CHESS_DOT_COM", "board": "????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????", "color": "white", "timeClass": "blitz"}`, username),
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "POST",
+				Path:   "/api/faster/board",
+			},
+		},
+	}
+
+	actualResponse, err := registrar.RegisterSearchRequest(&event)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, actualResponse.StatusCode, "Response status code is not 200!")
+
+	actualSearchResultResponse := SearchResponse{}
+	err = json.Unmarshal([]byte(actualResponse.Body), &actualSearchResultResponse)
+	assert.NoError(t, err)
+
+	actualSearchRecord, err := getSearchRecord(dynamodbClient, registrar, actualSearchResultResponse.SearchId)
+	assert.NoError(t, err)
+
+	assert.Equal(t, int(3), actualSearchRecord.Total, "Total is not equal!")
 
 	actualCommand, err := getTheLastCommand(svc, registrar)
 	assert.NoError(t, err)
@@ -124,10 +260,17 @@ func Test_SearchRegistrar_should_emit_SearchBoardCommand_for_an_existing_user_an
 		UserId:   userId,
 		SearchId: actualSearchResultResponse.SearchId,
 		Board:    "????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????",
+		Variant:  games.StandardRules,
+		Filter: &queue.SearchFilter{
+			Username:  username,
+			Aliases:   []string{previousUsername},
+			Color:     games.White,
+			TimeClass: games.Blitz,
+		},
+		ArchiveId: archiveResource,
 	}
 
 	assert.Equal(t, expectedCommand, *actualCommand, "Commands are not equal!")
-
 }
 
 func Test_SearchRegistrar_not_should_emit_SearchBoardCommand_for_an_existing_user_if_there_are_no_cached_archives(t *testing.T) {
@@ -213,6 +356,68 @@ func Test_SearchRegistrar_should_not_emit_SearchBoardCommand_for_an_invalid_sear
 	assert.Equal(t, 0, amountOfCommands, "Amount of commands is not equal!")
 }
 
+func Test_SearchRegistrar_should_not_emit_SearchBoardCommand_for_an_invalid_callback_url(t *testing.T) {
+	var err error
+
+	if !testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	username := uuid.New().String()
+
+	event := events.APIGatewayV2HTTPRequest{
+		Body: fmt.Sprintf(`{"username":"%v", "platform": "CHESS_DOT_COM", "board": "????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????", "callbackUrl": "localhost/done"}`, username),
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "POST",
+				Path:   "/api/faster/board",
+			},
+		},
+	}
+
+	actualResponse, err := api.WithRecover(registrar.RegisterSearchRequest)(&event)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusBadRequest, actualResponse.StatusCode, "Response status code is not 400!")
+	assert.Equal(t, "Callback url localhost/done has to be an absolute https url of a public host!", actualResponse.Body, "Response body is not equal!")
+
+	amountOfCommands, err := countCommands(svc, registrar)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, amountOfCommands, "Amount of commands is not equal!")
+}
+
+func Test_SearchRegistrar_should_not_emit_SearchBoardCommand_for_a_limit_above_the_maximum(t *testing.T) {
+	var err error
+
+	if !testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	username := uuid.New().String()
+
+	event := events.APIGatewayV2HTTPRequest{
+		Body: fmt.Sprintf(`{"username":"%v", "platform": "CHESS_DOT_COM", "board": "????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????", "limit": 1001}`, username),
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "POST",
+				Path:   "/api/faster/board",
+			},
+		},
+	}
+
+	actualResponse, err := api.WithRecover(registrar.RegisterSearchRequest)(&event)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusBadRequest, actualResponse.StatusCode, "Response status code is not 400!")
+	assert.Equal(t, "Limit 1001 has to be between 1 and 1000, or omitted for the default one!", actualResponse.Body, "Response body is not equal!")
+
+	amountOfCommands, err := countCommands(svc, registrar)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, amountOfCommands, "Amount of commands is not equal!")
+}
+
 func Test_SearchRegistrar_should_not_emit_SearchBoardCommand_for_a_non_existing_user(t *testing.T) {
 	var err error
 
@@ -246,6 +451,29 @@ func Test_SearchRegistrar_should_not_emit_SearchBoardCommand_for_a_non_existing_
 	assert.Equal(t, 0, amountOfCommands, "Amount of commands is not equal!")
 }
 
+func Test_SearchRegistrar_should_finish_the_search_partially_if_the_sent_shards_are_over_before_the_others_are_stopped(t *testing.T) {
+	if !testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	logger := zap.NewNop()
+	searchId := uuid.New().String()
+	searchRecord := searches.NewSearchRecord(searchId, time.Now(), 40)
+	searchRecord.Shards = 3
+	searchRecord.ShardsDone = 1
+
+	err := registrar.persistSearchRecord(dynamodbClient, logger, searchRecord)
+	assert.NoError(t, err)
+
+	registrar.stopUnsentShards(dynamodbClient, logger, searchId, 1, 2)
+
+	actualSearchRecord, err := getSearchRecord(dynamodbClient, registrar, searchId)
+	assert.NoError(t, err)
+	assert.Equal(t, searches.SearchedPartially, actualSearchRecord.Status, "Search status is not equal!")
+	assert.Equal(t, int(1), actualSearchRecord.Shards, "Shards are not equal!")
+	assert.Equal(t, int(2), actualSearchRecord.StoppedShards, "Stopped shards are not equal!")
+}
+
 func persistUserRecord(dynamodbClient dynamodbiface.DynamoDBAPI, registrar SearchRegistrar, user users.UserRecord) (err error) {
 
 	userItem, err := dynamodbattribute.MarshalMap(user)
@@ -282,6 +510,25 @@ func persistArchiveRecords(dynamodbClient dynamodbiface.DynamoDBAPI, registrar S
 	return
 }
 
+func persistGameRecord(dynamodbClient dynamodbiface.DynamoDBAPI, registrar SearchRegistrar, game games.GameRecord) (err error) {
+
+	gameItem, err := dynamodbattribute.MarshalMap(game)
+	if err != nil {
+		return
+	}
+
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(registrar.gamesTableName),
+		Item:      gameItem,
+	})
+
+	if err != nil {
+		return
+	}
+
+	return
+}
+
 func getSearchRecord(dynamodbClient dynamodbiface.DynamoDBAPI, registrar SearchRegistrar, searchResultId string) (searchResult searches.SearchRecord, err error) {
 	searchRecordItems, err := dynamodbClient.GetItem(
 		&dynamodb.GetItemInput{
@@ -350,6 +597,38 @@ func getTheLastCommand(svc *sqs.SQS, registrar SearchRegistrar) (command *queue.
 	return
 }
 
+// getCommands drains the commands of the queue, the shards of a search are in different groups and come in any order
+func getCommands(svc *sqs.SQS, registrar SearchRegistrar) (commands []queue.SearchBoardCommand, err error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		var resp *sqs.ReceiveMessageOutput
+		resp, err = svc.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl:            &registrar.searchBoardQueueUrl,
+			MaxNumberOfMessages: aws.Int64(10),
+			VisibilityTimeout:   aws.Int64(1),
+			WaitTimeSeconds:     aws.Int64(1),
+		})
+		if err != nil {
+			return
+		}
+		for _, message := range resp.Messages {
+			_, err = svc.DeleteMessage(&sqs.DeleteMessageInput{
+				QueueUrl:      &registrar.searchBoardQueueUrl,
+				ReceiptHandle: message.ReceiptHandle,
+			})
+			if err != nil {
+				return
+			}
+			command := queue.SearchBoardCommand{}
+			err = json.Unmarshal([]byte(*message.Body), &command)
+			if err != nil {
+				return
+			}
+			commands = append(commands, command)
+		}
+	}
+	return
+}
+
 func countCommands(svc *sqs.SQS, registrar SearchRegistrar) (count int, err error) {
 	resp, err := svc.GetQueueAttributes(&sqs.GetQueueAttributesInput{
 		QueueUrl: &registrar.searchBoardQueueUrl,
@@ -1,3 +1,5 @@
+//go:build core
+
 package validation
 
 /*
@@ -9,34 +11,19 @@ package validation
 import "C"
 
 import (
-	"fmt"
-	"runtime/debug"
 	"unsafe"
-)
-
-func ValidateBoard(board string) (bool, error) {
-	debug.SetPanicOnFault(true)
-	var err error
 
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Println("Recovered in ValidateBoard", r)
-			err = fmt.Errorf("%v", r)
-		}
-	}()
-
-	var isolate *C.graal_isolate_t = nil
-	var thread *C.graal_isolatethread_t = nil
-
-	if C.graal_create_isolate(nil, &isolate, &thread) != 0 {
-		fmt.Println("Initialization error")
-		return false, err
-	}
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/isolate"
+)
 
-	defer C.graal_tear_down_isolate(thread)
-	cstr := C.CString(board)
-	defer C.free(unsafe.Pointer(cstr))
-	isValid := C.validate(thread, cstr)
+const isCoreLinked = true
 
-	return isValid != 0, err
+// ValidateBoard runs the core on a thread of the isolate pool, it is safe to call from many goroutines at once
+func ValidateBoard(board string) (isValid bool, err error) {
+	err = isolate.Call(func(thread unsafe.Pointer) {
+		cstr := C.CString(board)
+		defer C.free(unsafe.Pointer(cstr))
+		isValid = C.validate((*C.graal_isolatethread_t)(thread), cstr) != 0
+	})
+	return
 }
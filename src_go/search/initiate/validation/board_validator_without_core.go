@@ -0,0 +1,10 @@
+//go:build !core
+
+package validation
+
+// the core library is linked only by the builds with the core tag, the others validate with the native validator
+const isCoreLinked = false
+
+func ValidateBoard(board string) (bool, error) {
+	return false, ErrCoreNotLinked
+}
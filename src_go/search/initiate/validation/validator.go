@@ -0,0 +1,48 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chess"
+)
+
+const CoreValidatorName = "core"
+const NativeValidatorName = "native"
+
+var ErrCoreNotLinked = errors.New("the core is not linked, it is built in only with the core tag")
+
+// Validator tells whether a search fen is a board that can be searched
+type Validator interface {
+	ValidateBoard(board string) (bool, error)
+}
+
+// CoreValidator validates the board with the native library of the core
+type CoreValidator struct{}
+
+func (CoreValidator) ValidateBoard(board string) (bool, error) {
+	return ValidateBoard(board)
+}
+
+// NativeValidator validates the board as the core does, with the reader of search fens written in Go
+type NativeValidator struct{}
+
+func (NativeValidator) ValidateBoard(board string) (bool, error) {
+	return chess.Validate(board), nil
+}
+
+func ValidatorOf(name string) (validator Validator, err error) {
+	switch name {
+	case CoreValidatorName:
+		if !isCoreLinked {
+			err = ErrCoreNotLinked
+			return
+		}
+		validator = CoreValidator{}
+	case NativeValidatorName:
+		validator = NativeValidator{}
+	default:
+		err = fmt.Errorf("validator %v is not one of %v and %v", name, CoreValidatorName, NativeValidatorName)
+	}
+	return
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/search/initiate/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NativeValidator_should_accept_the_same_boards_as_CoreValidator(t *testing.T) {
+	if _, err := validation.ValidateBoard("????????/????????/????????/????????/????????/????????/????????/????????"); err != nil {
+		t.Skip("the core is not available: ", err)
+	}
+
+	boards := []string{
+		"",
+		"????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????",
+		"rnbqkbnr/pppppppp/--------/--------/--------/--------/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"  rnbqkbnr/pppppppp/--------/--------/--------/--------/PPPPPPPP/RNBQKBNR  ",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR",
+		"????????/????????/????????/???oo???/???OO???/???00???/????????/????????",
+		"????????/????????/????????/????????/????????/????????/????????/",
+		"????????/????????/????????/????????/????????/????????/????????/????????/",
+		"????????/????????/????????/????????/????????/????????/????????",
+		"????????/????????/????????/????????/????????/????????/????????/???????",
+		"????????/????????/????????/????????/????????/????????/????????/?????????",
+		"????????/????????/????????/xyz.....//????????/????????/????????/????????",
+		"????????/????????/????????/ÄÖÜäöüßé/????????/????????/????????/????????",
+		"????????/????????/????????/♔♕♖♗♘♙♚♛/????????/????????/????????/????????",
+	}
+
+	coreValidator := validation.CoreValidator{}
+	nativeValidator := validation.NativeValidator{}
+	for _, board := range boards {
+		isValidByCore, err := coreValidator.ValidateBoard(board)
+		assert.NoError(t, err)
+		isValidByNative, err := nativeValidator.ValidateBoard(board)
+		assert.NoError(t, err)
+		assert.Equal(t, isValidByCore, isValidByNative, "board %v", board)
+	}
+}
@@ -2,15 +2,17 @@ package main
 
 import (
 	"encoding/json"
-	"net/http"
+	"errors"
 	"strconv"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/searches"
@@ -21,12 +23,19 @@ import (
 )
 
 const MaxGamesPerRequest = 100
+
+// StopSearchIfFound is the limit of matches for the commands that do not tell their own
 const StopSearchIfFound = 10
 
 type BoardFinder struct {
-	searchesTableName string
-	gamesTableName    string
-	awsConfig         *aws.Config
+	searchesTableName            string
+	gamesTableName               string
+	gamesByEndTimestampIndexName string
+	searchMatchesTableName       string
+	searchShardsTableName        string
+	deliverCallbacksQueueUrl     string
+	awsConfig                    *aws.Config
+	matcher                      searcher.Matcher
 }
 
 func (finder *BoardFinder) Find(commands events.SQSEvent) (commandsProcessed events.SQSEventResponse, err error) {
@@ -48,20 +57,45 @@ func (finder *BoardFinder) Find(commands events.SQSEvent) (commandsProcessed eve
 		return
 	}
 	dynamodbClient := dynamodb.New(awsSession)
-	chessDotComClient := &http.Client{}
+	svc := sqs.New(awsSession)
 
 	logger.Info("Processing commands in total", zap.Int("commands", len(commands.Records)))
 
+	permanentFailures := 0
+	failedGroups := map[string]struct{}{}
 	for _, message := range commands.Records {
-		_, _ = finder.processSingle(&message, dynamodbClient, chessDotComClient, logger)
+		// commands of a message group are delivered in order, the ones after a failed command wait for it
+		messageGroupId := message.Attributes["MessageGroupId"]
+		if _, isGroupFailed := failedGroups[messageGroupId]; isGroupFailed {
+			commandsProcessed.BatchItemFailures = append(commandsProcessed.BatchItemFailures, events.SQSBatchItemFailure{
+				ItemIdentifier: message.MessageId,
+			})
+			continue
+		}
+
+		commandFailure, errOfCommand := finder.processSingle(&message, dynamodbClient, svc, logger)
+		if commandFailure != nil {
+			failedGroups[messageGroupId] = struct{}{}
+			commandsProcessed.BatchItemFailures = append(commandsProcessed.BatchItemFailures, *commandFailure)
+			continue
+		}
+		if errOfCommand != nil {
+			permanentFailures++
+		}
 	}
+
+	logger.Info(
+		"commands processed",
+		zap.Int("permanentFailures", permanentFailures),
+		zap.Int("redelivered", len(commandsProcessed.BatchItemFailures)),
+	)
 	return
 }
 
 func (finder *BoardFinder) processSingle(
 	message *events.SQSMessage,
 	dynamodbClient *dynamodb.DynamoDB,
-	chessDotComClient *http.Client,
+	svc *sqs.SQS,
 	logger *zap.Logger,
 ) (commandProcessed *events.SQSBatchItemFailure, err error) {
 	command := queue.SearchBoardCommand{}
@@ -73,7 +107,18 @@ func (finder *BoardFinder) processSingle(
 
 	logger = logger.With(zap.String("searchId", command.SearchId))
 	logger = logger.With(zap.String("userId", command.UserId))
-	logger = logger.With(zap.String("archiveId", command.Board))
+	logger = logger.With(zap.String("board", command.Board))
+	logger = logger.With(zap.String("archiveId", command.ArchiveId))
+	variant := command.Variant
+	if variant == "" {
+		variant = games.StandardRules
+	}
+	logger = logger.With(zap.String("variant", variant))
+	matchLimit := command.MatchLimit
+	if matchLimit == 0 {
+		matchLimit = StopSearchIfFound
+	}
+	logger = logger.With(zap.Int("matchLimit", matchLimit))
 	logger.Info("Processing command")
 
 	logger.Info("getting the search record")
@@ -89,6 +134,7 @@ func (finder *BoardFinder) processSingle(
 
 	if err != nil {
 		logger.Error("impossible to get the search record", zap.Error(err))
+		commandProcessed = &events.SQSBatchItemFailure{ItemIdentifier: message.MessageId}
 		return
 	}
 
@@ -105,16 +151,25 @@ func (finder *BoardFinder) processSingle(
 
 	if searchRecord.Status != searches.InProgress {
 		logger.Info("search already processed")
+		// the previous delivery could have finished the search without enqueuing its callback
+		err = finder.notifyIfFinished(dynamodbClient, svc, logger, command.SearchId)
+		if err != nil && !queue.IsLastReceive(message.Attributes["ApproximateReceiveCount"]) {
+			commandProcessed = &events.SQSBatchItemFailure{ItemIdentifier: message.MessageId}
+		}
 		return
 	}
 
+	if command.ArchiveId != "" {
+		return finder.processShard(message, command, searchRecord, variant, matchLimit, dynamodbClient, svc, logger)
+	}
+
 	getGamesAnalyseAndUpdateStatus := func(
 		logger *zap.Logger,
 		lastKey map[string]*dynamodb.AttributeValue,
 		examinedBefore int,
-		matchedBefore []string,
+		matchedBefore int,
 	) (
-		totalMatched []string,
+		totalMatched int,
 		nextKey map[string]*dynamodb.AttributeValue,
 		totalExamined int,
 		err error,
@@ -153,33 +208,16 @@ func (finder *BoardFinder) processSingle(
 			return
 		}
 
-		for _, gameRecord := range gameRecords {
-			isFound, errFromSearch := searcher.SearchBoard(command.Board, gameRecord.Pgn)
-			totalExamined++
-			if errFromSearch != nil {
-				logger.Error("impossible to search the board", zap.Error(errFromSearch))
-				isFound = false
-			}
-			if isFound {
-				totalMatched = append(totalMatched, gameRecord.Resource)
-			}
-			if len(totalMatched) >= StopSearchIfFound {
-				logger.Info("stopping the search because of the limit")
-				break
-			}
+		examined, matches := finder.examineGames(logger, command, variant, gameRecords, matchLimit-totalMatched)
+		totalExamined += examined
+		totalMatched += len(matches)
+		// the matches are stored before they are counted, so the count never points to missing matches
+		err = finder.persistMatches(dynamodbClient, logger, matches)
+		if err != nil {
+			return
 		}
-		logger = logger.With(zap.Int("examined", totalExamined))
+
 		logger.Info("updating the search record")
-		var totalMatchedDynamodbAttribute *dynamodb.AttributeValue
-		if len(totalMatched) > 0 {
-			totalMatchedDynamodbAttribute = &dynamodb.AttributeValue{
-				SS: aws.StringSlice(totalMatched),
-			}
-		} else {
-			totalMatchedDynamodbAttribute = &dynamodb.AttributeValue{
-				NULL: aws.Bool(true),
-			}
-		}
 
 		_, err = dynamodbClient.UpdateItem(&dynamodb.UpdateItemInput{
 			TableName: aws.String(finder.searchesTableName),
@@ -195,9 +233,11 @@ func (finder *BoardFinder) processSingle(
 				":lastExaminedAt": {
 					S: aws.String(now.String()),
 				},
-				":matched": totalMatchedDynamodbAttribute,
+				":matchedCount": {
+					N: aws.String(strconv.Itoa(totalMatched)),
+				},
 			},
-			UpdateExpression: aws.String("SET examined = :examined, last_examined_at = :lastExaminedAt, matched = :matched"),
+			UpdateExpression: aws.String("SET examined = :examined, last_examined_at = :lastExaminedAt, matched_count = :matchedCount"),
 		})
 
 		if err != nil {
@@ -209,7 +249,7 @@ func (finder *BoardFinder) processSingle(
 		return
 	}
 
-	matchedGames := []string{}
+	matchedGames := 0
 	var lastKey map[string]*dynamodb.AttributeValue
 
 	round := 0
@@ -223,7 +263,7 @@ func (finder *BoardFinder) processSingle(
 			break
 		}
 
-		if len(matchedGames) >= StopSearchIfFound {
+		if matchedGames >= matchLimit {
 			logger.Info("stopping the whole search because of the limit")
 			break
 		}
@@ -235,10 +275,16 @@ func (finder *BoardFinder) processSingle(
 		}
 	}
 
+	if errOfSerach != nil && isRetryable(errOfSerach) && !queue.IsLastReceive(message.Attributes["ApproximateReceiveCount"]) {
+		logger.Info("search will be repeated when the command is delivered again", zap.Error(errOfSerach))
+		commandProcessed = &events.SQSBatchItemFailure{ItemIdentifier: message.MessageId}
+		return
+	}
+
 	logger.Info("search finished")
 
 	searchStatus := searches.SearchedAll
-	if len(matchedGames) >= 10 || errOfSerach != nil {
+	if matchedGames >= matchLimit || errOfSerach != nil {
 		searchStatus = searches.SearchedPartially
 	}
 
@@ -264,10 +310,74 @@ func (finder *BoardFinder) processSingle(
 
 	if err != nil {
 		logger.Error("impossible to update the search record", zap.Error(err))
+		commandProcessed = &events.SQSBatchItemFailure{ItemIdentifier: message.MessageId}
 		return
 	}
 
 	logger.Info("search record updated")
 
+	err = finder.notifyIfFinished(dynamodbClient, svc, logger, command.SearchId)
+	if err != nil && !queue.IsLastReceive(message.Attributes["ApproximateReceiveCount"]) {
+		commandProcessed = &events.SQSBatchItemFailure{ItemIdentifier: message.MessageId}
+	}
+
+	return
+}
+
+// examineGames searches the board in the games till the matches reach the limit, the games that do not pass the filter
+// are not examined and the games of other variants are examined without being searched
+func (finder *BoardFinder) examineGames(
+	logger *zap.Logger,
+	command queue.SearchBoardCommand,
+	variant string,
+	gameRecords []games.GameRecord,
+	matchesLeft int,
+) (examined int, matches []searches.MatchRecord) {
+	skipped := 0
+	filteredOut := 0
+	matches = []searches.MatchRecord{}
+	for _, gameRecord := range gameRecords {
+		// the total of a filtered search counts only the games that pass the filter, of any variant as the total of the others
+		if command.Filter != nil && !games.Filter(*command.Filter).Passes(gameRecord) {
+			filteredOut++
+			continue
+		}
+		examined++
+		// the games of other variants are not replayed, the matcher does not know their rules
+		if gameRecord.Variant() != variant {
+			skipped++
+			continue
+		}
+		isFound, errFromSearch := finder.matcher.SearchBoard(command.Board, gameRecord.Pgn)
+		if errFromSearch != nil {
+			logger.Error("impossible to search the board", zap.Error(errFromSearch))
+			isFound = false
+		}
+		if isFound {
+			matchRecord := searches.NewMatchRecord(command.SearchId, gameRecord.GameId, gameRecord.Resource, gameRecord.EndTimestamp)
+			matchLocation, errOfLocation := locate(command.Board, gameRecord.Pgn, finder.matcher.SearchBoard)
+			if errOfLocation != nil {
+				logger.Warn("impossible to locate the board, the match is kept without its position", zap.String("gameId", gameRecord.GameId), zap.Error(errOfLocation))
+			} else {
+				matchRecord.Ply = matchLocation.ply
+				matchRecord.Fen = matchLocation.fen
+			}
+			matches = append(matches, matchRecord)
+		}
+		if len(matches) >= matchesLeft {
+			logger.Info("stopping the search because of the limit")
+			break
+		}
+	}
+	logger.Info("games examined", zap.Int("examined", examined), zap.Int("skippedOfOtherVariants", skipped), zap.Int("filteredOut", filteredOut))
 	return
 }
+
+// isRetryable tells whether the same command can succeed later, only storage failures are worth another delivery
+func isRetryable(err error) bool {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return awsErr.Code() != "ValidationException"
+	}
+	return false
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,10 +14,13 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/batcher"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
 	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/searches"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/search/process/searcher"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/wiremock/go-wiremock"
@@ -29,9 +33,14 @@ var awsConfig = aws.Config{
 }
 
 var finder = BoardFinder{
-	searchesTableName: "chessfinder_dynamodb-searches",
-	gamesTableName:    "chessfinder_dynamodb-games",
-	awsConfig:         &awsConfig,
+	searchesTableName:            "chessfinder_dynamodb-searches",
+	gamesTableName:               "chessfinder_dynamodb-games",
+	gamesByEndTimestampIndexName: "chessfinder_dynamodb-gamesByEndTimestamp",
+	searchMatchesTableName:       "chessfinder_dynamodb-searchMatches",
+	searchShardsTableName:        "chessfinder_dynamodb-searchShards",
+	deliverCallbacksQueueUrl:     "http://localhost:4566/000000000000/chessfinder_sqs-DeliverCallbacks",
+	awsConfig:                    &awsConfig,
+	matcher:                      searcher.CoreMatcher{},
 }
 var awsSession = session.Must(session.NewSession(&awsConfig))
 var dynamodbClient = dynamodb.New(awsSession)
@@ -131,8 +140,11 @@ func Test_when_there_is_a_registered_search_BoardFinder_should_look_through_all_
 	assert.Equal(t, total, actualSearchRecord.Examined)
 	assert.Equal(t, total, actualSearchRecord.Total)
 	assert.Equal(t, searches.SearchedAll, actualSearchRecord.Status)
+	assert.Equal(t, 1, actualSearchRecord.MatchedCount)
 
-	assert.ElementsMatch(t, []string{"https://www.chess.com/game/live/63025767719"}, actualSearchRecord.Matched)
+	actualMatchedGames, err := finder.getMatchedGames(searchId)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://www.chess.com/game/live/63025767719"}, actualMatchedGames)
 }
 
 func Test_when_there_is_no_registered_search_BoardFinder_should_skip(t *testing.T) {
@@ -176,6 +188,66 @@ func Test_when_there_is_no_registered_search_BoardFinder_should_skip(t *testing.
 	assert.Nil(t, actualSearchRecord)
 }
 
+func Test_when_the_search_is_finished_without_a_callback_BoardFinder_should_enqueue_it_once(t *testing.T) {
+	if !testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	var err error
+	userId := uuid.New().String()
+	searchId := uuid.New().String()
+
+	searchRecord := searches.NewSearchRecord(searchId, time.Now(), 6)
+	searchRecord.Examined = 6
+	searchRecord.MatchedCount = 1
+	searchRecord.Status = searches.SearchedAll
+	searchRecord.CallbackUrl = "https://example.com/chessfinder/done"
+	searchRecord.CallbackSecret = "secret"
+
+	err = finder.persistSearchRecord(searchRecord)
+	assert.NoError(t, err)
+
+	command :=
+		events.SQSMessage{
+			Body: fmt.Sprintf(
+				`
+				{
+					"searchId": "%s",
+					"board": "????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????",
+					"userId": "%s"
+				}
+			`,
+				searchId,
+				userId,
+			),
+			MessageId: "1",
+		}
+
+	redeliveredCommand := command
+	redeliveredCommand.MessageId = "2"
+
+	actualCommandsProcessed, err := finder.Find(events.SQSEvent{Records: []events.SQSMessage{command, redeliveredCommand}})
+	assert.NoError(t, err)
+	assert.Nil(t, actualCommandsProcessed.BatchItemFailures)
+
+	actualSearchRecord, err := finder.getSearchRecord(searchId)
+	assert.NoError(t, err)
+	assert.True(t, actualSearchRecord.Notified)
+
+	actualCallbacks, err := finder.receiveCallbacks(searchId)
+	assert.NoError(t, err)
+
+	expectedCallbacks := []queue.DeliverCallbackCommand{
+		{
+			CallbackUrl:    "https://example.com/chessfinder/done",
+			CallbackSecret: "secret",
+			Payload:        json.RawMessage(fmt.Sprintf(`{"event":"SEARCH_COMPLETED","searchId":"%s","status":"SEARCHED_ALL","examined":6,"total":6,"matchedCount":1}`, searchId)),
+		},
+	}
+
+	assert.Equal(t, expectedCallbacks, actualCallbacks)
+}
+
 func Test_when_there_are_more_then_10_games_that_have_the_same_position_BoardFinder_should_stop(t *testing.T) {
 	defer wiremockClient.Reset()
 
@@ -263,7 +335,340 @@ func Test_when_there_are_more_then_10_games_that_have_the_same_position_BoardFin
 		"https://www.chess.com/game/live/52671571319",
 		"https://www.chess.com/game/live/52671679953",
 	}
-	assert.ElementsMatch(t, expectedMatchedGames, actualSearchRecord.Matched)
+	assert.Equal(t, StopSearchIfFound, actualSearchRecord.MatchedCount)
+
+	actualMatchedGames, err := finder.getMatchedGames(searchId)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, expectedMatchedGames, actualMatchedGames)
+}
+
+func Test_BoardFinder_should_search_only_the_games_of_the_requested_variant(t *testing.T) {
+	defer wiremockClient.Reset()
+
+	if !testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	var err error
+	userId := uuid.New().String()
+	archiveId := uuid.New().String()
+	chess960GameId := "https://www.chess.com/game/live/63025767719"
+
+	gameRecords, err := loadGameRecords(userId, archiveId, "testdata/2022-11.json")
+	assert.NoError(t, err)
+	for i := range gameRecords {
+		if gameRecords[i].GameId == chess960GameId {
+			gameRecords[i].Rules = "chess960"
+		}
+	}
+	err = finder.persistGameRecords(gameRecords)
+	assert.NoError(t, err)
+	total := len(gameRecords)
+
+	searchAtartAt := db.Zuludatetime(time.Now().UTC().Add(-1 * time.Hour))
+
+	searchBoard := func(variant string) (searchRecord *searches.SearchRecord) {
+		searchId := uuid.New().String()
+		err := finder.persistSearchRecord(searches.SearchRecord{
+			SearchId:       searchId,
+			StartAt:        searchAtartAt,
+			LastExaminedAt: searchAtartAt,
+			Examined:       0,
+			Total:          total,
+			Matched:        []string{},
+			Status:         searches.InProgress,
+		})
+		assert.NoError(t, err)
+
+		command := events.SQSMessage{
+			Body: fmt.Sprintf(
+				`
+				{
+					"searchId": "%s",
+					"board": "????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????",
+					"userId": "%s",
+					"variant": "%s"
+				}
+			`,
+				searchId,
+				userId,
+				variant,
+			),
+			MessageId: "1",
+		}
+
+		actualCommandsProcessed, err := finder.Find(events.SQSEvent{Records: []events.SQSMessage{command}})
+		assert.NoError(t, err)
+		assert.Equal(t, events.SQSEventResponse{BatchItemFailures: nil}, actualCommandsProcessed)
+
+		searchRecord, err = finder.getSearchRecord(searchId)
+		assert.NoError(t, err)
+		return
+	}
+
+	standardSearchRecord := searchBoard("")
+	assert.Equal(t, total, standardSearchRecord.Examined)
+	assert.Equal(t, searches.SearchedAll, standardSearchRecord.Status)
+	assert.Equal(t, 0, standardSearchRecord.MatchedCount)
+
+	chess960SearchRecord := searchBoard("chess960")
+	assert.Equal(t, total, chess960SearchRecord.Examined)
+	assert.Equal(t, searches.SearchedAll, chess960SearchRecord.Status)
+	assert.Equal(t, 1, chess960SearchRecord.MatchedCount)
+}
+
+func Test_BoardFinder_should_examine_only_the_games_that_pass_the_filter(t *testing.T) {
+	if !testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	var err error
+	userId := uuid.New().String()
+	archiveId := uuid.New().String()
+	searchId := uuid.New().String()
+	matchingGameId := "https://www.chess.com/game/live/63025767719"
+
+	gameRecords, err := loadGameRecords(userId, archiveId, "testdata/2022-11.json")
+	assert.NoError(t, err)
+	// the game of another variant that passes the filter is examined without being searched, as in the searches without a filter
+	otherVariantGameId := ""
+	for i := range gameRecords {
+		gameRecords[i].White = "Garry"
+		gameRecords[i].Black = "Tigran-C-137"
+		if gameRecords[i].GameId == matchingGameId {
+			gameRecords[i].White, gameRecords[i].Black = gameRecords[i].Black, gameRecords[i].White
+		} else if otherVariantGameId == "" {
+			otherVariantGameId = gameRecords[i].GameId
+			gameRecords[i].White, gameRecords[i].Black = gameRecords[i].Black, gameRecords[i].White
+			gameRecords[i].Rules = "crazyhouse"
+		}
+	}
+	err = finder.persistGameRecords(gameRecords)
+	assert.NoError(t, err)
+
+	searchAtartAt := db.Zuludatetime(time.Now().UTC().Add(-1 * time.Hour))
+	err = finder.persistSearchRecord(searches.SearchRecord{
+		SearchId:       searchId,
+		StartAt:        searchAtartAt,
+		LastExaminedAt: searchAtartAt,
+		Examined:       0,
+		Total:          2,
+		Matched:        []string{},
+		Status:         searches.InProgress,
+	})
+	assert.NoError(t, err)
+
+	command := events.SQSMessage{
+		Body: fmt.Sprintf(
+			`
+			{
+				"searchId": "%s",
+				"board": "????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????",
+				"userId": "%s",
+				"filter": {"username": "tigran-c-137", "color": "white"}
+			}
+		`,
+			searchId,
+			userId,
+		),
+		MessageId: "1",
+	}
+
+	actualCommandsProcessed, err := finder.Find(events.SQSEvent{Records: []events.SQSMessage{command}})
+	assert.NoError(t, err)
+	assert.Equal(t, events.SQSEventResponse{BatchItemFailures: nil}, actualCommandsProcessed)
+
+	actualSearchRecord, err := finder.getSearchRecord(searchId)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, actualSearchRecord.Examined)
+	assert.Equal(t, searches.SearchedAll, actualSearchRecord.Status)
+	assert.Equal(t, 1, actualSearchRecord.MatchedCount)
+
+	actualMatchedGames, err := finder.getMatchedGames(searchId)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{matchingGameId}, actualMatchedGames)
+}
+
+func Test_BoardFinder_should_finish_a_sharded_search_once_all_its_shards_are_counted(t *testing.T) {
+	if !testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	var err error
+	userId := uuid.New().String()
+	searchId := uuid.New().String()
+	archiveIds := []string{uuid.New().String(), uuid.New().String()}
+	total := 0
+
+	for i, fileRelativePath := range []string{"testdata/2022-10.json", "testdata/2022-11.json"} {
+		gameRecords, err := loadGameRecords(userId, archiveIds[i], fileRelativePath)
+		assert.NoError(t, err)
+		err = finder.persistGameRecords(gameRecords)
+		assert.NoError(t, err)
+		total += len(gameRecords)
+	}
+
+	searchRecord := searches.NewSearchRecord(searchId, time.Now().UTC().Add(-1*time.Hour), total)
+	searchRecord.Shards = len(archiveIds)
+	err = finder.persistSearchRecord(searchRecord)
+	assert.NoError(t, err)
+
+	commandOf := func(archiveId string, messageId string) events.SQSMessage {
+		return events.SQSMessage{
+			Body: fmt.Sprintf(
+				`
+				{
+					"searchId": "%s",
+					"board": "????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????",
+					"userId": "%s",
+					"archiveId": "%s"
+				}
+			`,
+				searchId,
+				userId,
+				archiveId,
+			),
+			MessageId: messageId,
+		}
+	}
+
+	actualCommandsProcessed, err := finder.Find(events.SQSEvent{Records: []events.SQSMessage{commandOf(archiveIds[0], "1")}})
+	assert.NoError(t, err)
+	assert.Nil(t, actualCommandsProcessed.BatchItemFailures)
+
+	actualSearchRecord, err := finder.getSearchRecord(searchId)
+	assert.NoError(t, err)
+	assert.Equal(t, searches.InProgress, actualSearchRecord.Status)
+	assert.Equal(t, 1, actualSearchRecord.ShardsDone)
+
+	// the redelivered shard is not counted twice
+	actualCommandsProcessed, err = finder.Find(events.SQSEvent{Records: []events.SQSMessage{commandOf(archiveIds[0], "2"), commandOf(archiveIds[1], "3")}})
+	assert.NoError(t, err)
+	assert.Nil(t, actualCommandsProcessed.BatchItemFailures)
+
+	actualSearchRecord, err = finder.getSearchRecord(searchId)
+	assert.NoError(t, err)
+	assert.Equal(t, searches.SearchedAll, actualSearchRecord.Status)
+	assert.Equal(t, 2, actualSearchRecord.ShardsDone)
+	assert.Equal(t, total, actualSearchRecord.Examined)
+	assert.Equal(t, 1, actualSearchRecord.MatchedCount)
+
+	actualMatchedGames, err := finder.getMatchedGames(searchId)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://www.chess.com/game/live/63025767719"}, actualMatchedGames)
+}
+
+func Test_BoardFinder_should_store_only_the_matches_of_a_shard_that_fit_under_the_limit(t *testing.T) {
+	if !testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	var err error
+	userId := uuid.New().String()
+	searchId := uuid.New().String()
+	archiveId := uuid.New().String()
+
+	gameRecords, err := loadGameRecords(userId, archiveId, "testdata/2022-07_repeating_games.json")
+	assert.NoError(t, err)
+	err = finder.persistGameRecords(gameRecords)
+	assert.NoError(t, err)
+
+	// the other shard running together has already claimed all the matches but 3
+	searchRecord := searches.NewSearchRecord(searchId, time.Now().UTC().Add(-1*time.Hour), len(gameRecords))
+	searchRecord.Shards = 2
+	searchRecord.ReservedMatches = StopSearchIfFound - 3
+	err = finder.persistSearchRecord(searchRecord)
+	assert.NoError(t, err)
+
+	command := events.SQSMessage{
+		Body: fmt.Sprintf(
+			`
+			{
+				"searchId": "%s",
+				"board": "????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????",
+				"userId": "%s",
+				"archiveId": "%s"
+			}
+		`,
+			searchId,
+			userId,
+			archiveId,
+		),
+		MessageId: "1",
+	}
+
+	actualCommandsProcessed, err := finder.Find(events.SQSEvent{Records: []events.SQSMessage{command}})
+	assert.NoError(t, err)
+	assert.Nil(t, actualCommandsProcessed.BatchItemFailures)
+
+	actualSearchRecord, err := finder.getSearchRecord(searchId)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, actualSearchRecord.ShardsDone)
+	assert.Equal(t, 1, actualSearchRecord.StoppedShards)
+	assert.Equal(t, 3, actualSearchRecord.MatchedCount)
+
+	actualMatchedGames, err := finder.getMatchedGames(searchId)
+	assert.NoError(t, err)
+	assert.Len(t, actualMatchedGames, 3)
+}
+
+func Test_BoardFinder_should_reuse_the_matches_a_redelivered_shard_reserved_before(t *testing.T) {
+	if !testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	var err error
+	userId := uuid.New().String()
+	searchId := uuid.New().String()
+	archiveId := uuid.New().String()
+
+	gameRecords, err := loadGameRecords(userId, archiveId, "testdata/2022-07_repeating_games.json")
+	assert.NoError(t, err)
+	err = finder.persistGameRecords(gameRecords)
+	assert.NoError(t, err)
+
+	// the previous delivery of the shard reserved 3 matches and failed, the other shard reserved the rest of the limit
+	searchRecord := searches.NewSearchRecord(searchId, time.Now().UTC().Add(-1*time.Hour), len(gameRecords))
+	searchRecord.Shards = 2
+	searchRecord.ReservedMatches = StopSearchIfFound
+	err = finder.persistSearchRecord(searchRecord)
+	assert.NoError(t, err)
+
+	shardRecord := searches.NewShardRecord(searchId, archiveId)
+	shardRecord.Reserved = 3
+	err = finder.persistShardRecord(shardRecord)
+	assert.NoError(t, err)
+
+	command := events.SQSMessage{
+		Body: fmt.Sprintf(
+			`
+			{
+				"searchId": "%s",
+				"board": "????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????",
+				"userId": "%s",
+				"archiveId": "%s"
+			}
+		`,
+			searchId,
+			userId,
+			archiveId,
+		),
+		MessageId: "2",
+	}
+
+	actualCommandsProcessed, err := finder.Find(events.SQSEvent{Records: []events.SQSMessage{command}})
+	assert.NoError(t, err)
+	assert.Nil(t, actualCommandsProcessed.BatchItemFailures)
+
+	actualSearchRecord, err := finder.getSearchRecord(searchId)
+	assert.NoError(t, err)
+	assert.Equal(t, StopSearchIfFound, actualSearchRecord.ReservedMatches)
+	assert.Equal(t, 1, actualSearchRecord.ShardsDone)
+	assert.Equal(t, 3, actualSearchRecord.MatchedCount)
+
+	actualMatchedGames, err := finder.getMatchedGames(searchId)
+	assert.NoError(t, err)
+	assert.Len(t, actualMatchedGames, 3)
 }
 
 func (finder BoardFinder) persistSearchRecord(searchRecord searches.SearchRecord) (err error) {
@@ -279,6 +684,19 @@ func (finder BoardFinder) persistSearchRecord(searchRecord searches.SearchRecord
 	return
 }
 
+func (finder BoardFinder) persistShardRecord(shardRecord searches.ShardRecord) (err error) {
+	shardMarshalledItems, err := dynamodbattribute.MarshalMap(shardRecord)
+	if err != nil {
+		return
+	}
+
+	_, err = dynamodbClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(finder.searchShardsTableName),
+		Item:      shardMarshalledItems,
+	})
+	return
+}
+
 func (finder BoardFinder) getSearchRecord(searchId string) (searchRecord *searches.SearchRecord, err error) {
 	getItemOutput, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
 		TableName: aws.String(finder.searchesTableName),
@@ -299,6 +717,66 @@ func (finder BoardFinder) getSearchRecord(searchId string) (searchRecord *search
 	return
 }
 
+// getMatchedGames reads the matches of the search in the order of the dates of their games
+func (finder BoardFinder) getMatchedGames(searchId string) (matchedGames []string, err error) {
+	queryOutput, err := dynamodbClient.Query(&dynamodb.QueryInput{
+		TableName:              aws.String(finder.searchMatchesTableName),
+		KeyConditionExpression: aws.String("search_id = :searchId"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":searchId": {
+				S: aws.String(searchId),
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+	matchRecords := []searches.MatchRecord{}
+	err = dynamodbattribute.UnmarshalListOfMaps(queryOutput.Items, &matchRecords)
+	if err != nil {
+		return
+	}
+	for _, matchRecord := range matchRecords {
+		matchedGames = append(matchedGames, matchRecord.Resource)
+	}
+	return
+}
+
+// receiveCallbacks drains the callbacks of the search from the queue shared with the other tests
+func (finder BoardFinder) receiveCallbacks(searchId string) (callbacks []queue.DeliverCallbackCommand, err error) {
+	svc := sqs.New(awsSession)
+	for attempt := 0; attempt < 3; attempt++ {
+		var receiveMessageOutput *sqs.ReceiveMessageOutput
+		receiveMessageOutput, err = svc.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(finder.deliverCallbacksQueueUrl),
+			MaxNumberOfMessages: aws.Int64(10),
+			WaitTimeSeconds:     aws.Int64(1),
+		})
+		if err != nil {
+			return
+		}
+		for _, message := range receiveMessageOutput.Messages {
+			command := queue.DeliverCallbackCommand{}
+			err = json.Unmarshal([]byte(*message.Body), &command)
+			if err != nil {
+				return
+			}
+			if !strings.Contains(string(command.Payload), searchId) {
+				continue
+			}
+			callbacks = append(callbacks, command)
+			_, err = svc.DeleteMessage(&sqs.DeleteMessageInput{
+				QueueUrl:      aws.String(finder.deliverCallbacksQueueUrl),
+				ReceiptHandle: message.ReceiptHandle,
+			})
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
 func loadGameRecords(userId string, archiveId string, fileRelativePath string) (gameRecords []games.GameRecord, err error) {
 
 	file, err := os.Open(fileRelativePath)
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chess"
+)
+
+// location is the first position of a matched game the board is found in
+type location struct {
+	ply int
+	fen string
+}
+
+// locate finds where the board occurs first in a game that matches it. The core tells only whether a game
+// contains the board, a prefix of the game contains it if and only if the first occurrence is within the prefix,
+// so the first occurrence is found by halving the prefixes, the fen of that position comes from the replay
+func locate(board string, pgn string, find func(board string, pgn string) (bool, error)) (matchLocation location, err error) {
+	game, err := chess.Read(pgn)
+	if err != nil {
+		return
+	}
+	positions, err := game.Positions()
+	if err != nil {
+		return
+	}
+
+	low, high := 0, len(game.Moves)
+	for low < high {
+		middle := (low + high) / 2
+		var isFound bool
+		isFound, err = find(board, game.PgnUpTo(middle))
+		if err != nil {
+			return
+		}
+		if isFound {
+			high = middle
+		} else {
+			low = middle + 1
+		}
+	}
+
+	if low == len(game.Moves) {
+		// the whole game is never asked about while halving, the replayed moves have to match as the original pgn does
+		var isFound bool
+		isFound, err = find(board, game.PgnUpTo(low))
+		if err != nil {
+			return
+		}
+		if !isFound {
+			err = fmt.Errorf("the replayed game does not contain the board")
+			return
+		}
+	}
+
+	matchLocation = location{
+		ply: low,
+		fen: positions[low].Fen(),
+	}
+	return
+}
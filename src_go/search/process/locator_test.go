@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_locate_should_find_the_first_position_the_board_occurs_in(t *testing.T) {
+	pgn := `[Event "Live Chess"]
+[Result "1-0"]
+
+1. e4 {[%clk 0:09:59.9]} 1... e5 2. Nf3 Nc6 3. Bb5 a6 4. Ba4 Nf6 1-0`
+
+	// the board of the test occurs once the bishop reaches b5 and stays in the game after that
+	find := func(board string, pgn string) (bool, error) {
+		return strings.Contains(pgn, "Bb5"), nil
+	}
+
+	actualLocation, err := locate("any board", pgn, find)
+	assert.NoError(t, err)
+	assert.Equal(t, location{
+		ply: 5,
+		fen: "r1bqkbnr/pppp1ppp/2n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 3",
+	}, actualLocation)
+}
+
+func Test_locate_should_fail_if_the_replayed_game_does_not_contain_the_board(t *testing.T) {
+	find := func(board string, pgn string) (bool, error) {
+		return false, nil
+	}
+
+	_, err := locate("any board", "1. e4 e5 *", find)
+	assert.Error(t, err)
+}
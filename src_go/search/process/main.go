@@ -4,9 +4,10 @@ import (
 	"errors"
 	"os"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/search/process/searcher"
 )
 
 func main() {
@@ -16,31 +17,58 @@ func main() {
 		panic(errors.New("GAMES_TABLE_NAME is missing"))
 	}
 
+	gamesByEndTimestampIndexName, gamesByEndTimestampIndexNameExists := os.LookupEnv("GAMES_BY_END_TIMESTAMP_INDEX_NAME")
+	if !gamesByEndTimestampIndexNameExists {
+		panic(errors.New("GAMES_BY_END_TIMESTAMP_INDEX_NAME is missing"))
+	}
+
 	searchesTableName, searchesTableNameExists := os.LookupEnv("SEARCHES_TABLE_NAME")
 	if !searchesTableNameExists {
 		panic(errors.New("SEARCHES_TABLE_NAME is missing"))
 	}
 
+	searchMatchesTableName, searchMatchesTableNameExists := os.LookupEnv("SEARCH_MATCHES_TABLE_NAME")
+	if !searchMatchesTableNameExists {
+		panic(errors.New("SEARCH_MATCHES_TABLE_NAME is missing"))
+	}
+
+	searchShardsTableName, searchShardsTableNameExists := os.LookupEnv("SEARCH_SHARDS_TABLE_NAME")
+	if !searchShardsTableNameExists {
+		panic(errors.New("SEARCH_SHARDS_TABLE_NAME is missing"))
+	}
+
+	deliverCallbacksQueueUrl, deliverCallbacksQueueUrlExists := os.LookupEnv("DELIVER_CALLBACKS_QUEUE_URL")
+	if !deliverCallbacksQueueUrlExists {
+		panic(errors.New("DELIVER_CALLBACKS_QUEUE_URL is missing"))
+	}
+
+	boardMatcher, boardMatcherExists := os.LookupEnv("BOARD_MATCHER")
+	if !boardMatcherExists {
+		panic(errors.New("BOARD_MATCHER is missing"))
+	}
+	matcher, err := searcher.MatcherOf(boardMatcher)
+	if err != nil {
+		panic(err)
+	}
+
 	awsRegion, awsRegionExists := os.LookupEnv("AWS_REGION")
 	if !awsRegionExists {
 		panic(errors.New("AWS_REGION is missing"))
 	}
 
 	finder := BoardFinder{
-		searchesTableName: searchesTableName,
-		gamesTableName:    gamesTableName,
+		searchesTableName:            searchesTableName,
+		gamesTableName:               gamesTableName,
+		gamesByEndTimestampIndexName: gamesByEndTimestampIndexName,
+		searchMatchesTableName:       searchMatchesTableName,
+		searchShardsTableName:        searchShardsTableName,
+		deliverCallbacksQueueUrl:     deliverCallbacksQueueUrl,
 		awsConfig: &aws.Config{
 			Region: &awsRegion,
 		},
+		matcher: matcher,
 	}
 
-	lambda.Start(sealErrors(finder.Find))
+	lambda.Start(queue.SealErrors(finder.Find))
 
 }
-
-func sealErrors(unsafeHandling func(events.SQSEvent) (events.SQSEventResponse, error)) func(events.SQSEvent) (events.SQSEventResponse, error) {
-	return func(commands events.SQSEvent) (commandsProcessed events.SQSEventResponse, err error) {
-		_, _ = unsafeHandling(commands)
-		return
-	}
-}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chess"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/search/process/searcher"
+	"github.com/stretchr/testify/assert"
+)
+
+type corpusGame struct {
+	Url string `json:"url"`
+	Pgn string `json:"pgn"`
+	Fen string `json:"fen"`
+}
+
+func loadCorpus(t *testing.T, fileRelativePaths []string) (corpus []corpusGame) {
+	for _, fileRelativePath := range fileRelativePaths {
+		data, err := os.ReadFile(fileRelativePath)
+		assert.NoError(t, err)
+
+		allGamesJson := struct {
+			Games []corpusGame `json:"games"`
+		}{}
+		err = json.Unmarshal(data, &allGamesJson)
+		assert.NoError(t, err)
+
+		corpus = append(corpus, allGamesJson.Games...)
+	}
+	return
+}
+
+// searchFenOf turns the fen of a position into the search fen of the same board, the files before unknownFiles are unknown
+func searchFenOf(fen string, unknownFiles int) string {
+	rows := []string{}
+	for _, rank := range strings.Split(strings.Fields(fen)[0], "/") {
+		row := []byte{}
+		for _, char := range []byte(rank) {
+			if char >= '1' && char <= '8' {
+				row = append(row, []byte(strings.Repeat("-", int(char-'0')))...)
+			} else {
+				row = append(row, char)
+			}
+		}
+		copy(row, strings.Repeat("?", unknownFiles))
+		rows = append(rows, string(row))
+	}
+	return strings.Join(rows, "/")
+}
+
+func Test_native_replay_should_reach_the_current_position_of_the_downloaded_games(t *testing.T) {
+	// the fens of chess.com keep the castling of a rook that is taken, the games of these archives do not take such rooks
+	for _, game := range loadCorpus(t, []string{"testdata/2022-07.json", "testdata/2022-08.json", "testdata/2022-11.json"}) {
+		replayedGame, err := chess.Read(game.Pgn)
+		if !assert.NoError(t, err, game.Url) {
+			continue
+		}
+		positions, err := replayedGame.Positions()
+		if !assert.NoError(t, err, game.Url) {
+			continue
+		}
+		lastPosition := positions[len(positions)-1]
+		assert.Equal(t, strings.Fields(game.Fen)[:4], strings.Fields(lastPosition.Fen())[:4], game.Url)
+	}
+}
+
+func Test_NativeMatcher_should_find_the_same_games_as_CoreMatcher(t *testing.T) {
+	if _, err := searcher.SearchBoard("????????/????????/????????/????????/????????/????????/????????/????????", "*"); err != nil {
+		t.Skip("the core is not available: ", err)
+	}
+
+	fileRelativePaths, err := filepath.Glob("testdata/*.json")
+	assert.NoError(t, err)
+	corpus := loadCorpus(t, fileRelativePaths)
+
+	boards := []string{
+		"????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????",
+		"rnbqkbnr/pppppppp/--------/--------/--------/--------/PPPPPPPP/RNBQKBNR",
+		"????????/????????/????????/????????/????????/????????/????????/????????",
+		"????????/????????/????????/???oo???/???oo???/????????/????????/????????",
+		"????????/????????/????????/????????/????????/????????/????????/",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR",
+	}
+	// the boards of the middle of some games are found at least in their own games
+	for i := 0; i < len(corpus); i += 200 {
+		game, err := chess.Read(corpus[i].Pgn)
+		if !assert.NoError(t, err, corpus[i].Url) {
+			continue
+		}
+		positions, err := game.Positions()
+		if !assert.NoError(t, err, corpus[i].Url) {
+			continue
+		}
+		fen := positions[len(positions)/2].Fen()
+		boards = append(boards, searchFenOf(fen, 0), searchFenOf(fen, 4))
+	}
+
+	coreMatcher := searcher.CoreMatcher{}
+	nativeMatcher := searcher.NativeMatcher{}
+	for _, board := range boards {
+		found := 0
+		for _, game := range corpus {
+			isFoundByCore, err := coreMatcher.SearchBoard(board, game.Pgn)
+			assert.NoError(t, err)
+			isFoundByNative, err := nativeMatcher.SearchBoard(board, game.Pgn)
+			assert.NoError(t, err)
+			assert.Equal(t, isFoundByCore, isFoundByNative, "board %v in game %v", board, game.Url)
+			if isFoundByCore {
+				found++
+			}
+		}
+		t.Logf("board %v is found in %d games", board, found)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/batcher"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/searches"
+	"go.uber.org/zap"
+)
+
+const maxMatchesPerBatch = 25
+
+// persistMatches stores the matches found in one round, a repeated search overwrites the same matches
+func (finder *BoardFinder) persistMatches(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	matches []searches.MatchRecord,
+) (err error) {
+	for _, batch := range batcher.Batcher(matches, maxMatchesPerBatch) {
+		writeRequests := make([]*dynamodb.WriteRequest, 0, len(batch))
+		for _, match := range batch {
+			var matchItems map[string]*dynamodb.AttributeValue
+			matchItems, err = dynamodbattribute.MarshalMap(match)
+			if err != nil {
+				logger.Error("impossible to marshal the match", zap.Error(err))
+				return
+			}
+			writeRequests = append(writeRequests, &dynamodb.WriteRequest{
+				PutRequest: &dynamodb.PutRequest{
+					Item: matchItems,
+				},
+			})
+		}
+
+		unprocessedWriteRequests := map[string][]*dynamodb.WriteRequest{
+			finder.searchMatchesTableName: writeRequests,
+		}
+
+		for len(unprocessedWriteRequests) > 0 {
+			var writeOutput *dynamodb.BatchWriteItemOutput
+			writeOutput, err = dynamodbClient.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+				RequestItems: unprocessedWriteRequests,
+			})
+			if err != nil {
+				logger.Error("impossible to persist the matches", zap.Error(err))
+				return
+			}
+
+			unprocessedWriteRequests = writeOutput.UnprocessedItems
+			if len(unprocessedWriteRequests) > 0 {
+				time.Sleep(time.Millisecond * 100)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/callback"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/searches"
+	"go.uber.org/zap"
+)
+
+// notifyIfFinished hands the final status of a finished search over to the deliverer of the callbacks,
+// a redelivered command does not send it twice
+func (finder *BoardFinder) notifyIfFinished(
+	dynamodbClient *dynamodb.DynamoDB,
+	svc *sqs.SQS,
+	logger *zap.Logger,
+	searchId string,
+) (err error) {
+	claim := callback.Claim{
+		TableName: finder.searchesTableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"search_id": {
+				S: aws.String(searchId),
+			},
+		},
+		Condition: "#status <> :inProgress",
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":inProgress": {
+				S: aws.String(string(searches.InProgress)),
+			},
+		},
+	}
+
+	err = callback.NotifyOnce(dynamodbClient, svc, logger, finder.deliverCallbacksQueueUrl, claim, func(record map[string]*dynamodb.AttributeValue) (callbackUrl string, callbackSecret string, payload any, err error) {
+		searchRecord := searches.SearchRecord{}
+		err = dynamodbattribute.UnmarshalMap(record, &searchRecord)
+		if err != nil {
+			return
+		}
+
+		callbackUrl = searchRecord.CallbackUrl
+		callbackSecret = searchRecord.CallbackSecret
+		payload = callback.SearchCompleted{
+			Event:        callback.SearchCompletedEvent,
+			SearchId:     searchRecord.SearchId,
+			Status:       string(searchRecord.Status),
+			Examined:     searchRecord.Examined,
+			Total:        searchRecord.Total,
+			MatchedCount: searchRecord.MatchedCount,
+		}
+		return
+	})
+	return
+}
@@ -1,3 +1,5 @@
+//go:build core
+
 package searcher
 
 /*
@@ -9,36 +11,34 @@ package searcher
 import "C"
 
 import (
-	"fmt"
-	"runtime/debug"
 	"unsafe"
-)
 
-func SearchBoard(board string, pgn string) (bool, error) {
-	debug.SetPanicOnFault(true)
-	var err error
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/isolate"
+)
 
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Println("Recovered in SearchBoard", r)
-			err = fmt.Errorf("%v", r)
-		}
-	}()
+const isCoreLinked = true
 
-	var isolate *C.graal_isolate_t = nil
-	var thread *C.graal_isolatethread_t = nil
+// SearchBoard runs the core on a thread of the isolate pool, it is safe to call from many goroutines at once
+func SearchBoard(board string, pgn string) (isFound bool, err error) {
+	err = isolate.Call(func(thread unsafe.Pointer) {
+		isFound = find((*C.graal_isolatethread_t)(thread), board, pgn)
+	})
+	return
+}
 
-	if C.graal_create_isolate(nil, &isolate, &thread) != 0 {
-		fmt.Println("Initialization error")
-		return false, err
-	}
+// SearchBoardInOwnIsolate creates and tears down an isolate for every board as SearchBoard did before the pool,
+// it is kept to measure the pool against
+func SearchBoardInOwnIsolate(board string, pgn string) (isFound bool, err error) {
+	err = isolate.CallInOwnIsolate(func(thread unsafe.Pointer) {
+		isFound = find((*C.graal_isolatethread_t)(thread), board, pgn)
+	})
+	return
+}
 
-	defer C.graal_tear_down_isolate(thread)
+func find(thread *C.graal_isolatethread_t, board string, pgn string) bool {
 	cBoard := C.CString(board)
 	defer C.free(unsafe.Pointer(cBoard))
 	cPgn := C.CString(pgn)
 	defer C.free(unsafe.Pointer(cPgn))
-	isFound := C.find(thread, cBoard, cPgn)
-
-	return isFound != 0, err
+	return C.find(thread, cBoard, cPgn) != 0
 }
@@ -0,0 +1,14 @@
+//go:build !core
+
+package searcher
+
+// the core library is linked only by the builds with the core tag, the others search with the native matcher
+const isCoreLinked = false
+
+func SearchBoard(board string, pgn string) (bool, error) {
+	return false, ErrCoreNotLinked
+}
+
+func SearchBoardInOwnIsolate(board string, pgn string) (bool, error) {
+	return false, ErrCoreNotLinked
+}
@@ -0,0 +1,48 @@
+package searcher
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/chess"
+)
+
+const CoreMatcherName = "core"
+const NativeMatcherName = "native"
+
+var ErrCoreNotLinked = errors.New("the core is not linked, it is built in only with the core tag")
+
+// Matcher tells whether a game has the board of a search fen in any of its positions
+type Matcher interface {
+	SearchBoard(board string, pgn string) (bool, error)
+}
+
+// CoreMatcher searches the board with the native library of the core
+type CoreMatcher struct{}
+
+func (CoreMatcher) SearchBoard(board string, pgn string) (bool, error) {
+	return SearchBoard(board, pgn)
+}
+
+// NativeMatcher searches the board with the replay written in Go, it finds the same games as the core
+type NativeMatcher struct{}
+
+func (NativeMatcher) SearchBoard(board string, pgn string) (bool, error) {
+	return chess.Find(board, pgn), nil
+}
+
+func MatcherOf(name string) (matcher Matcher, err error) {
+	switch name {
+	case CoreMatcherName:
+		if !isCoreLinked {
+			err = ErrCoreNotLinked
+			return
+		}
+		matcher = CoreMatcher{}
+	case NativeMatcherName:
+		matcher = NativeMatcher{}
+	default:
+		err = fmt.Errorf("matcher %v is not one of %v and %v", name, CoreMatcherName, NativeMatcherName)
+	}
+	return
+}
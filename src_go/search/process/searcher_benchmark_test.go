@@ -0,0 +1,65 @@
+//go:build core
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/search/process/searcher"
+)
+
+const benchmarkArchive = "testdata/2022-11.json"
+const benchmarkBoard = "????R?r?/?????kq?/????Q???/????????/????????/????????/????????/????????"
+
+func loadPgns(b *testing.B, fileRelativePath string) (pgns []string) {
+	data, err := os.ReadFile(fileRelativePath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	archive := struct {
+		Games []struct {
+			Pgn string `json:"pgn"`
+		} `json:"games"`
+	}{}
+	err = json.Unmarshal(data, &archive)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, game := range archive.Games {
+		pgns = append(pgns, game.Pgn)
+	}
+	return
+}
+
+func benchmarkSearch(b *testing.B, search func(board string, pgn string) (bool, error)) {
+	pgns := loadPgns(b, benchmarkArchive)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := search(benchmarkBoard, pgns[i%len(pgns)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_SearchBoard_in_own_isolate(b *testing.B) {
+	benchmarkSearch(b, searcher.SearchBoardInOwnIsolate)
+}
+
+func Benchmark_SearchBoard_in_isolate_pool(b *testing.B) {
+	benchmarkSearch(b, searcher.SearchBoard)
+}
+
+func Benchmark_SearchBoard_in_isolate_pool_from_many_goroutines(b *testing.B) {
+	pgns := loadPgns(b, benchmarkArchive)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if _, err := searcher.SearchBoard(benchmarkBoard, pgns[i%len(pgns)]); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
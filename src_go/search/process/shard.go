@@ -0,0 +1,443 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/games"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/db/searches"
+	"github.com/chessfinder/chessfinder-faster-backend/src_go/details/queue"
+	"go.uber.org/zap"
+)
+
+// processShard searches the games of one archive. The shards of a search run in parallel, each of them adds
+// its progress to the search record once it is over and the last one sets the final status of the search
+func (finder *BoardFinder) processShard(
+	message *events.SQSMessage,
+	command queue.SearchBoardCommand,
+	searchRecord searches.SearchRecord,
+	variant string,
+	matchLimit int,
+	dynamodbClient *dynamodb.DynamoDB,
+	svc *sqs.SQS,
+	logger *zap.Logger,
+) (commandProcessed *events.SQSBatchItemFailure, err error) {
+	shardRecord, err := finder.getShardRecord(dynamodbClient, logger, command)
+	if err != nil {
+		commandProcessed = &events.SQSBatchItemFailure{ItemIdentifier: message.MessageId}
+		return
+	}
+
+	if shardRecord.Counted {
+		logger.Info("shard is already counted in the search")
+	} else {
+		// the shards running together claim their matches before storing them, the reservation keeps the search under the limit,
+		// the matches a previous delivery of the shard claimed stay its own and they are found again in the same order
+		unusedReservation := shardRecord.Reserved
+		matchesLeft := matchLimit - searchRecord.ReservedMatches + unusedReservation
+		var lastKey map[string]*dynamodb.AttributeValue
+		var errOfSearch error
+
+		for round := 0; matchesLeft > 0; round++ {
+			logger := logger.With(zap.Int("round", round+1))
+			var gameRecords []games.GameRecord
+			gameRecords, lastKey, errOfSearch = finder.getArchiveGames(dynamodbClient, logger, command.ArchiveId, lastKey)
+			if errOfSearch != nil {
+				break
+			}
+
+			examined, matches := finder.examineGames(logger, command, variant, gameRecords, matchesLeft)
+			reserved := min(len(matches), unusedReservation)
+			unusedReservation -= reserved
+			if reserved < len(matches) {
+				var newlyReserved int
+				newlyReserved, errOfSearch = finder.reserveMatches(dynamodbClient, logger, shardRecord, len(matches)-reserved, matchLimit)
+				if errOfSearch != nil {
+					break
+				}
+				shardRecord.Reserved += newlyReserved
+				reserved += newlyReserved
+			}
+			errOfSearch = finder.persistMatches(dynamodbClient, logger, matches[:reserved])
+			if errOfSearch != nil {
+				break
+			}
+			shardRecord.Examined += examined
+			shardRecord.Matched += reserved
+			matchesLeft -= reserved
+			if reserved < len(matches) {
+				matchesLeft = 0
+			}
+
+			if len(lastKey) == 0 {
+				break
+			}
+		}
+
+		if errOfSearch != nil && isRetryable(errOfSearch) && !queue.IsLastReceive(message.Attributes["ApproximateReceiveCount"]) {
+			logger.Info("shard will be repeated when the command is delivered again", zap.Error(errOfSearch))
+			commandProcessed = &events.SQSBatchItemFailure{ItemIdentifier: message.MessageId}
+			return
+		}
+
+		if matchesLeft <= 0 {
+			logger.Info("stopping the shard because of the limit")
+		}
+		shardRecord.Stopped = matchesLeft <= 0 || errOfSearch != nil
+
+		err = finder.countShard(dynamodbClient, logger, shardRecord)
+		if err != nil {
+			commandProcessed = &events.SQSBatchItemFailure{ItemIdentifier: message.MessageId}
+			return
+		}
+	}
+
+	err = finder.finishIfAllShardsCounted(dynamodbClient, logger, command.SearchId)
+	if err != nil {
+		commandProcessed = &events.SQSBatchItemFailure{ItemIdentifier: message.MessageId}
+		return
+	}
+
+	err = finder.notifyIfFinished(dynamodbClient, svc, logger, command.SearchId)
+	if err != nil && !queue.IsLastReceive(message.Attributes["ApproximateReceiveCount"]) {
+		commandProcessed = &events.SQSBatchItemFailure{ItemIdentifier: message.MessageId}
+	}
+	return
+}
+
+// getShardRecord reads what the previous deliveries of the shard have done, a new record is returned for the first one
+func (finder *BoardFinder) getShardRecord(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	command queue.SearchBoardCommand,
+) (shardRecord searches.ShardRecord, err error) {
+	shardItems, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(finder.searchShardsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"search_id": {
+				S: aws.String(command.SearchId),
+			},
+			"archive_id": {
+				S: aws.String(command.ArchiveId),
+			},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		logger.Error("impossible to get the shard record", zap.Error(err))
+		return
+	}
+
+	shardRecord = searches.NewShardRecord(command.SearchId, command.ArchiveId)
+	err = dynamodbattribute.UnmarshalMap(shardItems.Item, &shardRecord)
+	if err != nil {
+		logger.Error("impossible to unmarshal the shard record", zap.Error(err))
+		return
+	}
+	return
+}
+
+// getArchiveGames reads the games of the archive in the order they ended
+func (finder *BoardFinder) getArchiveGames(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	archiveId string,
+	lastKey map[string]*dynamodb.AttributeValue,
+) (gameRecords []games.GameRecord, nextKey map[string]*dynamodb.AttributeValue, err error) {
+	logger.Info("getting the game records of the archive")
+	gameRecordsItems, err := dynamodbClient.Query(&dynamodb.QueryInput{
+		TableName:              aws.String(finder.gamesTableName),
+		IndexName:              aws.String(finder.gamesByEndTimestampIndexName),
+		KeyConditionExpression: aws.String("archive_id = :archive_id"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":archive_id": {
+				S: aws.String(archiveId),
+			},
+		},
+		Limit:             aws.Int64(MaxGamesPerRequest),
+		ExclusiveStartKey: lastKey,
+	})
+	if err != nil {
+		logger.Error("impossible to get the game records", zap.Error(err))
+		return
+	}
+
+	err = dynamodbattribute.UnmarshalListOfMaps(gameRecordsItems.Items, &gameRecords)
+	if err != nil {
+		logger.Error("impossible to unmarshal the game records", zap.Error(err))
+		return
+	}
+
+	nextKey = gameRecordsItems.LastEvaluatedKey
+	return
+}
+
+// reserveMatches claims the found matches under the limit of the search for the shard. The claim is added to the search
+// and to the shard record at once, it is made again with the new count if another shard claimed matches in between
+func (finder *BoardFinder) reserveMatches(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	shardRecord searches.ShardRecord,
+	found int,
+	matchLimit int,
+) (reserved int, err error) {
+	for {
+		var searchRecordItems *dynamodb.GetItemOutput
+		searchRecordItems, err = dynamodbClient.GetItem(&dynamodb.GetItemInput{
+			TableName: aws.String(finder.searchesTableName),
+			Key: map[string]*dynamodb.AttributeValue{
+				"search_id": {
+					S: aws.String(shardRecord.SearchId),
+				},
+			},
+			ProjectionExpression: aws.String("reserved_matches"),
+			ConsistentRead:       aws.Bool(true),
+		})
+		if err != nil {
+			logger.Error("impossible to get the reserved matches", zap.Error(err))
+			return
+		}
+
+		searchRecord := searches.SearchRecord{}
+		err = dynamodbattribute.UnmarshalMap(searchRecordItems.Item, &searchRecord)
+		if err != nil {
+			logger.Error("impossible to unmarshal the reserved matches", zap.Error(err))
+			return
+		}
+
+		reserved = min(max(matchLimit-searchRecord.ReservedMatches, 0), found)
+		if reserved == 0 {
+			logger.Info("no match is left under the limit", zap.Int("found", found))
+			return
+		}
+
+		_, err = dynamodbClient.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+			TransactItems: []*dynamodb.TransactWriteItem{
+				{
+					Update: &dynamodb.Update{
+						TableName: aws.String(finder.searchesTableName),
+						Key: map[string]*dynamodb.AttributeValue{
+							"search_id": {
+								S: aws.String(shardRecord.SearchId),
+							},
+						},
+						UpdateExpression:    aws.String("ADD reserved_matches :reserved"),
+						ConditionExpression: aws.String("attribute_not_exists(reserved_matches) OR reserved_matches <= :reservedBefore"),
+						ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+							":reserved": {
+								N: aws.String(strconv.Itoa(reserved)),
+							},
+							":reservedBefore": {
+								N: aws.String(strconv.Itoa(matchLimit - reserved)),
+							},
+						},
+					},
+				},
+				{
+					Update: &dynamodb.Update{
+						TableName: aws.String(finder.searchShardsTableName),
+						Key: map[string]*dynamodb.AttributeValue{
+							"search_id": {
+								S: aws.String(shardRecord.SearchId),
+							},
+							"archive_id": {
+								S: aws.String(shardRecord.ArchiveId),
+							},
+						},
+						UpdateExpression:    aws.String("ADD reserved :reserved"),
+						ConditionExpression: aws.String("attribute_not_exists(counted)"),
+						ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+							":reserved": {
+								N: aws.String(strconv.Itoa(reserved)),
+							},
+						},
+					},
+				},
+			},
+		})
+
+		var transactionCanceled *dynamodb.TransactionCanceledException
+		if errors.As(err, &transactionCanceled) {
+			reasons := transactionCanceled.CancellationReasons
+			if len(reasons) > 0 && aws.StringValue(reasons[0].Code) == "ConditionalCheckFailed" {
+				logger.Info("another shard reserved matches in between, reserving again")
+				continue
+			}
+		}
+
+		if err != nil {
+			logger.Error("impossible to reserve the matches", zap.Error(err))
+			return
+		}
+
+		logger.Info("matches are reserved", zap.Int("found", found), zap.Int("reserved", reserved))
+		return
+	}
+}
+
+// countShard adds the progress of the shard to the search record, the counted shard record makes sure it is added only once
+func (finder *BoardFinder) countShard(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	shardRecord searches.ShardRecord,
+) (err error) {
+	shardRecord.Counted = true
+	shardItems, err := dynamodbattribute.MarshalMap(shardRecord)
+	if err != nil {
+		logger.Error("impossible to marshal the shard record", zap.Error(err))
+		return
+	}
+
+	stopped := 0
+	if shardRecord.Stopped {
+		stopped = 1
+	}
+
+	_, err = dynamodbClient.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{
+				Put: &dynamodb.Put{
+					TableName:           aws.String(finder.searchShardsTableName),
+					Item:                shardItems,
+					ConditionExpression: aws.String("attribute_not_exists(counted)"),
+				},
+			},
+			{
+				Update: &dynamodb.Update{
+					TableName: aws.String(finder.searchesTableName),
+					Key: map[string]*dynamodb.AttributeValue{
+						"search_id": {
+							S: aws.String(shardRecord.SearchId),
+						},
+					},
+					UpdateExpression:    aws.String("ADD examined :examined, matched_count :matched, shards_done :increment, stopped_shards :stopped SET last_examined_at = :lastExaminedAt"),
+					ConditionExpression: aws.String("attribute_not_exists(shards_done) OR shards_done < shards"),
+					ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+						":examined": {
+							N: aws.String(strconv.Itoa(shardRecord.Examined)),
+						},
+						":matched": {
+							N: aws.String(strconv.Itoa(shardRecord.Matched)),
+						},
+						":increment": {
+							N: aws.String("1"),
+						},
+						":stopped": {
+							N: aws.String(strconv.Itoa(stopped)),
+						},
+						":lastExaminedAt": {
+							S: aws.String(db.Zuludatetime(time.Now()).String()),
+						},
+					},
+				},
+			},
+		},
+	})
+
+	var transactionCanceled *dynamodb.TransactionCanceledException
+	if errors.As(err, &transactionCanceled) {
+		reasons := transactionCanceled.CancellationReasons
+		if len(reasons) > 0 && aws.StringValue(reasons[0].Code) == "ConditionalCheckFailed" {
+			logger.Info("shard is already counted in the search")
+			err = nil
+			return
+		}
+		if len(reasons) > 1 && aws.StringValue(reasons[1].Code) == "ConditionalCheckFailed" {
+			logger.Error("all the shards of the search are already counted")
+			err = nil
+			return
+		}
+	}
+
+	if err != nil {
+		logger.Error("impossible to count the shard in the search", zap.Error(err))
+		return
+	}
+
+	logger.Info("shard is counted in the search", zap.Int("examined", shardRecord.Examined), zap.Int("matched", shardRecord.Matched), zap.Bool("stopped", shardRecord.Stopped))
+	return
+}
+
+// finishIfAllShardsCounted sets the final status once all the shards are counted, the search is searched partially
+// if any of its shards stopped before examining all its games
+func (finder *BoardFinder) finishIfAllShardsCounted(
+	dynamodbClient *dynamodb.DynamoDB,
+	logger *zap.Logger,
+	searchId string,
+) (err error) {
+	searchRecordItems, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(finder.searchesTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"search_id": {
+				S: aws.String(searchId),
+			},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		logger.Error("impossible to get the search record", zap.Error(err))
+		return
+	}
+
+	searchRecord := searches.SearchRecord{}
+	err = dynamodbattribute.UnmarshalMap(searchRecordItems.Item, &searchRecord)
+	if err != nil {
+		logger.Error("impossible to unmarshal the search record", zap.Error(err))
+		return
+	}
+
+	if searchRecord.Status != searches.InProgress || searchRecord.ShardsDone < searchRecord.Shards {
+		logger.Info("search is not finished by this shard", zap.Int("shardsDone", searchRecord.ShardsDone), zap.Int("shards", searchRecord.Shards))
+		return
+	}
+
+	searchStatus := searches.SearchedAll
+	if searchRecord.StoppedShards > 0 {
+		searchStatus = searches.SearchedPartially
+	}
+
+	_, err = dynamodbClient.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(finder.searchesTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"search_id": {
+				S: aws.String(searchId),
+			},
+		},
+		UpdateExpression:    aws.String("SET #status = :status"),
+		ConditionExpression: aws.String("shards_done = shards AND #status = :inProgress"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":status": {
+				S: aws.String(string(searchStatus)),
+			},
+			":inProgress": {
+				S: aws.String(string(searches.InProgress)),
+			},
+		},
+	})
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		logger.Info("search is already finished by another shard")
+		err = nil
+		return
+	}
+
+	if err != nil {
+		logger.Error("impossible to finish the search", zap.Error(err))
+		return
+	}
+
+	logger.Info("search is finished by its last shard", zap.String("status", string(searchStatus)))
+	return
+}